@@ -23,23 +23,23 @@ func (wosClient WosClient) doAuthTemporary(method, bucketName, objectKey string,
 	isV4 := wosClient.conf.signature == SignatureV4
 	isWos := wosClient.conf.signature == SignatureWos
 	isV2 := wosClient.conf.signature == SignatureV2
-	prepareHostAndDate(headers, hostName, isV4, isWos)
+	prepareHostAndDate(headers, hostName, isV4, isWos, wosClient.now())
 
 	if isAkSkEmpty {
-		doLog(LEVEL_WARN, "No ak/sk provided, skip to construct authorization")
+		wosClient.logf(LEVEL_WARN, "No ak/sk provided, skip to construct authorization")
 	} else {
 
 		if isV2 {
 			originDate := headers[HEADER_DATE_CAMEL][0]
 			date, parseDateErr := time.Parse(RFC1123_FORMAT, originDate)
 			if parseDateErr != nil {
-				doLog(LEVEL_WARN, "Failed to parse date with reason: %v", parseDateErr)
+				wosClient.logf(LEVEL_WARN, "Failed to parse date with reason: %v", parseDateErr)
 				return "", parseDateErr
 			}
 			expires += date.Unix()
 			headers[HEADER_DATE_CAMEL] = []string{Int64ToString(expires)}
 
-			stringToSign := getV2StringToSign(method, canonicalizedURL, headers)
+			stringToSign := wosClient.getV2StringToSign(method, canonicalizedURL, headers)
 			signature := UrlEncode(Base64Encode(HmacSha1([]byte(sh.sk), []byte(stringToSign))), false)
 			if strings.Index(requestURL, "?") < 0 {
 				requestURL += "?"
@@ -55,7 +55,7 @@ func (wosClient WosClient) doAuthTemporary(method, bucketName, objectKey string,
 		} else {
 			date, parseDateErr := time.Parse(LONG_DATE_FORMAT, headers[HEADER_DATE_CAMEL][0])
 			if parseDateErr != nil {
-				doLog(LEVEL_WARN, "Failed to parse date with reason: %v", parseDateErr)
+				wosClient.logf(LEVEL_WARN, "Failed to parse date with reason: %v", parseDateErr)
 				return "", parseDateErr
 			}
 			delete(headers, HEADER_DATE_CAMEL)
@@ -96,8 +96,8 @@ func (wosClient WosClient) doAuthTemporary(method, bucketName, objectKey string,
 				return "", _err
 			}
 
-			stringToSign := getV4StringToSign(method, canonicalizedURL, parsedRequestURL.RawQuery, scope, longDate, UNSIGNED_PAYLOAD, signedHeaders, _headers, isWos)
-			signature := getSignature(stringToSign, sh.sk, wosClient.conf.region, shortDate, isWos)
+			stringToSign := wosClient.getV4StringToSign(method, canonicalizedURL, parsedRequestURL.RawQuery, scope, longDate, UNSIGNED_PAYLOAD, signedHeaders, _headers, isWos)
+			signature := wosClient.getCachedSignature(stringToSign, sh.sk, wosClient.conf.region, shortDate, isWos)
 
 			if isWos {
 				requestURL += fmt.Sprintf("&%s=%s", PARAM_SIGNATURE_WOS_CAMEL, UrlEncode(signature, false))
@@ -128,26 +128,35 @@ func (wosClient WosClient) doAuth(method, bucketName, objectKey string, params m
 
 	isV4 := wosClient.conf.signature == SignatureV4
 	isV2 := wosClient.conf.signature == SignatureV2
-	prepareHostAndDate(headers, hostName, isV4, isWos)
+	prepareHostAndDate(headers, hostName, isV4, isWos, wosClient.now())
 
 	if isAkSkEmpty {
-		doLog(LEVEL_WARN, "No ak/sk provided, skip to construct authorization")
+		wosClient.logf(LEVEL_WARN, "No ak/sk provided, skip to construct authorization")
 	} else {
 		ak := sh.ak
 		sk := sh.sk
 		var authorization string
 
 		if isV2 {
-			ret := v2Auth(ak, sk, method, canonicalizedURL, headers)
+			ret := wosClient.v2Auth(ak, sk, method, canonicalizedURL, headers)
 			hashPrefix := V2_HASH_PREFIX
 			authorization = fmt.Sprintf("%s %s:%s", hashPrefix, ak, ret["Signature"])
 		} else {
+			payload := UNSIGNED_PAYLOAD
+			if wosClient.call.streaming != nil {
+				payload = wosClient.call.streaming.payload()
+			} else if wosClient.call.payloadHash != "" {
+				payload = wosClient.call.payloadHash
+			}
 			if isWos {
-				headers[HEADER_CONTENT_SHA256_WOS] = []string{UNSIGNED_PAYLOAD}
+				headers[HEADER_CONTENT_SHA256_WOS] = []string{payload}
 			} else {
-				headers[HEADER_CONTENT_SHA256_AMZ] = []string{UNSIGNED_PAYLOAD}
+				headers[HEADER_CONTENT_SHA256_AMZ] = []string{payload}
+			}
+			ret := wosClient.v4Auth(ak, sk, wosClient.conf.region, method, canonicalizedURL, parsedRequestURL.RawQuery, headers, isWos)
+			if wosClient.call.streaming != nil {
+				wosClient.call.streaming.seed(ret["Signature"], sk, wosClient.conf.region, headers)
 			}
-			ret := v4Auth(ak, sk, wosClient.conf.region, method, canonicalizedURL, parsedRequestURL.RawQuery, headers, isWos)
 			if isWos {
 				authorization = fmt.Sprintf("%s Credential=%s,SignedHeaders=%s,Signature=%s", V4_WOS_HASH_PREFIX, ret["Credential"], ret["SignedHeaders"], ret["Signature"])
 			} else {
@@ -160,7 +169,7 @@ func (wosClient WosClient) doAuth(method, bucketName, objectKey string, params m
 	return
 }
 
-func prepareHostAndDate(headers map[string][]string, hostName string, isV4 bool, isWos bool) {
+func prepareHostAndDate(headers map[string][]string, hostName string, isV4 bool, isWos bool, now time.Time) {
 	headers[HEADER_HOST_CAMEL] = []string{hostName}
 	var (
 		date []string
@@ -193,9 +202,9 @@ func prepareHostAndDate(headers map[string][]string, hostName string, isV4 bool,
 	}
 	if _, ok := headers[HEADER_DATE_CAMEL]; !ok {
 		if isV4 || isWos {
-			headers[HEADER_DATE_CAMEL] = []string{time.Now().UTC().Format(LONG_DATE_FORMAT)}
+			headers[HEADER_DATE_CAMEL] = []string{now.Format(LONG_DATE_FORMAT)}
 		} else {
-			headers[HEADER_DATE_CAMEL] = []string{FormatUtcToRfc1123(time.Now().UTC())}
+			headers[HEADER_DATE_CAMEL] = []string{FormatUtcToRfc1123(now)}
 		}
 	}
 
@@ -285,37 +294,21 @@ func attachHeaders(headers map[string][]string) string {
 	return strings.Join(stringToSign, "\n")
 }
 
-func getV2StringToSign(method, canonicalizedURL string, headers map[string][]string) string {
+func (wosClient WosClient) getV2StringToSign(method, canonicalizedURL string, headers map[string][]string) string {
 	stringToSign := strings.Join([]string{method, "\n", attachHeaders(headers), "\n", canonicalizedURL}, "")
 
-	var isSecurityToken bool
-	var securityToken []string
-	securityToken, isSecurityToken = headers[HEADER_STS_TOKEN_AMZ]
-	var query []string
-	if !isSecurityToken {
-		parmas := strings.Split(canonicalizedURL, "?")
-		if len(parmas) > 1 {
-			query = strings.Split(parmas[1], "&")
-			for _, value := range query {
-				if strings.HasPrefix(value, HEADER_STS_TOKEN_AMZ+"=") || strings.HasPrefix(value, HEADER_STS_TOKEN_WOS+"=") {
-					if value[len(HEADER_STS_TOKEN_AMZ)+1:] != "" {
-						securityToken = []string{value[len(HEADER_STS_TOKEN_AMZ)+1:]}
-						isSecurityToken = true
-					}
-				}
-			}
-		}
+	var query string
+	if parmas := strings.SplitN(canonicalizedURL, "?", 2); len(parmas) > 1 {
+		query = parmas[1]
 	}
-	logStringToSign := stringToSign
-	if isSecurityToken && len(securityToken) > 0 {
-		logStringToSign = strings.Replace(logStringToSign, securityToken[0], "******", -1)
+	if wosClient.logEnabled(LEVEL_DEBUG) {
+		wosClient.logf(LEVEL_DEBUG, "The v2 auth stringToSign:\n%s", wosClient.getRedactor().redactText(stringToSign, headers, query))
 	}
-	doLog(LEVEL_DEBUG, "The v2 auth stringToSign:\n%s", logStringToSign)
 	return stringToSign
 }
 
-func v2Auth(ak, sk, method, canonicalizedURL string, headers map[string][]string) map[string]string {
-	stringToSign := getV2StringToSign(method, canonicalizedURL, headers)
+func (wosClient WosClient) v2Auth(ak, sk, method, canonicalizedURL string, headers map[string][]string) map[string]string {
+	stringToSign := wosClient.getV2StringToSign(method, canonicalizedURL, headers)
 	return map[string]string{"Signature": Base64Encode(HmacSha1([]byte(sk), []byte(stringToSign)))}
 }
 
@@ -334,7 +327,7 @@ func getCredential(ak, region, shortDate string, isWos bool) (string, string) {
 	return fmt.Sprintf("%s/%s", ak, scope), scope
 }
 
-func getV4StringToSign(method, canonicalizedURL, queryURL, scope, longDate, payload string, signedHeaders []string, headers map[string][]string, isWos bool) string {
+func (wosClient WosClient) getV4StringToSign(method, canonicalizedURL, queryURL, scope, longDate, payload string, signedHeaders []string, headers map[string][]string, isWos bool) string {
 	canonicalRequest := make([]string, 0, 10+len(signedHeaders)*4)
 	canonicalRequest = append(canonicalRequest, method)
 	canonicalRequest = append(canonicalRequest, "\n")
@@ -359,28 +352,9 @@ func getV4StringToSign(method, canonicalizedURL, queryURL, scope, longDate, payl
 
 	_canonicalRequest := strings.Join(canonicalRequest, "")
 
-	var isSecurityToken bool
-	var securityToken []string
-	if securityToken, isSecurityToken = headers[HEADER_STS_TOKEN_WOS]; !isSecurityToken {
-		securityToken, isSecurityToken = headers[HEADER_STS_TOKEN_AMZ]
-	}
-	var query []string
-	if !isSecurityToken {
-		query = strings.Split(queryURL, "&")
-		for _, value := range query {
-			if strings.HasPrefix(value, HEADER_STS_TOKEN_AMZ+"=") || strings.HasPrefix(value, HEADER_STS_TOKEN_WOS+"=") {
-				if value[len(HEADER_STS_TOKEN_AMZ)+1:] != "" {
-					securityToken = []string{value[len(HEADER_STS_TOKEN_AMZ)+1:]}
-					isSecurityToken = true
-				}
-			}
-		}
-	}
-	logCanonicalRequest := _canonicalRequest
-	if isSecurityToken && len(securityToken) > 0 {
-		logCanonicalRequest = strings.Replace(logCanonicalRequest, securityToken[0], "******", -1)
+	if wosClient.logEnabled(LEVEL_DEBUG) {
+		wosClient.logf(LEVEL_DEBUG, "The v4 auth canonicalRequest:\n%s", wosClient.getRedactor().redactText(_canonicalRequest, headers, queryURL))
 	}
-	doLog(LEVEL_DEBUG, "The v4 auth canonicalRequest:\n%s", logCanonicalRequest)
 
 	stringToSign := make([]string, 0, 7)
 
@@ -398,7 +372,7 @@ func getV4StringToSign(method, canonicalizedURL, queryURL, scope, longDate, payl
 
 	_stringToSign := strings.Join(stringToSign, "")
 
-	doLog(LEVEL_DEBUG, "The v4 auth stringToSign:\n%s", _stringToSign)
+	wosClient.logf(LEVEL_DEBUG, "The v4 auth stringToSign:\n%s", _stringToSign)
 	return _stringToSign
 }
 
@@ -420,30 +394,24 @@ func getSignedHeaders(headers map[string][]string) ([]string, map[string][]strin
 }
 
 func getSignature(stringToSign, sk, region, shortDate string, isWos bool) string {
-	var signature string
+	return Hex(HmacSha256(getSigningKey(sk, region, shortDate, isWos), []byte(stringToSign)))
+}
+
+func getSigningKey(sk, region, shortDate string, isWos bool) []byte {
 	if isWos {
 		key := HmacSha256([]byte(V4_WOS_HASH_PRE+sk), []byte(shortDate))
 		key = HmacSha256(key, []byte(region))
 		key = HmacSha256(key, []byte(V4_WOS_SERVICE_NAME))
-		key = HmacSha256(key, []byte(V4_WOS_SERVICE_SUFFIX))
-		signature = Hex(HmacSha256(key, []byte(stringToSign)))
-	} else {
-		key := HmacSha256([]byte(V4_HASH_PRE+sk), []byte(shortDate))
-		key = HmacSha256(key, []byte(region))
-		key = HmacSha256(key, []byte(V4_SERVICE_NAME))
-		key = HmacSha256(key, []byte(V4_SERVICE_SUFFIX))
-		signature = Hex(HmacSha256(key, []byte(stringToSign)))
+		return HmacSha256(key, []byte(V4_WOS_SERVICE_SUFFIX))
 	}
-
-	return signature
+	key := HmacSha256([]byte(V4_HASH_PRE+sk), []byte(shortDate))
+	key = HmacSha256(key, []byte(region))
+	key = HmacSha256(key, []byte(V4_SERVICE_NAME))
+	return HmacSha256(key, []byte(V4_SERVICE_SUFFIX))
 }
 
-// V4Auth is a wrapper for v4Auth
-func V4Auth(ak, sk, region, method, canonicalizedURL, queryURL string, headers map[string][]string) map[string]string {
-	return v4Auth(ak, sk, region, method, canonicalizedURL, queryURL, headers, false)
-}
-
-func v4Auth(ak, sk, region, method, canonicalizedURL, queryURL string, headers map[string][]string, isWos bool) map[string]string {
+// getV4Date returns the signing date of the V4 and WOS signatures from the headers.
+func getV4Date(headers map[string][]string, isWos bool) time.Time {
 	var t time.Time
 	var headDate string
 	if isWos {
@@ -480,6 +448,16 @@ func v4Auth(ak, sk, region, method, canonicalizedURL, queryURL string, headers m
 		t = time.Now().UTC()
 	}
 
+	return t
+}
+
+// V4Auth is a wrapper for v4Auth
+func V4Auth(ak, sk, region, method, canonicalizedURL, queryURL string, headers map[string][]string) map[string]string {
+	return WosClient{}.v4Auth(ak, sk, region, method, canonicalizedURL, queryURL, headers, false)
+}
+
+func (wosClient WosClient) v4Auth(ak, sk, region, method, canonicalizedURL, queryURL string, headers map[string][]string, isWos bool) map[string]string {
+	t := getV4Date(headers, isWos)
 	shortDate := t.Format(SHORT_DATE_FORMAT)
 	longDate := t.Format(LONG_DATE_FORMAT)
 
@@ -488,10 +466,14 @@ func v4Auth(ak, sk, region, method, canonicalizedURL, queryURL string, headers m
 	credential, scope := getCredential(ak, region, shortDate, isWos)
 
 	payload := UNSIGNED_PAYLOAD
-	if val, ok := headers[HEADER_CONTENT_SHA256_AMZ]; ok {
+	if isWos {
+		if val, ok := headers[HEADER_CONTENT_SHA256_WOS]; ok {
+			payload = val[0]
+		}
+	} else if val, ok := headers[HEADER_CONTENT_SHA256_AMZ]; ok {
 		payload = val[0]
 	}
-	stringToSign := getV4StringToSign(method, canonicalizedURL, queryURL, scope, longDate, payload, signedHeaders, _headers, isWos)
+	stringToSign := wosClient.getV4StringToSign(method, canonicalizedURL, queryURL, scope, longDate, payload, signedHeaders, _headers, isWos)
 
 	signature := getSignature(stringToSign, sk, region, shortDate, isWos)
 
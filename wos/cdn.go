@@ -0,0 +1,214 @@
+package wos
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CdnTokenType defines the type of the anti-leech token of the Wangsu CDN
+type CdnTokenType string
+
+const (
+	// CdnTokenTypePath embeds the token in the path: /{time}/{md5(key + path + time)}/path.
+	CdnTokenTypePath CdnTokenType = "path"
+	// CdnTokenTypeQuery appends the token to the query: ?wsSecret={md5(key + path + wsTime)}&wsTime={time}.
+	CdnTokenTypeQuery CdnTokenType = "query"
+
+	// DEFAULT_CDN_URL_EXPIRES is the duration in which the CDN urls expire by default.
+	DEFAULT_CDN_URL_EXPIRES = time.Hour
+
+	cdnSecretParam = "wsSecret"
+	cdnTimeParam   = "wsTime"
+)
+
+// CdnUrlSigner signs the urls of the objects served by the Wangsu CDN with the timestamp anti-leech tokens.
+//
+// The time of the token is the expiration of the url, in decimal or hexadecimal Unix seconds as configured on the
+// CDN. The urls are signed with the primary key, the CDN accepts the backup key as well during a key rotation.
+type CdnUrlSigner struct {
+	TokenType CdnTokenType
+	// Expires is the duration in which the urls expire, DEFAULT_CDN_URL_EXPIRES if not positive.
+	Expires time.Duration
+	// HexTime formats the time of the token in hexadecimal.
+	HexTime bool
+	// Domain is the scheme and the host of the urls built by SignObjectUrl, such as https://cdn.example.com, it is
+	// the custom domain of the client by default. Domains overrides it for the buckets served by other domains.
+	Domain  string
+	Domains map[string]string
+	// Now returns the current time, time.Now is used if nil.
+	Now func() time.Time
+
+	conf       *config
+	lock       sync.RWMutex
+	primaryKey string
+	backupKey  string
+}
+
+// NewCdnUrlSigner creates a new CdnUrlSigner instance with the primary and the backup keys configured on the CDN, the
+// urls are built with the custom domain set by WithCustomDomainName.
+func (wosClient WosClient) NewCdnUrlSigner(tokenType CdnTokenType, primaryKey, backupKey string) (*CdnUrlSigner, error) {
+	if tokenType != CdnTokenTypePath && tokenType != CdnTokenTypeQuery {
+		return nil, fmt.Errorf("The token type %s is not supported", tokenType)
+	}
+	if primaryKey == "" {
+		return nil, errors.New("Primary key is empty")
+	}
+	signer := &CdnUrlSigner{TokenType: tokenType, conf: wosClient.conf, primaryKey: primaryKey, backupKey: backupKey}
+	if urlHolder := wosClient.conf.urlHolder; wosClient.conf.cname {
+		signer.Domain = urlHolder.scheme + "://" + urlHolder.host
+		if (urlHolder.scheme == "https" && urlHolder.port != 443) || (urlHolder.scheme == "http" && urlHolder.port != 80) {
+			signer.Domain += ":" + IntToString(urlHolder.port)
+		}
+	}
+	return signer, nil
+}
+
+// RotateKey makes the primary key the backup key and signs the urls with the new primary key.
+func (signer *CdnUrlSigner) RotateKey(primaryKey string) error {
+	if primaryKey == "" {
+		return errors.New("Primary key is empty")
+	}
+	signer.lock.Lock()
+	defer signer.lock.Unlock()
+	signer.backupKey, signer.primaryKey = signer.primaryKey, primaryKey
+	return nil
+}
+
+func (signer *CdnUrlSigner) keys() (primaryKey, backupKey string) {
+	signer.lock.RLock()
+	defer signer.lock.RUnlock()
+	return signer.primaryKey, signer.backupKey
+}
+
+func (signer *CdnUrlSigner) now() time.Time {
+	if signer.Now != nil {
+		return signer.Now()
+	}
+	return time.Now()
+}
+
+func (signer *CdnUrlSigner) formatTime(t time.Time) string {
+	if signer.HexTime {
+		return strconv.FormatInt(t.Unix(), 16)
+	}
+	return Int64ToString(t.Unix())
+}
+
+func (signer *CdnUrlSigner) parseTime(value string) (time.Time, error) {
+	base := 10
+	if signer.HexTime {
+		base = 16
+	}
+	seconds, err := strconv.ParseInt(value, base, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("The time %s of the token is malformed", value)
+	}
+	return time.Unix(seconds, 0), nil
+}
+
+func getCdnToken(key, path, timestamp string) string {
+	return HexMd5([]byte(key + path + timestamp))
+}
+
+// SignObjectUrl builds the CDN url of the object with the domain of the bucket and signs it.
+func (signer *CdnUrlSigner) SignObjectUrl(bucketName, objectKey string) (string, error) {
+	domain := signer.Domain
+	if _domain, ok := signer.Domains[bucketName]; ok {
+		domain = _domain
+	}
+	if domain == "" {
+		return "", fmt.Errorf("No CDN domain is configured for the bucket %s", bucketName)
+	}
+	if !strings.Contains(domain, "://") {
+		domain = "https://" + domain
+	}
+	path := signer.conf.prepareObjectKey(true, strings.TrimPrefix(objectKey, "/"), nil)
+	return signer.SignUrl(strings.TrimSuffix(domain, "/") + "/" + path)
+}
+
+// SignUrl signs a CDN url, the token is computed over the escaped path of the url.
+func (signer *CdnUrlSigner) SignUrl(rawURL string) (string, error) {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	primaryKey, _ := signer.keys()
+	expires := signer.Expires
+	if expires <= 0 {
+		expires = DEFAULT_CDN_URL_EXPIRES
+	}
+	timestamp := signer.formatTime(signer.now().Add(expires))
+	path := parsedURL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	token := getCdnToken(primaryKey, path, timestamp)
+
+	if signer.TokenType == CdnTokenTypePath {
+		signedURL := *parsedURL
+		signedURL.RawPath = "/" + timestamp + "/" + token + path
+		if signedURL.Path, err = url.PathUnescape(signedURL.RawPath); err != nil {
+			return "", err
+		}
+		return signedURL.String(), nil
+	}
+	if parsedURL.RawQuery != "" {
+		rawURL += "&"
+	} else if !strings.HasSuffix(rawURL, "?") {
+		rawURL += "?"
+	}
+	return rawURL + cdnSecretParam + "=" + token + "&" + cdnTimeParam + "=" + timestamp, nil
+}
+
+// VerifyUrl verifies the token of a url signed with the primary or the backup key and not expired at now, which
+// helps to test the configuration of the CDN and the origin servers behind it.
+func (signer *CdnUrlSigner) VerifyUrl(signedURL string, now time.Time) error {
+	parsedURL, err := url.Parse(signedURL)
+	if err != nil {
+		return err
+	}
+	var path, timestamp, token string
+	if signer.TokenType == CdnTokenTypePath {
+		parts := strings.SplitN(parsedURL.EscapedPath(), "/", 4)
+		if len(parts) != 4 {
+			return errors.New("The path of the url does not contain the token")
+		}
+		timestamp, token, path = parts[1], parts[2], "/"+parts[3]
+	} else {
+		query := parsedURL.Query()
+		timestamp, token, path = query.Get(cdnTimeParam), query.Get(cdnSecretParam), parsedURL.EscapedPath()
+		if timestamp == "" || token == "" {
+			return errors.New("The query of the url does not contain the token")
+		}
+		if path == "" {
+			path = "/"
+		}
+	}
+	expiration, err := signer.parseTime(timestamp)
+	if err != nil {
+		return err
+	}
+	if !now.Before(expiration) {
+		return fmt.Errorf("The url is expired at %s", expiration.UTC().Format(ISO8601_DATE_FORMAT))
+	}
+	primaryKey, backupKey := signer.keys()
+	token = strings.ToLower(token)
+	if matchCdnToken(token, getCdnToken(primaryKey, path, timestamp)) {
+		return nil
+	}
+	if backupKey != "" && matchCdnToken(token, getCdnToken(backupKey, path, timestamp)) {
+		return nil
+	}
+	return errors.New("The token of the url does not match")
+}
+
+// matchCdnToken compares the lower-case hex tokens in constant time, which does not leak the matched prefix.
+func matchCdnToken(token, expected string) bool {
+	return subtle.ConstantTimeCompare([]byte(token), []byte(strings.ToLower(expected))) == 1
+}
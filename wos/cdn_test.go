@@ -0,0 +1,68 @@
+package wos
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCdnUrlSignerVerifyUrl(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	cases := []struct {
+		name      string
+		tokenType CdnTokenType
+		hexTime   bool
+		// modify changes the signed url and the signer before the url is verified.
+		modify func(signedURL string, signer *CdnUrlSigner) string
+		at     time.Time
+		valid  bool
+	}{
+		{name: "path", tokenType: CdnTokenTypePath, at: now, valid: true},
+		{name: "query", tokenType: CdnTokenTypeQuery, at: now, valid: true},
+		{name: "hex time", tokenType: CdnTokenTypeQuery, hexTime: true, at: now, valid: true},
+		{name: "upper-case token", tokenType: CdnTokenTypeQuery, at: now, valid: true,
+			modify: func(signedURL string, signer *CdnUrlSigner) string {
+				index := strings.Index(signedURL, cdnSecretParam+"=") + len(cdnSecretParam) + 1
+				return signedURL[:index] + strings.ToUpper(signedURL[index:index+32]) + signedURL[index+32:]
+			}},
+		{name: "tampered token", tokenType: CdnTokenTypeQuery, at: now,
+			modify: func(signedURL string, signer *CdnUrlSigner) string {
+				return strings.Replace(signedURL, cdnSecretParam+"=", cdnSecretParam+"=0", 1)
+			}},
+		{name: "tampered path", tokenType: CdnTokenTypePath, at: now,
+			modify: func(signedURL string, signer *CdnUrlSigner) string {
+				return strings.Replace(signedURL, "/object", "/other", 1)
+			}},
+		{name: "backup key", tokenType: CdnTokenTypePath, at: now, valid: true,
+			modify: func(signedURL string, signer *CdnUrlSigner) string {
+				signer.RotateKey("new-key")
+				return signedURL
+			}},
+		{name: "rotated out key", tokenType: CdnTokenTypePath, at: now,
+			modify: func(signedURL string, signer *CdnUrlSigner) string {
+				signer.RotateKey("new-key")
+				signer.RotateKey("newer-key")
+				return signedURL
+			}},
+		{name: "expired", tokenType: CdnTokenTypeQuery, at: now.Add(DEFAULT_CDN_URL_EXPIRES)},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			signer, err := WosClient{conf: &config{urlHolder: &urlHolder{}}}.NewCdnUrlSigner(c.tokenType, "key", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			signer.HexTime, signer.Now = c.hexTime, func() time.Time { return now }
+			signedURL, err := signer.SignUrl("http://cdn.example.com/bucket/object?a=b")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.modify != nil {
+				signedURL = c.modify(signedURL, signer)
+			}
+			if err = signer.VerifyUrl(signedURL, c.at); (err == nil) != c.valid {
+				t.Errorf("VerifyUrl(%s) error = %v, want valid %t", signedURL, err, c.valid)
+			}
+		})
+	}
+}
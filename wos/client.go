@@ -1,6 +1,8 @@
 package wos
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -8,12 +10,34 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"time"
 )
 
 // WosClient defines WOS client.
 type WosClient struct {
 	conf       *config
 	httpClient *http.Client
+	// call describes the operation in progress, it is set on the copy of the client made for each call.
+	call callInfo
+}
+
+type callInfo struct {
+	action      string
+	bucketName  string
+	objectKey   string
+	diagnostics *Diagnostics
+	// clientRequestID is the id generated by the client or set by WithClientRequestID to correlate the logs.
+	clientRequestID string
+	// transfer reports the progress and limits the rate of the bodies.
+	transfer *transferControl
+	// payloadHash is the SHA-256 of the body signed instead of the UNSIGNED-PAYLOAD.
+	payloadHash string
+	// streaming signs the chunks of a non-seekable body set by WithStreamingSigning.
+	streaming *streamingSigner
+	// signingTime pins the time at which the signed URLs of a batch are signed, so that they expire together.
+	signingTime time.Time
+	// security is the snapshot of the credentials shared by the signed URLs of a batch.
+	security *securityHolder
 }
 
 // New creates a new WosClient instance.
@@ -24,6 +48,9 @@ func New(ak, sk, endpoint string, configurers ...configurer) (*WosClient, error)
 
 	conf.maxRetryCount = -1
 	conf.maxRedirectCount = -1
+	conf.retryTokenBucket = NewRetryTokenBucket(DEFAULT_RETRY_TOKEN_CAPACITY, DEFAULT_RETRY_COST, DEFAULT_RETRY_TIMEOUT_COST)
+	conf.clockSkew = &clockSkew{}
+	conf.signingKeys = &signingKeyCache{}
 	for _, configurer := range configurers {
 		configurer(conf)
 	}
@@ -31,12 +58,18 @@ func New(ak, sk, endpoint string, configurers ...configurer) (*WosClient, error)
 	if err := conf.initConfigWithDefault(); err != nil {
 		return nil, err
 	}
-	err := conf.getTransport()
-	if err != nil {
+	if err := conf.checkTransportOptions(); err != nil {
 		return nil, err
 	}
+	if conf.httpClient == nil && conf.roundTripper == nil {
+		err := conf.getTransport()
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	if isWarnLogEnabled() {
+	logClient := WosClient{conf: conf}
+	if logClient.logEnabled(LEVEL_WARN) {
 		info := make([]string, 3)
 		info[0] = fmt.Sprintf("[WOS SDK Version=%s", wosSdkVersion)
 		info[1] = fmt.Sprintf("Endpoint=%s", conf.endpoint)
@@ -45,10 +78,16 @@ func New(ak, sk, endpoint string, configurers ...configurer) (*WosClient, error)
 			accessMode = "Path"
 		}
 		info[2] = fmt.Sprintf("Access Mode=%s]", accessMode)
-		doLog(LEVEL_WARN, strings.Join(info, "];["))
+		logClient.log(LEVEL_WARN, strings.Join(info, "];["))
+	}
+	logClient.logf(LEVEL_DEBUG, "Create wosclient with config:\n%s\n", conf)
+	if conf.signedUrlCacheSize > 0 && conf.signedUrlFreshness > 0 {
+		conf.signedUrlCache = newSignedUrlCache(conf.signedUrlCacheSize, conf.signedUrlFreshness)
+	}
+	wosClient := &WosClient{conf: conf, httpClient: conf.getHTTPClient()}
+	if pool := conf.endpointPool; pool != nil && pool.probeBucket != "" && pool.probeInterval > 0 {
+		go pool.probe(*wosClient)
 	}
-	doLog(LEVEL_DEBUG, "Create wosclient with config:\n%s\n", conf)
-	wosClient := &WosClient{conf: conf, httpClient: &http.Client{Transport: conf.transport, CheckRedirect: checkRedirectFunc}}
 	return wosClient, nil
 }
 
@@ -63,12 +102,15 @@ func (wosClient WosClient) Refresh(ak, sk, securityToken string) {
 }
 
 func (wosClient WosClient) getSecurity() securityHolder {
+	if wosClient.call.security != nil {
+		return *wosClient.call.security
+	}
 	if wosClient.conf.securityProviders != nil {
 		for _, sp := range wosClient.conf.securityProviders {
 			if sp == nil {
 				continue
 			}
-			sh := sp.getSecurity()
+			sh := sp.getSecurity(wosClient.getLogger())
 			if sh.ak != "" && sh.sk != "" {
 				return sh
 			}
@@ -79,8 +121,13 @@ func (wosClient WosClient) getSecurity() securityHolder {
 
 // Close closes WosClient.
 func (wosClient WosClient) Close() {
+	if wosClient.conf != nil && wosClient.conf.endpointPool != nil {
+		wosClient.conf.endpointPool.close()
+	}
+	if wosClient.httpClient != nil {
+		wosClient.httpClient.CloseIdleConnections()
+	}
 	wosClient.httpClient = nil
-	wosClient.conf.transport.CloseIdleConnections()
 	wosClient.conf = nil
 }
 
@@ -140,7 +187,7 @@ func (wosClient WosClient) ListMultipartUploads(input *ListMultipartUploadsInput
 	} else if output.EncodingType == "url" {
 		err = decodeListMultipartUploadsOutput(output)
 		if err != nil {
-			doLog(LEVEL_ERROR, "Failed to get ListMultipartUploadsOutput with error: %v.", err)
+			wosClient.logf(LEVEL_ERROR, "Failed to get ListMultipartUploadsOutput with error: %v.", err)
 			output = nil
 		}
 	}
@@ -381,7 +428,7 @@ func (wosClient WosClient) PutFile(input *PutFileInput, extensions ...extensionO
 		defer func() {
 			errMsg := fd.Close()
 			if errMsg != nil {
-				doLog(LEVEL_WARN, "Failed to close file with reason: %v", errMsg)
+				wosClient.logf(LEVEL_WARN, "Failed to close file with reason: %v", errMsg)
 			}
 		}()
 
@@ -483,7 +530,7 @@ func (wosClient WosClient) InitiateMultipartUpload(input *InitiateMultipartUploa
 		if output.EncodingType == "url" {
 			err = decodeInitiateMultipartUploadOutput(output)
 			if err != nil {
-				doLog(LEVEL_ERROR, "Failed to get InitiateMultipartUploadOutput with error: %v.", err)
+				wosClient.logf(LEVEL_ERROR, "Failed to get InitiateMultipartUploadOutput with error: %v.", err)
 				output = nil
 			}
 		}
@@ -532,7 +579,7 @@ func (wosClient WosClient) UploadPart(_input *UploadPartInput, extensions ...ext
 		defer func() {
 			errMsg := fd.Close()
 			if errMsg != nil {
-				doLog(LEVEL_WARN, "Failed to close file with reason: %v", errMsg)
+				wosClient.logf(LEVEL_WARN, "Failed to close file with reason: %v", errMsg)
 			}
 		}()
 
@@ -595,7 +642,7 @@ func (wosClient WosClient) CompleteMultipartUpload(input *CompleteMultipartUploa
 		if output.EncodingType == "url" {
 			err = decodeCompleteMultipartUploadOutput(output)
 			if err != nil {
-				doLog(LEVEL_ERROR, "Failed to get CompleteMultipartUploadOutput with error: %v.", err)
+				wosClient.logf(LEVEL_ERROR, "Failed to get CompleteMultipartUploadOutput with error: %v.", err)
 				output = nil
 			}
 		}
@@ -618,7 +665,7 @@ func (wosClient WosClient) ListParts(input *ListPartsInput, extensions ...extens
 	} else if output.EncodingType == "url" {
 		err = decodeListPartsOutput(output)
 		if err != nil {
-			doLog(LEVEL_ERROR, "Failed to get ListPartsOutput with error: %v.", err)
+			wosClient.logf(LEVEL_ERROR, "Failed to get ListPartsOutput with error: %v.", err)
 			output = nil
 		}
 	}
@@ -671,7 +718,16 @@ func (wosClient WosClient) UploadFile(input *UploadFileInput, extensions ...exte
 		input.PartSize = MAX_PART_SIZE
 	}
 
+	wosClient, err = wosClient.withExtensionConfigs(extensions)
+	if err != nil {
+		return nil, err
+	}
+	wosClient, span := wosClient.startSpan("UploadFile", "", input.Bucket, input.Key, nil)
+	// the operations of the parts are traced as children of the UploadFile span
+	wosClient, extensions, cancel := wosClient.withTransferCall(extensions)
+	defer cancel()
 	output, err = wosClient.resumeUpload(input, extensions)
+	endSpan(span, nil, err)
 	return
 }
 
@@ -695,7 +751,16 @@ func (wosClient WosClient) DownloadFile(input *DownloadFileInput, extensions ...
 		input.PartSize = DEFAULT_PART_SIZE
 	}
 
+	wosClient, err = wosClient.withExtensionConfigs(extensions)
+	if err != nil {
+		return nil, err
+	}
+	wosClient, span := wosClient.startSpan("DownloadFile", "", input.Bucket, input.Key, nil)
+	// the operations of the parts are traced as children of the DownloadFile span
+	wosClient, extensions, cancel := wosClient.withTransferCall(extensions)
+	defer cancel()
 	output, err = wosClient.resumeDownload(input, extensions)
+	endSpan(span, nil, err)
 	return
 }
 
@@ -785,3 +850,37 @@ func (wosClient WosClient) GetBucketFetchJob(input *GetBucketFetchJobInput, exte
 	}
 	return
 }
+
+// Do sends a request which is not wrapped by the SDK yet.
+//
+// The request is signed, retried, redirected and passed through the middlewares as the other APIs,
+// the method, such as PATCH which is not used by the other APIs, the params and the headers are sent as is. The caller must close the body of the returned response,
+// a response with status code 300 or above is returned as a WosError.
+// The request is retried only if the body is nil, a *bytes.Reader or a *strings.Reader.
+func (wosClient WosClient) Do(ctx context.Context, input RawRequest, extensions ...extensionOptions) (*http.Response, error) {
+	method := strings.ToUpper(strings.TrimSpace(input.Method))
+	if method == "" {
+		return nil, errors.New("Method is empty")
+	}
+	if strings.TrimSpace(input.Key) != "" && strings.TrimSpace(input.Bucket) == "" && !wosClient.conf.cname {
+		return nil, errors.New("Bucket is empty")
+	}
+	_extensions := make([]extensionOptions, 0, len(extensions)+3)
+	if ctx != nil {
+		_extensions = append(_extensions, WithContext(ctx))
+	}
+	_extensions = append(_extensions, withRequestHeaders(input.Headers), withRequestParams(input.Params))
+	_extensions = append(_extensions, extensions...)
+
+	var data interface{}
+	repeatable := true
+	if input.Body != nil {
+		data = input.Body
+		switch input.Body.(type) {
+		case *bytes.Reader, *strings.Reader:
+		default:
+			repeatable = false
+		}
+	}
+	return wosClient.doRequest("Do", method, input.Bucket, input.Key, &DefaultSerializable{data: data}, nil, false, repeatable, _extensions)
+}
@@ -0,0 +1,127 @@
+package wos
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestClient creates a client of the test server with path style addressing and fast retries.
+func newTestClient(t *testing.T, endpoint string, configurers ...configurer) *WosClient {
+	t.Helper()
+	policy := NewStandardRetryPolicy()
+	policy.BaseDelay, policy.MaxDelay = time.Millisecond, time.Millisecond
+	configurers = append([]configurer{WithPathStyle(true), WithRetryPolicy(policy)}, configurers...)
+	client, err := New("ak", "sk", endpoint, configurers...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
+
+func TestDo(t *testing.T) {
+	cases := []struct {
+		name string
+		// handle responds to the attempt-th request
+		handle   func(w http.ResponseWriter, r *http.Request, attempt int32)
+		attempts int32
+		wantCode string
+	}{
+		{name: "success", attempts: 1, handle: func(w http.ResponseWriter, r *http.Request, attempt int32) {
+			w.Write([]byte("done"))
+		}},
+		{name: "retry", attempts: 2, handle: func(w http.ResponseWriter, r *http.Request, attempt int32) {
+			if attempt == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte("done"))
+		}},
+		{name: "redirect", attempts: 2, handle: func(w http.ResponseWriter, r *http.Request, attempt int32) {
+			if attempt == 1 {
+				// the request is signed again for the host redirected to
+				w.Header().Set("Location", "http://"+strings.Replace(r.Host, "127.0.0.1", "localhost", 1)+r.URL.RequestURI())
+				w.WriteHeader(http.StatusTemporaryRedirect)
+				return
+			}
+			if !strings.HasPrefix(r.Host, "localhost:") {
+				t.Errorf("redirected request to %s", r.Host)
+			}
+			w.Write([]byte("done"))
+		}},
+		{name: "error", attempts: 1, wantCode: "AccessDenied", handle: func(w http.ResponseWriter, r *http.Request, attempt int32) {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("<Error><Code>AccessDenied</Code><Message>denied</Message></Error>"))
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var requests int32
+			verifier := NewVerifier(testCredentials, "")
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if _, err := verifier.Verify(r); err != nil {
+					t.Errorf("Verify error = %v", err)
+				}
+				body, _ := ioutil.ReadAll(r.Body)
+				// the method, the params and the headers are sent as is
+				if r.Method != "PATCH" || r.URL.Path != "/bucket/key" || !r.URL.Query().Has("tagging") || r.URL.Query().Get("version") != "1" ||
+					r.Header.Get("X-Custom") != "custom" || string(body) != "hello" {
+					t.Errorf("request %s %s %v with body %q", r.Method, r.URL, r.Header, body)
+				}
+				c.handle(w, r, atomic.AddInt32(&requests, 1))
+			}))
+			defer server.Close()
+			client := newTestClient(t, server.URL, WithSignature(SignatureWos))
+
+			input := RawRequest{Method: "patch", Bucket: "bucket", Key: "key", Params: map[string]string{"tagging": ""},
+				Headers: map[string][]string{"X-Custom": {"custom"}}, Body: strings.NewReader("hello")}
+			resp, err := client.Do(context.Background(), input, WithQueryParam("version", "1"))
+			if got := atomic.LoadInt32(&requests); got != c.attempts {
+				t.Errorf("%d requests, want %d", got, c.attempts)
+			}
+			if c.wantCode != "" {
+				wosError, ok := err.(WosError)
+				if !ok || wosError.Code != c.wantCode || wosError.Message != "denied" || wosError.StatusCode != http.StatusForbidden {
+					t.Errorf("Do error = %v, want WosError %s", err, c.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if body, _ := ioutil.ReadAll(resp.Body); resp.StatusCode != http.StatusOK || string(body) != "done" {
+				t.Errorf("response %d with body %q", resp.StatusCode, body)
+			}
+		})
+	}
+}
+
+func TestWithQueryParam(t *testing.T) {
+	var query url.Values
+	verifier := NewVerifier(testCredentials, "")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := verifier.Verify(r); err != nil {
+			t.Errorf("Verify error = %v", err)
+		}
+		query = r.URL.Query()
+	}))
+	defer server.Close()
+	client := newTestClient(t, server.URL)
+
+	// the sub-resource with an empty value is signed as the other params
+	if _, err := client.HeadObject(&HeadObjectInput{Bucket: "bucket", Key: "key"}, WithQueryParam("versionId", "v1"), WithQueryParam("acl", "")); err != nil {
+		t.Fatal(err)
+	}
+	if query.Get("versionId") != "v1" || !query.Has("acl") {
+		t.Errorf("query %v", query)
+	}
+}
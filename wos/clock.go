@@ -0,0 +1,91 @@
+package wos
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// clockSkewThreshold is the offset below which the local clock is used as is, the Date header of the
+	// response is in seconds and is received after the latency of the request.
+	clockSkewThreshold = 5 * time.Second
+	// errorCodeRequestTimeTooSkewed is the code of the error returned if the signing date is too skewed.
+	errorCodeRequestTimeTooSkewed = "RequestTimeTooSkewed"
+)
+
+// clockSkew holds the offset between the server clock and the local clock, it is shared by the copies of the config.
+type clockSkew struct {
+	offset int64
+}
+
+func (c *clockSkew) get() time.Duration {
+	if c == nil {
+		return 0
+	}
+	return time.Duration(atomic.LoadInt64(&c.offset))
+}
+
+// observe updates the offset with the Date header of the response, and reports whether it is changed significantly.
+func (c *clockSkew) observe(resp *http.Response) (time.Duration, bool) {
+	if c == nil || resp == nil {
+		return 0, false
+	}
+	value := resp.Header.Get(HEADER_DATE_CAMEL)
+	if value == "" {
+		return 0, false
+	}
+	serverTime, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	// the server time is truncated to seconds
+	offset := serverTime.Add(500 * time.Millisecond).Sub(time.Now())
+	if offset > -clockSkewThreshold && offset < clockSkewThreshold {
+		offset = 0
+	}
+	previous := time.Duration(atomic.SwapInt64(&c.offset, int64(offset)))
+	diff := offset - previous
+	return offset, diff <= -clockSkewThreshold || diff >= clockSkewThreshold
+}
+
+func (wosClient WosClient) observeClockSkew(resp *http.Response) {
+	if offset, changed := wosClient.conf.clockSkew.observe(resp); changed {
+		wosClient.logf(LEVEL_WARN, "The offset between the server clock and the local clock is changed to %v", offset)
+	}
+}
+
+// now returns the current time corrected with the offset of the server clock in UTC, or the signing time pinned
+// for the call.
+func (wosClient WosClient) now() time.Time {
+	if !wosClient.call.signingTime.IsZero() {
+		return wosClient.call.signingTime
+	}
+	return time.Now().Add(wosClient.conf.clockSkew.get()).UTC()
+}
+
+// ClockOffset returns the offset between the server clock and the local clock detected from the Date header of
+// the responses, it is added to the local clock to sign the requests, the signed URLs and the browser based policies.
+func (wosClient WosClient) ClockOffset() time.Duration {
+	if wosClient.conf == nil {
+		return 0
+	}
+	return wosClient.conf.clockSkew.get()
+}
+
+func isClockSkewError(err error) bool {
+	wosError, ok := err.(WosError)
+	return ok && wosError.Code == errorCodeRequestTimeTooSkewed
+}
+
+// resetDateHeaders removes the Date header and the x-amz-date or x-wos-date header of a request,
+// so that it is re-signed with the corrected time instead of the skewed one.
+func resetDateHeaders(headers map[string][]string) {
+	for key := range headers {
+		switch strings.ToLower(key) {
+		case strings.ToLower(HEADER_DATE_CAMEL), HEADER_DATE_AMZ, HEADER_DATE_WOS:
+			delete(headers, key)
+		}
+	}
+}
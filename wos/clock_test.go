@@ -0,0 +1,48 @@
+package wos
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResetDateHeaders(t *testing.T) {
+	headers := map[string][]string{
+		HEADER_DATE_CAMEL:  {"Mon, 01 Jan 2024 00:00:00 GMT"},
+		"X-Amz-Date":       {"20240101T000000Z"},
+		HEADER_DATE_WOS:    {"20240101T000000Z"},
+		HEADER_HOST_CAMEL:  {"wos.example.com"},
+		HEADER_PREFIX_META: {"date"},
+	}
+	resetDateHeaders(headers)
+	if len(headers) != 2 || headers[HEADER_HOST_CAMEL] == nil || headers[HEADER_PREFIX_META] == nil {
+		t.Errorf("headers = %v, want only the date headers removed", headers)
+	}
+}
+
+func TestClockSkewRetryResigns(t *testing.T) {
+	stale := time.Now().Add(-2 * time.Hour).UTC().Format(LONG_DATE_FORMAT)
+	var dates []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dates = append(dates, r.Header.Get(HEADER_DATE_AMZ)+"|"+r.Header.Get(HEADER_DATE_CAMEL))
+		if len(dates) == 1 {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("<Error><Code>RequestTimeTooSkewed</Code></Error>"))
+		}
+	}))
+	defer server.Close()
+	client := newTestClient(t, server.URL, WithSignature(SignatureV4), WithMaxRetryCount(0))
+
+	input := &DeleteObjectInput{Bucket: "bucket", Key: "key"}
+	if _, err := client.DeleteObject(input, WithHeader(HEADER_DATE_AMZ, stale)); err != nil {
+		t.Fatal(err)
+	}
+	if len(dates) != 2 {
+		t.Fatalf("%d requests are sent, want 2", len(dates))
+	}
+	if !strings.Contains(dates[0], stale) || strings.Contains(dates[1], stale) {
+		t.Errorf("the skew retry is signed with the stale date: %v", dates)
+	}
+}
@@ -22,28 +22,70 @@ type urlHolder struct {
 }
 
 type config struct {
-	securityProviders []securityProvider
-	urlHolder         *urlHolder
-	pathStyle         bool
-	cname             bool
-	sslVerify         bool
-	endpoint          string
-	signature         SignatureType
-	region            string
-	connectTimeout    int
-	socketTimeout     int
-	headerTimeout     int
-	idleConnTimeout   int
-	finalTimeout      int
-	maxRetryCount     int
-	proxyURL          string
-	maxConnsPerHost   int
-	pemCerts          []byte
-	transport         *http.Transport
-	ctx               context.Context
-	maxRedirectCount  int
-	userAgent         string
-	enableCompression bool
+	securityProviders        []securityProvider
+	urlHolder                *urlHolder
+	pathStyle                bool
+	cname                    bool
+	sslVerify                bool
+	endpoint                 string
+	signature                SignatureType
+	region                   string
+	connectTimeout           int
+	socketTimeout            int
+	headerTimeout            int
+	idleConnTimeout          int
+	finalTimeout             int
+	maxRetryCount            int
+	proxyURL                 string
+	maxConnsPerHost          int
+	pemCerts                 []byte
+	transport                *http.Transport
+	ctx                      context.Context
+	maxRedirectCount         int
+	userAgent                string
+	enableCompression        bool
+	retryPolicy              RetryPolicy
+	retryTokenBucket         *RetryTokenBucket
+	middlewares              []Middleware
+	metrics                  MetricsCollector
+	tracer                   Tracer
+	traceInjection           bool
+	logger                   Logger
+	redactor                 *redactor
+	wireDump                 bool
+	wireDumpBodyBytes        int
+	clientRequestID          string
+	requestHeaders           map[string][]string
+	requestParams            map[string]string
+	timeout                  time.Duration
+	deadline                 time.Time
+	progressListener         ProgressListener
+	rateLimit                int64
+	rateLimiter              *rateLimiter
+	httpClient               *http.Client
+	roundTripper             http.RoundTripper
+	dialContext              func(ctx context.Context, network, addr string) (net.Conn, error)
+	clientCertPEM            []byte
+	clientKeyPEM             []byte
+	proxyUsername            string
+	proxyPassword            string
+	noProxy                  []string
+	endpoints                []string
+	endpointStrategy         EndpointStrategy
+	endpointFailureThreshold int
+	endpointEjectDuration    time.Duration
+	endpointProbeBucket      string
+	endpointProbeInterval    time.Duration
+	endpointPool             *endpointPool
+	endpointPinned           bool
+	pathStylePinned          bool
+	payloadSigning           bool
+	streamingChunkSize       int
+	clockSkew                *clockSkew
+	signingKeys              *signingKeyCache
+	signedUrlCacheSize       int
+	signedUrlFreshness       time.Duration
+	signedUrlCache           *signedUrlCache
 }
 
 func (conf config) String() string {
@@ -88,13 +130,47 @@ func WithHeaderTimeout(headerTimeout int) configurer {
 	}
 }
 
-// WithProxyUrl is a configurer for WosClient to set HTTP proxy.
+// WithProxyUrl is a configurer for WosClient to set HTTP proxy, a SOCKS5 proxy is set with the socks5 scheme,
+// such as socks5://127.0.0.1:1080.
 func WithProxyUrl(proxyURL string) configurer {
 	return func(conf *config) {
 		conf.proxyURL = proxyURL
 	}
 }
 
+// WithProxyCredentials is a configurer for WosClient to set the username and the password of the proxy.
+func WithProxyCredentials(username, password string) configurer {
+	return func(conf *config) {
+		conf.proxyUsername = username
+		conf.proxyPassword = password
+	}
+}
+
+// WithNoProxy is a configurer for WosClient to set the hosts accessed without the proxy in the format of
+// the NO_PROXY environment variable, which is used if it is not set.
+func WithNoProxy(noProxy ...string) configurer {
+	return func(conf *config) {
+		conf.noProxy = append(make([]string, 0, len(noProxy)), noProxy...)
+	}
+}
+
+// WithDialContext is a configurer for WosClient to set the dial function of the transport, such as dialing
+// a Unix socket or a pinned IP. The connect timeout and the socket timeout are still applied.
+func WithDialContext(dialContext func(ctx context.Context, network, addr string) (net.Conn, error)) configurer {
+	return func(conf *config) {
+		conf.dialContext = dialContext
+	}
+}
+
+// WithClientCertificate is a configurer for WosClient to set the PEM encoded certificate and private key
+// presented to the server for the mutual TLS authentication.
+func WithClientCertificate(certPEM, keyPEM []byte) configurer {
+	return func(conf *config) {
+		conf.clientCertPEM = certPEM
+		conf.clientKeyPEM = keyPEM
+	}
+}
+
 // WithMaxConnections is a configurer for WosClient to set the maximum number of idle HTTP connections.
 func WithMaxConnections(maxConnsPerHost int) configurer {
 	return func(conf *config) {
@@ -116,6 +192,42 @@ func WithSignature(signature SignatureType) configurer {
 	}
 }
 
+// WithPayloadSigning is a configurer for WosClient to sign the SHA-256 of the request body with the V4 and WOS
+// signatures instead of UNSIGNED-PAYLOAD. The body of bytes, string or file is read once more to compute the hash,
+// a non-seekable body is sent with UNSIGNED-PAYLOAD unless WithStreamingSigning is set.
+func WithPayloadSigning(payloadSigning bool) configurer {
+	return func(conf *config) {
+		conf.payloadSigning = payloadSigning
+	}
+}
+
+// WithStreamingSigning is a configurer for WosClient to send a non-seekable body, such as the Body of PutObject
+// and UploadPart with an unknown length, in the aws-chunked encoding with the V4 and WOS signatures. Each chunk of
+// chunkSize bytes is signed with the signature of the previous one, and the CRC32 of the body is sent in a signed
+// trailer. The default chunk size is used if chunkSize is not positive. Only PutObject and UploadPart send such a
+// body, AppendObject is not provided by this package.
+func WithStreamingSigning(chunkSize int) configurer {
+	return func(conf *config) {
+		if chunkSize <= 0 {
+			chunkSize = DEFAULT_STREAMING_CHUNK_SIZE
+		} else if chunkSize < MIN_STREAMING_CHUNK_SIZE {
+			chunkSize = MIN_STREAMING_CHUNK_SIZE
+		}
+		conf.streamingChunkSize = chunkSize
+	}
+}
+
+// WithSignedUrlCache is a configurer for WosClient to cache at most capacity signed urls, CreateSignedUrl and
+// CreateSignedUrls return the same url for the identical inputs signed within the freshness, so that the responses
+// of the url can be cached by the CDN. The freshness should be shorter than the Expires of the inputs, the url is
+// not returned once expired.
+func WithSignedUrlCache(capacity int, freshness time.Duration) configurer {
+	return func(conf *config) {
+		conf.signedUrlCacheSize = capacity
+		conf.signedUrlFreshness = freshness
+	}
+}
+
 // WithRegion is a configurer for WosClient.
 func WithRegion(region string) configurer {
 	return func(conf *config) {
@@ -154,13 +266,136 @@ func WithMaxRetryCount(maxRetryCount int) configurer {
 	}
 }
 
-// WithHttpTransport is a configurer for WosClient to set the customized http Transport.
+// WithRetryPolicy is a configurer for WosClient to set the policy deciding whether and when a failed request is retried.
+// The number of retries is still limited by WithMaxRetryCount.
+func WithRetryPolicy(retryPolicy RetryPolicy) configurer {
+	return func(conf *config) {
+		conf.retryPolicy = retryPolicy
+	}
+}
+
+// WithRetryTokenBucket is a configurer for WosClient to set the token bucket shared by all retries of the client.
+// Passing nil disables the limit.
+func WithRetryTokenBucket(retryTokenBucket *RetryTokenBucket) configurer {
+	return func(conf *config) {
+		conf.retryTokenBucket = retryTokenBucket
+	}
+}
+
+// WithMiddleware is a configurer for WosClient to add middlewares, which are called in the order they are added.
+func WithMiddleware(middlewares ...Middleware) configurer {
+	return func(conf *config) {
+		for _, middleware := range middlewares {
+			if middleware != nil {
+				conf.middlewares = append(conf.middlewares, middleware)
+			}
+		}
+	}
+}
+
+// WithMetrics is a configurer for WosClient to set the collector of the request metrics,
+// NewInMemoryMetricsCollector creates one which can be exported in the Prometheus text format.
+func WithMetrics(metrics MetricsCollector) configurer {
+	return func(conf *config) {
+		conf.metrics = metrics
+	}
+}
+
+// WithTracer is a configurer for WosClient to set the tracer starting the spans of the operations and the HTTP attempts.
+// If injectTraceContext is true, the trace context is injected into the request headers by Tracer.Inject,
+// otherwise no trace context is propagated to the server.
+func WithTracer(tracer Tracer, injectTraceContext bool) configurer {
+	return func(conf *config) {
+		conf.tracer = tracer
+		conf.traceInjection = injectTraceContext
+	}
+}
+
+// WithLogger is a configurer for WosClient to set the logger of the client, which overrides the global logger set by InitLog.
+func WithLogger(logger Logger) configurer {
+	return func(conf *config) {
+		conf.logger = logger
+	}
+}
+
+// WithRedaction is a configurer for WosClient to mask the values of the headers and the query parameters in the logs,
+// in addition to Authorization, the security token, the SSE-C keys and the signatures which are always masked.
+func WithRedaction(headers []string, queryParams []string) configurer {
+	return func(conf *config) {
+		if conf.redactor == nil {
+			conf.redactor = defaultRedactor
+		}
+		conf.redactor = conf.redactor.with(headers, queryParams)
+	}
+}
+
+// WithWireDump is a configurer for WosClient to log the request line, the headers and the first maxBodyBytes bytes
+// of the bodies of each HTTP attempt and its response at the debug level, the sensitive values are masked.
+func WithWireDump(maxBodyBytes int) configurer {
+	return func(conf *config) {
+		if maxBodyBytes < 0 {
+			maxBodyBytes = 0
+		}
+		conf.wireDump = true
+		conf.wireDumpBodyBytes = maxBodyBytes
+	}
+}
+
+// WithHttpTransport is a configurer for WosClient to set the customized http Transport. New fails if it is used
+// with WithDialContext, WithClientCertificate, WithProxyCredentials or WithNoProxy, which are not applied to it,
+// so are WithHTTPClient and WithRoundTripper.
 func WithHttpTransport(transport *http.Transport) configurer {
 	return func(conf *config) {
 		conf.transport = transport
 	}
 }
 
+// WithHTTPClient is a configurer for WosClient to send the requests with the http Client, its Transport, Timeout
+// and TLS settings are used as is. A copy of the http Client is used, the redirects are handled by WosClient
+// to sign the redirected requests.
+func WithHTTPClient(httpClient *http.Client) configurer {
+	return func(conf *config) {
+		conf.httpClient = httpClient
+	}
+}
+
+// WithRoundTripper is a configurer for WosClient to send the requests with the RoundTripper as is,
+// such as an instrumented or a recording transport. Use SocketTimeoutDialContext in the dialer of
+// the transport to apply the socket timeout.
+func WithRoundTripper(roundTripper http.RoundTripper) configurer {
+	return func(conf *config) {
+		conf.roundTripper = roundTripper
+	}
+}
+
+// WithEndpoints is a configurer for WosClient to set the endpoints used besides the endpoint of the client,
+// the endpoint of each request is selected by the strategy among the healthy endpoints and a failed request
+// is retried on another endpoint. The signed URLs are created with the endpoint of the client.
+func WithEndpoints(strategy EndpointStrategy, endpoints ...string) configurer {
+	return func(conf *config) {
+		conf.endpointStrategy = strategy
+		conf.endpoints = append(make([]string, 0, len(endpoints)), endpoints...)
+	}
+}
+
+// WithEndpointHealth is a configurer for WosClient to eject an endpoint set by WithEndpoints for the ejectDuration
+// after failureThreshold consecutive errors or 5xx responses, the ejected endpoint is used again after the duration.
+func WithEndpointHealth(failureThreshold int, ejectDuration time.Duration) configurer {
+	return func(conf *config) {
+		conf.endpointFailureThreshold = failureThreshold
+		conf.endpointEjectDuration = ejectDuration
+	}
+}
+
+// WithEndpointProbe is a configurer for WosClient to check the endpoints set by WithEndpoints with HeadBucket
+// on the bucket every interval, the probe stops when the client is closed.
+func WithEndpointProbe(bucketName string, interval time.Duration) configurer {
+	return func(conf *config) {
+		conf.endpointProbeBucket = bucketName
+		conf.endpointProbeInterval = interval
+	}
+}
+
 // WithRequestContext is a configurer for WosClient to set the context for each HTTP request.
 func WithRequestContext(ctx context.Context) configurer {
 	return func(conf *config) {
@@ -227,12 +462,40 @@ func (conf *config) prepareConfig() {
 		conf.maxRedirectCount = DEFAULT_MAX_REDIRECT_COUNT
 	}
 
+	if conf.retryPolicy == nil {
+		conf.retryPolicy = NewStandardRetryPolicy()
+	}
+
 	//if conf.pathStyle && conf.signature == SignatureWos {
 	//	conf.signature = SignatureV2
 	//}
 }
 
 func (conf *config) initConfigWithDefault() error {
+	pathStyle := conf.pathStyle
+	if err := conf.initURLHolder(); err != nil {
+		return err
+	}
+
+	if err := conf.initEndpointPool(pathStyle); err != nil {
+		return err
+	}
+
+	if conf.signature == "" {
+		conf.signature = DEFAULT_SIGNATURE
+	}
+
+	conf.region = strings.TrimSpace(conf.region)
+	if conf.region == "" {
+		conf.region = DEFAULT_REGION
+	}
+
+	conf.prepareConfig()
+	conf.proxyURL = strings.TrimSpace(conf.proxyURL)
+	return nil
+}
+
+func (conf *config) initURLHolder() error {
 	conf.endpoint = strings.TrimSpace(conf.endpoint)
 	if conf.endpoint == "" {
 		return errors.New("endpoint is not set")
@@ -242,14 +505,10 @@ func (conf *config) initConfigWithDefault() error {
 		conf.endpoint = conf.endpoint[:index]
 	}
 
-	for strings.LastIndex(conf.endpoint, "/") == len(conf.endpoint)-1 {
+	for conf.endpoint != "" && strings.LastIndex(conf.endpoint, "/") == len(conf.endpoint)-1 {
 		conf.endpoint = conf.endpoint[:len(conf.endpoint)-1]
 	}
 
-	if conf.signature == "" {
-		conf.signature = DEFAULT_SIGNATURE
-	}
-
 	urlHolder := &urlHolder{}
 	var address string
 	if strings.HasPrefix(conf.endpoint, "https://") {
@@ -283,27 +542,52 @@ func (conf *config) initConfigWithDefault() error {
 	}
 
 	conf.urlHolder = urlHolder
+	return nil
+}
 
-	conf.region = strings.TrimSpace(conf.region)
-	if conf.region == "" {
-		conf.region = DEFAULT_REGION
+// checkTransportOptions fails the options of the transport built by WosClient if the transport is set by the caller,
+// instead of ignoring them.
+func (conf *config) checkTransportOptions() error {
+	if conf.transport == nil && conf.httpClient == nil && conf.roundTripper == nil {
+		return nil
+	}
+	options := make([]string, 0, 4)
+	if conf.dialContext != nil {
+		options = append(options, "WithDialContext")
+	}
+	if conf.clientCertPEM != nil || conf.clientKeyPEM != nil {
+		options = append(options, "WithClientCertificate")
+	}
+	if conf.proxyUsername != "" || conf.proxyPassword != "" {
+		options = append(options, "WithProxyCredentials")
+	}
+	if conf.noProxy != nil {
+		options = append(options, "WithNoProxy")
+	}
+	if len(options) > 0 {
+		return fmt.Errorf("%s cannot be used with WithHttpTransport, WithHTTPClient or WithRoundTripper, "+
+			"set them in the transport instead", strings.Join(options, ", "))
 	}
-
-	conf.prepareConfig()
-	conf.proxyURL = strings.TrimSpace(conf.proxyURL)
 	return nil
 }
 
 func (conf *config) getTransport() error {
 	if conf.transport == nil {
+		connectTimeout := time.Second * time.Duration(conf.connectTimeout)
+		dialContext := conf.dialContext
+		if dialContext == nil {
+			dialContext = (&net.Dialer{Timeout: connectTimeout}).DialContext
+		} else {
+			customDialContext := dialContext
+			dialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				ctx, cancel := context.WithTimeout(ctx, connectTimeout)
+				defer cancel()
+				return customDialContext(ctx, network, addr)
+			}
+		}
 		conf.transport = &http.Transport{
-			Dial: func(network, addr string) (net.Conn, error) {
-				conn, err := net.DialTimeout(network, addr, time.Second*time.Duration(conf.connectTimeout))
-				if err != nil {
-					return nil, err
-				}
-				return getConnDelegate(conn, conf.socketTimeout, conf.finalTimeout), nil
-			},
+			DialContext: socketTimeoutDialContext(dialContext, time.Second*time.Duration(conf.socketTimeout),
+				time.Second*time.Duration(conf.finalTimeout), conf.getLogger()),
 			MaxIdleConns:          conf.maxConnsPerHost,
 			MaxIdleConnsPerHost:   conf.maxConnsPerHost,
 			ResponseHeaderTimeout: time.Second * time.Duration(conf.headerTimeout),
@@ -311,11 +595,11 @@ func (conf *config) getTransport() error {
 		}
 
 		if conf.proxyURL != "" {
-			proxyURL, err := url.Parse(conf.proxyURL)
+			proxy, err := conf.getProxyFunc()
 			if err != nil {
 				return err
 			}
-			conf.transport.Proxy = http.ProxyURL(proxyURL)
+			conf.transport.Proxy = proxy
 		}
 
 		tlsConfig := &tls.Config{InsecureSkipVerify: !conf.sslVerify}
@@ -324,6 +608,13 @@ func (conf *config) getTransport() error {
 			pool.AppendCertsFromPEM(conf.pemCerts)
 			tlsConfig.RootCAs = pool
 		}
+		if conf.clientCertPEM != nil {
+			cert, err := tls.X509KeyPair(conf.clientCertPEM, conf.clientKeyPEM)
+			if err != nil {
+				return err
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
 
 		conf.transport.TLSClientConfig = tlsConfig
 		conf.transport.DisableCompression = !conf.enableCompression
@@ -332,6 +623,20 @@ func (conf *config) getTransport() error {
 	return nil
 }
 
+// getHTTPClient returns the http Client of WosClient, the transport built by getTransport is used
+// unless a http Client or a RoundTripper is set.
+func (conf *config) getHTTPClient() *http.Client {
+	if conf.httpClient != nil {
+		httpClient := *conf.httpClient
+		httpClient.CheckRedirect = checkRedirectFunc
+		return &httpClient
+	}
+	if conf.roundTripper != nil {
+		return &http.Client{Transport: conf.roundTripper, CheckRedirect: checkRedirectFunc}
+	}
+	return &http.Client{Transport: conf.transport, CheckRedirect: checkRedirectFunc}
+}
+
 func checkRedirectFunc(req *http.Request, via []*http.Request) error {
 	return http.ErrUseLastResponse
 }
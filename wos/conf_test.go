@@ -0,0 +1,192 @@
+package wos
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newClientCertificate creates a self-signed client certificate and its key in PEM.
+func newClientCertificate(t *testing.T) (*x509.Certificate, []byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "wos-client"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestClientCertificate(t *testing.T) {
+	clientCert, certPEM, keyPEM := newClientCertificate(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+	serverPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	cases := []struct {
+		name        string
+		configurers []configurer
+		ok          bool
+	}{
+		{"with certificate", []configurer{WithSslVerifyAndPemCerts(true, serverPEM), WithClientCertificate(certPEM, keyPEM)}, true},
+		{"without certificate", []configurer{WithSslVerifyAndPemCerts(true, serverPEM)}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			client := newTestClient(t, server.URL, append(c.configurers, WithMaxRetryCount(0))...)
+			if _, err := client.HeadBucket("bucket"); (err == nil) != c.ok {
+				t.Errorf("HeadBucket error = %v, want ok %t", err, c.ok)
+			}
+		})
+	}
+}
+
+func TestDialContextUnixSocket(t *testing.T) {
+	// the path of a unix socket is limited to about 100 bytes
+	dir, err := os.MkdirTemp("", "wos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "wos.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix socket is not supported: %v", err)
+	}
+	var host string
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host = r.Host
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	dialer := &net.Dialer{}
+	client := newTestClient(t, "http://wos.example.com", WithDialContext(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dialer.DialContext(ctx, "unix", socket)
+	}))
+	if _, err := client.HeadBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	if host != "wos.example.com:80" {
+		t.Errorf("Host = %s, want wos.example.com:80", host)
+	}
+}
+
+func TestProxyCredentials(t *testing.T) {
+	var authorization, requestURI string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization, requestURI = r.Header.Get("Proxy-Authorization"), r.RequestURI
+	}))
+	defer proxy.Close()
+
+	client := newTestClient(t, "http://wos.example.com", WithProxyUrl(proxy.URL), WithProxyCredentials("user", "p@ss"))
+	if _, err := client.HeadBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:p@ss")); authorization != want {
+		t.Errorf("Proxy-Authorization = %q, want %q", authorization, want)
+	}
+	if requestURI != "http://wos.example.com:80/bucket" {
+		t.Errorf("RequestURI = %s, want the absolute url", requestURI)
+	}
+}
+
+func TestNoProxy(t *testing.T) {
+	conf := &config{proxyURL: "http://proxy.example.com:8080",
+		noProxy: []string{"internal.example.com", ".corp.example.com", "10.0.0.0/8", "192.168.1.1", "wos.example.com:8443"}}
+	proxy, err := conf.getProxyFunc()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		rawURL  string
+		proxied bool
+	}{
+		{"https://internal.example.com/bucket", false},
+		{"https://bucket.internal.example.com/key", false},
+		{"https://external.example.com/bucket", true},
+		{"https://xcorp.example.com/bucket", true},
+		{"https://a.corp.example.com/bucket", false},
+		{"http://10.1.2.3/bucket", false},
+		{"http://11.1.2.3/bucket", true},
+		{"http://192.168.1.1/bucket", false},
+		{"https://wos.example.com:8443/bucket", false},
+		{"https://wos.example.com/bucket", true},
+	}
+	for _, c := range cases {
+		requestURL, _ := url.Parse(c.rawURL)
+		proxyURL, err := proxy(&http.Request{URL: requestURL})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if proxied := proxyURL != nil; proxied != c.proxied {
+			t.Errorf("%s proxied = %t, want %t", c.rawURL, proxied, c.proxied)
+		}
+	}
+}
+
+func TestTransportOptionsWithCustomTransport(t *testing.T) {
+	dialContext := (&net.Dialer{}).DialContext
+	cases := []struct {
+		name        string
+		configurers []configurer
+		ok          bool
+	}{
+		{"transport only", []configurer{WithHttpTransport(&http.Transport{})}, true},
+		{"transport with proxy url", []configurer{WithHttpTransport(&http.Transport{}), WithProxyUrl("http://proxy.example.com")}, true},
+		{"transport with dial context", []configurer{WithHttpTransport(&http.Transport{}), WithDialContext(dialContext)}, false},
+		{"http client with certificate", []configurer{WithHTTPClient(&http.Client{}), WithClientCertificate([]byte("cert"), []byte("key"))}, false},
+		{"round tripper with proxy credentials", []configurer{WithRoundTripper(http.DefaultTransport), WithProxyCredentials("user", "pass")}, false},
+		{"transport with no proxy", []configurer{WithHttpTransport(&http.Transport{}), WithNoProxy("localhost")}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			client, err := New("ak", "sk", "https://wos.example.com", c.configurers...)
+			if err == nil {
+				client.Close()
+			}
+			if (err == nil) != c.ok {
+				t.Errorf("New error = %v, want ok %t", err, c.ok)
+			}
+		})
+	}
+}
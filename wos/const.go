@@ -1,5 +1,7 @@
 package wos
 
+import "time"
+
 const (
 	wosSdkVersion          = "1.0.1"
 	USER_AGENT             = "wcs-go-sdk-v2/" + wosSdkVersion
@@ -16,6 +18,9 @@ const (
 
 	HEADER_CONTENT_SHA256_AMZ               = "x-amz-content-sha256"
 	HEADER_CONTENT_SHA256_WOS               = "x-wos-content-sha256"
+	HEADER_DECODED_CONTENT_LENGTH           = "decoded-content-length"
+	HEADER_TRAILER                          = "trailer"
+	HEADER_CHECKSUM_CRC32                   = "checksum-crc32"
 	HEADER_ACL_AMZ                          = "x-amz-acl"
 	HEADER_ACL_WOS                          = "x-wos-acl"
 	HEADER_ACL                              = "acl"
@@ -35,6 +40,7 @@ const (
 	HEADER_GRANT_READ_DELIVERED_WOS         = "grant-read-delivered"
 	HEADER_GRANT_FULL_CONTROL_DELIVERED_WOS = "grant-full-control-delivered"
 	HEADER_REQUEST_ID                       = "request-id"
+	HEADER_CLIENT_REQUEST_ID                = "client-request-id"
 	HEADER_BUCKET_REGION                    = "bucket-region"
 	HEADER_ACCESS_CONRTOL_ALLOW_ORIGIN      = "access-control-allow-origin"
 	HEADER_ACCESS_CONRTOL_ALLOW_HEADERS     = "access-control-allow-headers"
@@ -112,6 +118,7 @@ const (
 	HEADER_CONTENT_ENCODING_CAMEL              = "Content-Encoding"
 	HEADER_CONTENT_LANGUAGE_CAMEL              = "Content-Language"
 	HEADER_EXPIRES_CAMEL                       = "Expires"
+	HEADER_RETRY_AFTER_CAMEL                   = "Retry-After"
 
 	PARAM_VERSION_ID                   = "versionId"
 	PARAM_RESPONSE_CONTENT_TYPE        = "response-content-type"
@@ -135,22 +142,31 @@ const (
 	PARAM_SIGNATURE_AMZ_CAMEL     = "X-Amz-Signature"
 	PARAM_SIGNATURE_WOS_CAMEL     = "X-Wos-Signature"
 
-	DEFAULT_SIGNATURE            = SignatureWos
-	DEFAULT_REGION               = "default-region"
-	DEFAULT_CONNECT_TIMEOUT      = 60
-	DEFAULT_SOCKET_TIMEOUT       = 60
-	DEFAULT_HEADER_TIMEOUT       = 0
-	DEFAULT_IDLE_CONN_TIMEOUT    = 30
-	DEFAULT_MAX_RETRY_COUNT      = 3
-	DEFAULT_MAX_REDIRECT_COUNT   = 3
-	DEFAULT_MAX_CONN_PER_HOST    = 1000
-	EMPTY_CONTENT_SHA256         = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
-	UNSIGNED_PAYLOAD             = "UNSIGNED-PAYLOAD"
-	LONG_DATE_FORMAT             = "20060102T150405Z"
-	SHORT_DATE_FORMAT            = "20060102"
-	ISO8601_DATE_FORMAT          = "2006-01-02T15:04:05Z"
-	ISO8601_MIDNIGHT_DATE_FORMAT = "2006-01-02T00:00:00Z"
-	RFC1123_FORMAT               = "Mon, 02 Jan 2006 15:04:05 GMT"
+	DEFAULT_SIGNATURE             = SignatureWos
+	DEFAULT_REGION                = "default-region"
+	DEFAULT_CONNECT_TIMEOUT       = 60
+	DEFAULT_SOCKET_TIMEOUT        = 60
+	DEFAULT_HEADER_TIMEOUT        = 0
+	DEFAULT_IDLE_CONN_TIMEOUT     = 30
+	DEFAULT_MAX_RETRY_COUNT       = 3
+	DEFAULT_MAX_REDIRECT_COUNT    = 3
+	DEFAULT_MAX_CONN_PER_HOST     = 1000
+	DEFAULT_RETRY_TOKEN_CAPACITY  = 500
+	DEFAULT_RETRY_COST            = 5
+	DEFAULT_RETRY_TIMEOUT_COST    = 10
+	DEFAULT_RETRY_SUCCESS_REFUND  = 1
+	EMPTY_CONTENT_SHA256          = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	UNSIGNED_PAYLOAD              = "UNSIGNED-PAYLOAD"
+	STREAMING_PAYLOAD_TRAILER     = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD-TRAILER"
+	STREAMING_PAYLOAD_TRAILER_WOS = "STREAMING-WOS-HMAC-SHA256-PAYLOAD-TRAILER"
+	AWS_CHUNKED_ENCODING          = "aws-chunked"
+	DEFAULT_STREAMING_CHUNK_SIZE  = 64 * 1024
+	MIN_STREAMING_CHUNK_SIZE      = 8 * 1024
+	LONG_DATE_FORMAT              = "20060102T150405Z"
+	SHORT_DATE_FORMAT             = "20060102"
+	ISO8601_DATE_FORMAT           = "2006-01-02T15:04:05Z"
+	ISO8601_MIDNIGHT_DATE_FORMAT  = "2006-01-02T00:00:00Z"
+	RFC1123_FORMAT                = "Mon, 02 Jan 2006 15:04:05 GMT"
 
 	V4_SERVICE_NAME       = "s3"
 	V4_WOS_SERVICE_NAME   = "wos"
@@ -184,6 +200,10 @@ const (
 	MIN_PART_SIZE     = 100 * 1024
 	DEFAULT_PART_SIZE = 9 * 1024 * 1024
 	MAX_PART_NUM      = 10000
+
+	DEFAULT_RETRY_BASE_DELAY       = 200 * time.Millisecond
+	DEFAULT_RETRY_MAX_DELAY        = 20 * time.Second
+	DEFAULT_RETRY_MAX_ELAPSED_TIME = 5 * time.Minute
 )
 
 // SignatureType defines type of signature
@@ -625,7 +625,10 @@ func ParseGetObjectOutput(output *GetObjectOutput) {
 	}
 }
 
-// ConvertRequestToIoReaderV2 converts req to XML data
+// ConvertRequestToIoReaderV2 converts req to XML data.
+//
+// It is a package-level helper, the data is logged through the global logger set by InitLog instead of the
+// logger of a client.
 func ConvertRequestToIoReaderV2(req interface{}) (io.Reader, string, error) {
 	data, err := TransToXml(req)
 	if err == nil {
@@ -637,7 +640,8 @@ func ConvertRequestToIoReaderV2(req interface{}) (io.Reader, string, error) {
 	return nil, "", err
 }
 
-// ConvertRequestToIoReader converts req to XML data
+// ConvertRequestToIoReader converts req to XML data, the data is logged through the global logger as
+// ConvertRequestToIoReaderV2.
 func ConvertRequestToIoReader(req interface{}) (io.Reader, error) {
 	body, err := TransToXml(req)
 	if err == nil {
@@ -658,14 +662,27 @@ func parseBucketPolicyOutput(s reflect.Type, baseModel IBaseModel, body []byte)
 	}
 }
 
+// convertRequestToXML converts req to XML data, which is logged by the client sending it.
+func convertRequestToXML(req interface{}) (string, error) {
+	data, err := TransToXml(req)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
 // ParseResponseToBaseModel gets response from WOS
 func ParseResponseToBaseModel(resp *http.Response, baseModel IBaseModel, xmlResult bool, isWos bool) (err error) {
+	return WosClient{}.parseResponseToBaseModel(resp, baseModel, xmlResult, isWos)
+}
+
+func (wosClient WosClient) parseResponseToBaseModel(resp *http.Response, baseModel IBaseModel, xmlResult bool, isWos bool) (err error) {
 	readCloser, ok := baseModel.(IReadCloser)
 	if !ok {
 		defer func() {
 			errMsg := resp.Body.Close()
 			if errMsg != nil {
-				doLog(LEVEL_WARN, "Failed to close response body")
+				wosClient.logf(LEVEL_WARN, "Failed to close response body")
 			}
 		}()
 		var body []byte
@@ -682,7 +699,7 @@ func ParseResponseToBaseModel(resp *http.Response, baseModel IBaseModel, xmlResu
 				}
 			}
 			if err != nil {
-				doLog(LEVEL_ERROR, "Unmarshal error: %v", err)
+				wosClient.logf(LEVEL_ERROR, "Unmarshal error: %v", err)
 			}
 		}
 	} else {
@@ -700,15 +717,19 @@ func ParseResponseToBaseModel(resp *http.Response, baseModel IBaseModel, xmlResu
 
 // ParseResponseToWosError gets wosError from WOS
 func ParseResponseToWosError(resp *http.Response, isWos bool) error {
+	return WosClient{}.parseResponseToWosError(resp, isWos)
+}
+
+func (wosClient WosClient) parseResponseToWosError(resp *http.Response, isWos bool) error {
 	isJson := false
 	if contentType, ok := resp.Header[HEADER_CONTENT_TYPE_CAML]; ok {
 		jsonType, _ := mimeTypes["json"]
 		isJson = contentType[0] == jsonType
 	}
 	wosError := WosError{}
-	respError := ParseResponseToBaseModel(resp, &wosError, !isJson, isWos)
+	respError := wosClient.parseResponseToBaseModel(resp, &wosError, !isJson, isWos)
 	if respError != nil {
-		doLog(LEVEL_WARN, "Parse response to BaseModel with error: %v", respError)
+		wosClient.logf(LEVEL_WARN, "Parse response to BaseModel with error: %v", respError)
 	}
 	wosError.Status = resp.Status
 	return wosError
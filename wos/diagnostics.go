@@ -0,0 +1,186 @@
+package wos
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HTTPTimings defines the time spent in each phase of an HTTP attempt
+type HTTPTimings struct {
+	DNSLookup       time.Duration
+	Connect         time.Duration
+	TLSHandshake    time.Duration
+	TimeToFirstByte time.Duration
+	Total           time.Duration
+	ConnReused      bool
+}
+
+// Diagnostics defines how an operation was sent, it is set on the output and on the WosError of the operation.
+// The BytesReceived of an output with a Body, such as GetObjectOutput, is final after the Body is closed.
+type Diagnostics struct {
+	ClientRequestId string
+	Endpoint        string
+	Attempts        int
+	RedirectChain   []string
+	TotalLatency    time.Duration
+	Timings         HTTPTimings
+	AttemptTimings  []HTTPTimings
+	RemoteAddr      string
+	BytesSent       int64
+	BytesReceived   int64
+}
+
+func (baseModel *BaseModel) setDiagnostics(diagnostics *Diagnostics) {
+	baseModel.Diagnostics = diagnostics
+}
+
+// attemptTrace records the phases of an HTTP attempt through httptrace.
+type attemptTrace struct {
+	lock         sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	timings      HTTPTimings
+	remoteAddr   string
+	body         *countingReadCloser
+}
+
+func (t *attemptTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.lock.Lock()
+			t.dnsStart = time.Now()
+			t.lock.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.lock.Lock()
+			t.timings.DNSLookup = time.Since(t.dnsStart)
+			t.lock.Unlock()
+		},
+		ConnectStart: func(string, string) {
+			t.lock.Lock()
+			t.connectStart = time.Now()
+			t.lock.Unlock()
+		},
+		ConnectDone: func(string, string, error) {
+			t.lock.Lock()
+			t.timings.Connect = time.Since(t.connectStart)
+			t.lock.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			t.lock.Lock()
+			t.tlsStart = time.Now()
+			t.lock.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.lock.Lock()
+			t.timings.TLSHandshake = time.Since(t.tlsStart)
+			t.lock.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.lock.Lock()
+			t.timings.ConnReused = info.Reused
+			if info.Conn != nil && info.Conn.RemoteAddr() != nil {
+				t.remoteAddr = info.Conn.RemoteAddr().String()
+			}
+			t.lock.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			t.lock.Lock()
+			t.timings.TimeToFirstByte = time.Since(t.start)
+			t.lock.Unlock()
+		},
+	}
+}
+
+// startAttemptTrace returns the request carrying the trace of the attempt, or nil if the diagnostics are not collected.
+func (wosClient WosClient) startAttemptTrace(req *http.Request) (*http.Request, *attemptTrace) {
+	diagnostics := wosClient.call.diagnostics
+	if diagnostics == nil {
+		return req, nil
+	}
+	t := &attemptTrace{start: time.Now()}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), t.clientTrace()))
+	if req.Body != nil && req.Body != http.NoBody {
+		t.body = &countingReadCloser{ReadCloser: req.Body, report: func(int64) {}}
+		req.Body = t.body
+	}
+	return req, t
+}
+
+func (wosClient WosClient) finishAttemptTrace(t *attemptTrace, resp *http.Response) {
+	diagnostics := wosClient.call.diagnostics
+	if t == nil || diagnostics == nil {
+		return
+	}
+	t.lock.Lock()
+	timings := t.timings
+	remoteAddr := t.remoteAddr
+	t.lock.Unlock()
+	timings.Total = time.Since(t.start)
+	if t.body != nil {
+		diagnostics.BytesSent += atomic.LoadInt64(&t.body.count)
+	}
+	if resp != nil && resp.Body != nil {
+		resp.Body = &countingReadCloser{ReadCloser: resp.Body, report: func(count int64) {
+			diagnostics.BytesReceived += count
+		}}
+	}
+
+	diagnostics.Attempts++
+	diagnostics.Timings = timings
+	diagnostics.AttemptTimings = append(diagnostics.AttemptTimings, timings)
+	if remoteAddr != "" {
+		diagnostics.RemoteAddr = remoteAddr
+	}
+}
+
+func newClientRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return Int64ToString(time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// newCall returns the description of an operation with a new client request id and diagnostics.
+func (wosClient WosClient) newCall(action, bucketName, objectKey string) callInfo {
+	clientRequestID := wosClient.conf.clientRequestID
+	if clientRequestID == "" {
+		clientRequestID = newClientRequestID()
+	}
+	return callInfo{
+		action:          action,
+		bucketName:      bucketName,
+		objectKey:       objectKey,
+		diagnostics:     &Diagnostics{ClientRequestId: clientRequestID},
+		clientRequestID: clientRequestID,
+		transfer:        wosClient.newTransferControl(),
+	}
+}
+
+// finishCall sets the diagnostics and the client request id of the operation on the output,
+// or on the error if it is a WosError.
+func (wosClient WosClient) finishCall(output IBaseModel, respError error, begin time.Time) error {
+	diagnostics := wosClient.call.diagnostics
+	if diagnostics != nil {
+		diagnostics.TotalLatency = time.Since(begin)
+	}
+	if wosError, ok := respError.(WosError); ok {
+		wosError.Diagnostics = diagnostics
+		wosError.ClientRequestId = wosClient.call.clientRequestID
+		return wosError
+	}
+	if output != nil {
+		output.setDiagnostics(diagnostics)
+		output.setClientRequestID(wosClient.call.clientRequestID)
+	}
+	return respError
+}
@@ -0,0 +1,103 @@
+package wos
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDiagnostics(t *testing.T) {
+	cases := []struct {
+		name string
+		// handle responds to the attempt-th request
+		handle   func(w http.ResponseWriter, r *http.Request, attempt int32)
+		attempts int
+		redirect bool
+		wantErr  bool
+	}{
+		{name: "success", attempts: 1, handle: func(w http.ResponseWriter, r *http.Request, attempt int32) {
+			w.Header().Set("ETag", `"etag"`)
+		}},
+		{name: "retry", attempts: 2, handle: func(w http.ResponseWriter, r *http.Request, attempt int32) {
+			if attempt == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Header().Set("ETag", `"etag"`)
+		}},
+		{name: "redirect", attempts: 2, redirect: true, handle: func(w http.ResponseWriter, r *http.Request, attempt int32) {
+			if attempt == 1 {
+				w.Header().Set("Location", "http://"+r.Host+r.URL.Path+"?redirected=1")
+				w.WriteHeader(http.StatusTemporaryRedirect)
+				return
+			}
+			w.Header().Set("ETag", `"etag"`)
+		}},
+		{name: "error", attempts: 1, wantErr: true, handle: func(w http.ResponseWriter, r *http.Request, attempt int32) {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("<Error><Code>AccessDenied</Code></Error>"))
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var requests int32
+			var lock sync.Mutex
+			var clientRequestIDs []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				io.Copy(ioutil.Discard, r.Body)
+				lock.Lock()
+				clientRequestIDs = append(clientRequestIDs, r.Header.Get(HEADER_PREFIX_WOS+HEADER_CLIENT_REQUEST_ID))
+				lock.Unlock()
+				c.handle(w, r, atomic.AddInt32(&requests, 1))
+			}))
+			defer server.Close()
+			client := newTestClient(t, server.URL)
+
+			input := &PutObjectInput{}
+			input.Bucket, input.Key, input.Body = "bucket", "key", strings.NewReader("hello")
+			output, err := client.PutObject(input)
+			var diagnostics *Diagnostics
+			if c.wantErr {
+				wosError, ok := err.(WosError)
+				if !ok {
+					t.Fatalf("PutObject error = %v, want WosError", err)
+				}
+				diagnostics = wosError.Diagnostics
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				diagnostics = output.Diagnostics
+			}
+
+			if diagnostics == nil {
+				t.Fatal("Diagnostics is nil")
+			}
+			if diagnostics.Attempts != c.attempts || len(diagnostics.AttemptTimings) != c.attempts {
+				t.Errorf("Attempts = %d with %d timings, want %d", diagnostics.Attempts, len(diagnostics.AttemptTimings), c.attempts)
+			}
+			if (len(diagnostics.RedirectChain) == 1) != c.redirect {
+				t.Errorf("RedirectChain = %v", diagnostics.RedirectChain)
+			}
+			if diagnostics.BytesSent != int64(5*c.attempts) {
+				t.Errorf("BytesSent = %d, want %d", diagnostics.BytesSent, 5*c.attempts)
+			}
+			if diagnostics.RemoteAddr == "" || diagnostics.TotalLatency <= 0 {
+				t.Errorf("RemoteAddr = %q, TotalLatency = %v", diagnostics.RemoteAddr, diagnostics.TotalLatency)
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			for _, clientRequestID := range clientRequestIDs {
+				if clientRequestID == "" || clientRequestID != diagnostics.ClientRequestId {
+					t.Errorf("client request id %q, want %q", clientRequestID, diagnostics.ClientRequestId)
+				}
+			}
+		})
+	}
+}
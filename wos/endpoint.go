@@ -0,0 +1,261 @@
+package wos
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EndpointStrategy defines how the endpoint of a request is selected from the endpoints set by WithEndpoints
+type EndpointStrategy int
+
+const (
+	// EndpointStrategyOrdered selects the first healthy endpoint in order, the others are used for failover
+	EndpointStrategyOrdered EndpointStrategy = iota
+	// EndpointStrategyRoundRobin selects the healthy endpoints in turn
+	EndpointStrategyRoundRobin
+	// EndpointStrategyLeastLatency selects the healthy endpoint with the least average latency
+	EndpointStrategyLeastLatency
+)
+
+const (
+	DEFAULT_ENDPOINT_FAILURE_THRESHOLD = 3
+	DEFAULT_ENDPOINT_EJECT_DURATION    = 30 * time.Second
+	endpointLatencyWeight              = 0.2
+)
+
+// EndpointStatus defines the health of an endpoint
+type EndpointStatus struct {
+	Endpoint            string
+	Healthy             bool
+	ConsecutiveFailures int
+	EjectedUntil        time.Time
+	AverageLatency      time.Duration
+}
+
+type poolEndpoint struct {
+	endpoint  string
+	urlHolder *urlHolder
+	pathStyle bool
+
+	failures       int
+	ejectedUntil   time.Time
+	averageLatency time.Duration
+}
+
+// endpointPool holds the endpoints of a client and their health, it is shared by the copies of the config.
+type endpointPool struct {
+	strategy         EndpointStrategy
+	failureThreshold int
+	ejectDuration    time.Duration
+	probeBucket      string
+	probeInterval    time.Duration
+
+	lock      sync.Mutex
+	endpoints []*poolEndpoint
+	next      uint32
+	stop      chan struct{}
+	stopOnce  sync.Once
+}
+
+// initEndpointPool creates the pool of the endpoints set by WithEndpoints, the endpoint of the client is the first one.
+// The pathStyle is the one set by WithPathStyle, the endpoints with an IP host are always accessed in path style.
+func (conf *config) initEndpointPool(pathStyle bool) error {
+	if len(conf.endpoints) == 0 {
+		return nil
+	}
+	pool := &endpointPool{
+		strategy:         conf.endpointStrategy,
+		failureThreshold: conf.endpointFailureThreshold,
+		ejectDuration:    conf.endpointEjectDuration,
+		probeBucket:      conf.endpointProbeBucket,
+		probeInterval:    conf.endpointProbeInterval,
+		stop:             make(chan struct{}),
+	}
+	if pool.failureThreshold <= 0 {
+		pool.failureThreshold = DEFAULT_ENDPOINT_FAILURE_THRESHOLD
+	}
+	if pool.ejectDuration <= 0 {
+		pool.ejectDuration = DEFAULT_ENDPOINT_EJECT_DURATION
+	}
+	endpoints := append([]string{conf.endpoint}, conf.endpoints...)
+	seen := make(map[string]bool, len(endpoints))
+	for _, endpoint := range endpoints {
+		_conf := &config{endpoint: endpoint, pathStyle: pathStyle}
+		if err := _conf.initURLHolder(); err != nil {
+			return err
+		}
+		if seen[_conf.endpoint] {
+			continue
+		}
+		seen[_conf.endpoint] = true
+		pool.endpoints = append(pool.endpoints, &poolEndpoint{endpoint: _conf.endpoint, urlHolder: _conf.urlHolder, pathStyle: _conf.pathStyle})
+	}
+	if len(pool.endpoints) > 1 {
+		conf.endpointPool = pool
+	}
+	return nil
+}
+
+func (e *poolEndpoint) healthy(now time.Time) bool {
+	return !now.Before(e.ejectedUntil)
+}
+
+// pick selects the endpoint of an attempt, the endpoints failed in the same call are avoided if possible.
+func (pool *endpointPool) pick(failed map[*poolEndpoint]bool) *poolEndpoint {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	now := time.Now()
+	candidates := make([]*poolEndpoint, 0, len(pool.endpoints))
+	for _, e := range pool.endpoints {
+		if e.healthy(now) && !failed[e] {
+			candidates = append(candidates, e)
+		}
+	}
+	if len(candidates) == 0 {
+		for _, e := range pool.endpoints {
+			if e.healthy(now) {
+				candidates = append(candidates, e)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		// all the endpoints are ejected, try the one which recovers first
+		earliest := pool.endpoints[0]
+		for _, e := range pool.endpoints[1:] {
+			if e.ejectedUntil.Before(earliest.ejectedUntil) {
+				earliest = e
+			}
+		}
+		return earliest
+	}
+
+	switch pool.strategy {
+	case EndpointStrategyRoundRobin:
+		return candidates[int(atomic.AddUint32(&pool.next, 1)-1)%len(candidates)]
+	case EndpointStrategyLeastLatency:
+		best := candidates[0]
+		for _, e := range candidates[1:] {
+			if e.averageLatency < best.averageLatency {
+				best = e
+			}
+		}
+		return best
+	default:
+		return candidates[0]
+	}
+}
+
+// report updates the health of the endpoint with the result of an attempt,
+// an endpoint is ejected after failureThreshold consecutive errors or 5xx responses.
+// An attempt cancelled or timed out by the context of the call says nothing about the endpoint and is ignored.
+func (pool *endpointPool) report(wosClient *WosClient, e *poolEndpoint, statusCode int, err error, latency time.Duration) bool {
+	if isContextDone(wosClient.conf.ctx) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	failed := err != nil || statusCode >= 500
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	if failed {
+		e.failures++
+		if e.failures >= pool.failureThreshold {
+			e.ejectedUntil = time.Now().Add(pool.ejectDuration)
+			wosClient.logf(LEVEL_WARN, "Eject endpoint %s for %v after %d consecutive failures", e.endpoint, pool.ejectDuration, e.failures)
+		}
+		return true
+	}
+	if !e.ejectedUntil.IsZero() {
+		wosClient.logf(LEVEL_INFO, "Endpoint %s is recovered", e.endpoint)
+	}
+	e.failures = 0
+	e.ejectedUntil = time.Time{}
+	if e.averageLatency == 0 {
+		e.averageLatency = latency
+	} else {
+		e.averageLatency += time.Duration(endpointLatencyWeight * float64(latency-e.averageLatency))
+	}
+	return false
+}
+
+func (pool *endpointPool) status() []EndpointStatus {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+	now := time.Now()
+	ret := make([]EndpointStatus, 0, len(pool.endpoints))
+	for _, e := range pool.endpoints {
+		ret = append(ret, EndpointStatus{
+			Endpoint:            e.endpoint,
+			Healthy:             e.healthy(now),
+			ConsecutiveFailures: e.failures,
+			EjectedUntil:        e.ejectedUntil,
+			AverageLatency:      e.averageLatency,
+		})
+	}
+	return ret
+}
+
+// probe sends HeadBucket to each endpoint periodically until the client is closed,
+// any response below 500 means the endpoint is healthy.
+func (pool *endpointPool) probe(wosClient WosClient) {
+	ticker := time.NewTicker(pool.probeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-pool.stop:
+			return
+		case <-ticker.C:
+		}
+		for _, e := range pool.endpoints {
+			client := wosClient.withEndpoint(e)
+			start := time.Now()
+			_, err := client.HeadBucket(pool.probeBucket, WithCallMaxRetryCount(0))
+			statusCode := http.StatusOK
+			if wosError, ok := err.(WosError); ok {
+				statusCode, err = wosError.StatusCode, nil
+			}
+			pool.report(&client, e, statusCode, err, time.Since(start))
+		}
+	}
+}
+
+func (pool *endpointPool) close() {
+	pool.stopOnce.Do(func() {
+		close(pool.stop)
+	})
+}
+
+// withEndpoint returns a copy of the client sending the requests to the endpoint of the pool,
+// the path style of the endpoint is used unless it is set by WithCallPathStyle.
+func (wosClient WosClient) withEndpoint(e *poolEndpoint) WosClient {
+	conf := *wosClient.conf
+	conf.endpoint = e.endpoint
+	conf.urlHolder = e.urlHolder
+	if !conf.pathStylePinned {
+		conf.pathStyle = e.pathStyle
+	}
+	conf.endpointPool = nil
+	wosClient.conf = &conf
+	return wosClient
+}
+
+// pickEndpoint returns a copy of the client for an attempt and the endpoint selected from the pool,
+// the endpoint is nil if the client has a single endpoint or the endpoint is set by WithCallEndpoint.
+func (wosClient WosClient) pickEndpoint(failed map[*poolEndpoint]bool) (WosClient, *poolEndpoint) {
+	pool := wosClient.conf.endpointPool
+	if pool == nil || wosClient.conf.endpointPinned {
+		return wosClient, nil
+	}
+	e := pool.pick(failed)
+	return wosClient.withEndpoint(e), e
+}
+
+// EndpointStatus returns the health of the endpoints set by WithEndpoints.
+func (wosClient WosClient) EndpointStatus() []EndpointStatus {
+	if wosClient.conf == nil || wosClient.conf.endpointPool == nil {
+		return nil
+	}
+	return wosClient.conf.endpointPool.status()
+}
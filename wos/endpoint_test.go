@@ -0,0 +1,190 @@
+package wos
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestEndpointPoolReport(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	cases := []struct {
+		name       string
+		ctx        context.Context
+		statusCode int
+		err        error
+		failed     bool
+	}{
+		{"ok", nil, http.StatusOK, nil, false},
+		{"not found", nil, http.StatusNotFound, nil, false},
+		{"service unavailable", nil, http.StatusServiceUnavailable, nil, true},
+		{"connection reset", nil, 0, &url.Error{Op: "Get", URL: "http://wos", Err: syscall.ECONNRESET}, true},
+		{"canceled", nil, 0, &url.Error{Op: "Get", URL: "http://wos", Err: context.Canceled}, false},
+		{"deadline exceeded", nil, 0, context.DeadlineExceeded, false},
+		{"context done", cancelled, 0, errors.New("read: connection closed"), false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			e := &poolEndpoint{endpoint: "http://wos"}
+			pool := &endpointPool{failureThreshold: 1, ejectDuration: time.Minute, endpoints: []*poolEndpoint{e}}
+			wosClient := &WosClient{conf: &config{ctx: c.ctx, logger: &captureLogger{}}}
+			if failed := pool.report(wosClient, e, c.statusCode, c.err, time.Millisecond); failed != c.failed {
+				t.Errorf("report = %t, want %t", failed, c.failed)
+			}
+			if ejected := !e.ejectedUntil.IsZero(); ejected != c.failed {
+				t.Errorf("ejected = %t, want %t", ejected, c.failed)
+			}
+		})
+	}
+}
+
+func TestEndpointNotEjectedByCallTimeout(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer slow.Close()
+	logger := &captureLogger{}
+	client := newTestClient(t, slow.URL, WithEndpoints(EndpointStrategyOrdered, "http://127.0.0.1:1"),
+		WithEndpointHealth(1, time.Minute), WithLogger(logger))
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.HeadBucket("bucket", WithTimeout(20*time.Millisecond)); err == nil {
+			t.Fatal("HeadBucket succeeded, want the timeout of the call")
+		}
+	}
+	if status := client.EndpointStatus()[0]; !status.Healthy || status.ConsecutiveFailures != 0 {
+		t.Errorf("the endpoint is reported by the timed out calls: %+v", status)
+	}
+	if logger.find("Eject endpoint") != nil {
+		t.Error("the endpoint is ejected by the timed out calls")
+	}
+}
+
+func TestEndpointEjectionLogged(t *testing.T) {
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unavailable.Close()
+	available := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer available.Close()
+	logger := &captureLogger{}
+	client := newTestClient(t, unavailable.URL, WithEndpoints(EndpointStrategyOrdered, available.URL),
+		WithEndpointHealth(1, time.Minute), WithLogger(logger))
+
+	if _, err := client.HeadBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	if entry := logger.find("Eject endpoint " + unavailable.URL); entry == nil || entry.fields[LogFieldOperation] != "HeadBucket" {
+		t.Errorf("the ejection is not logged through the client logger: %v", entry)
+	}
+}
+
+func TestEndpointCallPathStyle(t *testing.T) {
+	client := newTestClient(t, "http://wos-a.example.com", WithEndpoints(EndpointStrategyOrdered, "http://wos-b.example.com"))
+	cases := []struct {
+		name       string
+		extensions []extensionOptions
+		pathStyle  bool
+	}{
+		{"endpoint", nil, true},
+		{"call", []extensionOptions{WithCallPathStyle(false)}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			callClient, err := client.withExtensionConfigs(c.extensions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			attemptClient, e := callClient.pickEndpoint(nil)
+			if e == nil || attemptClient.conf.pathStyle != c.pathStyle {
+				t.Errorf("path style of endpoint %v = %t, want %t", e, attemptClient.conf.pathStyle, c.pathStyle)
+			}
+		})
+	}
+}
+
+// newCountingServers starts n servers which count their requests, each one responds after delays[i].
+func newCountingServers(t *testing.T, delays ...time.Duration) ([]string, []int32) {
+	endpoints, requests := make([]string, len(delays)), make([]int32, len(delays))
+	for i, delay := range delays {
+		i, delay := i, delay
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests[i], 1)
+			time.Sleep(delay)
+		}))
+		t.Cleanup(server.Close)
+		endpoints[i] = server.URL
+	}
+	return endpoints, requests
+}
+
+func TestEndpointStrategy(t *testing.T) {
+	cases := []struct {
+		name     string
+		strategy EndpointStrategy
+		delays   []time.Duration
+		want     []int32
+	}{
+		{"ordered", EndpointStrategyOrdered, []time.Duration{0, 0, 0}, []int32{6, 0, 0}},
+		{"round robin", EndpointStrategyRoundRobin, []time.Duration{0, 0, 0}, []int32{2, 2, 2}},
+		// the slow endpoint is tried once before its latency is known
+		{"least latency", EndpointStrategyLeastLatency, []time.Duration{50 * time.Millisecond, 0}, []int32{1, 5}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			endpoints, requests := newCountingServers(t, c.delays...)
+			client := newTestClient(t, endpoints[0], WithEndpoints(c.strategy, endpoints[1:]...))
+
+			for i := 0; i < 6; i++ {
+				if _, err := client.HeadBucket("bucket"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			for i := range endpoints {
+				if got := atomic.LoadInt32(&requests[i]); got != c.want[i] {
+					t.Errorf("endpoint %d got %d requests, want %d", i, got, c.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestEndpointProbeRecovery(t *testing.T) {
+	var unavailable int32 = 1
+	recovering := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&unavailable) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer recovering.Close()
+	available := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer available.Close()
+	client := newTestClient(t, recovering.URL, WithEndpoints(EndpointStrategyOrdered, available.URL),
+		WithEndpointHealth(1, time.Hour), WithEndpointProbe("bucket", 10*time.Millisecond))
+
+	if _, err := client.HeadBucket("bucket"); err != nil {
+		t.Fatal(err)
+	}
+	if status := client.EndpointStatus()[0]; status.Healthy {
+		t.Fatalf("the unavailable endpoint is not ejected: %+v", status)
+	}
+	// the ejected endpoint is used again once the probe succeeds, long before the eject duration
+	atomic.StoreInt32(&unavailable, 0)
+	deadline := time.Now().Add(time.Second)
+	for !client.EndpointStatus()[0].Healthy {
+		if time.Now().After(deadline) {
+			t.Fatalf("the endpoint is not recovered by the probe: %+v", client.EndpointStatus()[0])
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -17,6 +17,10 @@ type WosError struct {
 }
 
 func (err WosError) Error() string {
+	if err.ClientRequestId != "" {
+		return fmt.Sprintf("wos: service returned error: Status=%s, Code=%s, Message=%s, RequestId=%s, ClientRequestId=%s",
+			err.Status, err.Code, err.Message, err.RequestId, err.ClientRequestId)
+	}
 	return fmt.Sprintf("wos: service returned error: Status=%s, Code=%s, Message=%s, RequestId=%s",
 		err.Status, err.Code, err.Message, err.RequestId)
 }
@@ -1,13 +1,22 @@
 package wos
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type extensionOptions interface{}
 type extensionHeaders func(headers map[string][]string, isWos bool) error
 
+// extensionConfig overrides the client config for a single call, it is applied to a copy of the config.
+type extensionConfig func(conf *config) error
+
 func setHeaderPrefix(key string, value string) extensionHeaders {
 	return func(headers map[string][]string, isWos bool) error {
 		if strings.TrimSpace(value) == "" {
@@ -22,3 +31,273 @@ func setHeaderPrefix(key string, value string) extensionHeaders {
 func WithReqPaymentHeader(requester PayerType) extensionHeaders {
 	return setHeaderPrefix(REQUEST_PAYER, string(requester))
 }
+
+// WithContext sets the context for a single call, the call is cancelled when the context is done.
+// It overrides the context set by WithRequestContext.
+func WithContext(ctx context.Context) extensionConfig {
+	return func(conf *config) error {
+		conf.ctx = ctx
+		return nil
+	}
+}
+
+// WithClientRequestID sets the client request id of a single call instead of the generated one,
+// the id is sent in the client-request-id header and written in the logs, the outputs and the WosError.
+func WithClientRequestID(clientRequestID string) extensionConfig {
+	return func(conf *config) error {
+		conf.clientRequestID = clientRequestID
+		return nil
+	}
+}
+
+// WithHeader sets a header of a single call as is, without the metadata prefix added to the unknown headers.
+func WithHeader(key, value string) extensionConfig {
+	return withRequestHeaders(map[string][]string{key: {value}})
+}
+
+// WithQueryParam sets a query parameter of a single call, the value is empty for a sub-resource.
+func WithQueryParam(key, value string) extensionConfig {
+	return withRequestParams(map[string]string{key: value})
+}
+
+// withRequestHeaders merges the headers into a new map, the maps of the shared config are never modified.
+func withRequestHeaders(headers map[string][]string) extensionConfig {
+	return func(conf *config) error {
+		_headers := make(map[string][]string, len(conf.requestHeaders)+len(headers))
+		for key, value := range conf.requestHeaders {
+			_headers[key] = value
+		}
+		for key, value := range headers {
+			_headers[key] = value
+		}
+		conf.requestHeaders = _headers
+		return nil
+	}
+}
+
+func withRequestParams(params map[string]string) extensionConfig {
+	return func(conf *config) error {
+		_params := make(map[string]string, len(conf.requestParams)+len(params))
+		for key, value := range conf.requestParams {
+			_params[key] = value
+		}
+		for key, value := range params {
+			_params[key] = value
+		}
+		conf.requestParams = _params
+		return nil
+	}
+}
+
+// WithTimeout sets the timeout of a single call including its retries, the body of the output,
+// such as GetObjectOutput.Body, must be read before the timeout. The timeout of UploadFile and DownloadFile
+// covers all the parts.
+func WithTimeout(timeout time.Duration) extensionConfig {
+	return func(conf *config) error {
+		conf.timeout = timeout
+		return nil
+	}
+}
+
+// WithDeadline sets the deadline of a single call including its retries, the deadline of UploadFile and DownloadFile
+// covers all the parts.
+func WithDeadline(deadline time.Time) extensionConfig {
+	return func(conf *config) error {
+		conf.deadline = deadline
+		return nil
+	}
+}
+
+// WithCallMaxRetryCount overrides the max retry count set by WithMaxRetryCount for a single call.
+func WithCallMaxRetryCount(maxRetryCount int) extensionConfig {
+	return func(conf *config) error {
+		if maxRetryCount >= 0 {
+			conf.maxRetryCount = maxRetryCount
+		}
+		return nil
+	}
+}
+
+// WithCallRetryPolicy overrides the retry policy set by WithRetryPolicy for a single call.
+func WithCallRetryPolicy(retryPolicy RetryPolicy) extensionConfig {
+	return func(conf *config) error {
+		if retryPolicy != nil {
+			conf.retryPolicy = retryPolicy
+		}
+		return nil
+	}
+}
+
+// WithCallEndpoint overrides the endpoint of the client for a single call, the connections are shared with the client.
+// The call fails if the endpoint is invalid.
+func WithCallEndpoint(endpoint string) extensionConfig {
+	return func(conf *config) error {
+		if strings.TrimSpace(endpoint) == "" {
+			return nil
+		}
+		if err := checkEndpoint(endpoint); err != nil {
+			return fmt.Errorf("Failed to set the endpoint of the call with reason: %v", err)
+		}
+		pathStyle := conf.pathStyle
+		conf.endpoint = endpoint
+		if err := conf.initURLHolder(); err != nil {
+			return fmt.Errorf("Failed to set the endpoint of the call with reason: %v", err)
+		}
+		if !IsIP(conf.urlHolder.host) {
+			conf.pathStyle = pathStyle
+		}
+		conf.endpointPinned = true
+		return nil
+	}
+}
+
+// checkEndpoint checks the endpoint is an http or https address with a host and a valid port.
+func checkEndpoint(endpoint string) error {
+	rawURL := strings.TrimSpace(endpoint)
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %s of endpoint %s", u.Scheme, endpoint)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("no host in endpoint %s", endpoint)
+	}
+	if port := u.Port(); port != "" {
+		if _port, _err := strconv.Atoi(port); _err != nil || _port <= 0 || _port > 65535 {
+			return fmt.Errorf("invalid port %s of endpoint %s", port, endpoint)
+		}
+	}
+	return nil
+}
+
+// WithCallRegion overrides the region set by WithRegion for a single call.
+func WithCallRegion(region string) extensionConfig {
+	return func(conf *config) error {
+		if region = strings.TrimSpace(region); region != "" {
+			conf.region = region
+		}
+		return nil
+	}
+}
+
+// WithCallPathStyle overrides the path style set by WithPathStyle for a single call, including the calls to the
+// endpoints set by WithEndpoints.
+func WithCallPathStyle(pathStyle bool) extensionConfig {
+	return func(conf *config) error {
+		conf.pathStyle = pathStyle
+		conf.pathStylePinned = true
+		return nil
+	}
+}
+
+// WithProgress sets the listener of the progress of the request body and the response body of a single call.
+func WithProgress(listener ProgressListener) extensionConfig {
+	return func(conf *config) error {
+		conf.progressListener = listener
+		return nil
+	}
+}
+
+// WithRateLimit limits the rate of the request body and the response body of a single call in bytes per second,
+// the limit of UploadFile and DownloadFile is shared by the parts transferred at the same time.
+func WithRateLimit(bytesPerSecond int64) extensionConfig {
+	return func(conf *config) error {
+		conf.rateLimit = bytesPerSecond
+		return nil
+	}
+}
+
+func withRateLimiter(limiter *rateLimiter) extensionConfig {
+	return func(conf *config) error {
+		conf.rateLimiter = limiter
+		return nil
+	}
+}
+
+// withCallTimeout returns a copy of the client whose context is bounded by the timeout and the deadline of the call,
+// the returned function releases the context.
+func (wosClient WosClient) withCallTimeout() (WosClient, context.CancelFunc) {
+	conf := wosClient.conf
+	if conf.timeout <= 0 && conf.deadline.IsZero() {
+		return wosClient, func() {}
+	}
+	ctx := conf.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	deadline := conf.deadline
+	if conf.timeout > 0 {
+		if timeoutDeadline := time.Now().Add(conf.timeout); deadline.IsZero() || timeoutDeadline.Before(deadline) {
+			deadline = timeoutDeadline
+		}
+	}
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	_conf := *conf
+	_conf.ctx = ctx
+	wosClient.conf = &_conf
+	return wosClient, cancel
+}
+
+// withTransferCall applies the timeout, the deadline and the rate limit of UploadFile and DownloadFile to the whole call,
+// the extensions returned bound the operations of the parts by the context of the call and share its rate limiter.
+func (wosClient WosClient) withTransferCall(extensions []extensionOptions) (WosClient, []extensionOptions, context.CancelFunc) {
+	wosClient, cancel := wosClient.withCallTimeout()
+	conf := wosClient.conf
+	extensions = extensions[:len(extensions):len(extensions)]
+	if conf.ctx != nil {
+		extensions = append(extensions, WithContext(conf.ctx))
+	}
+	if conf.rateLimit > 0 {
+		ctx := conf.ctx
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		extensions = append(extensions, withRateLimiter(newRateLimiter(ctx, conf.rateLimit)))
+	}
+	return wosClient, extensions, cancel
+}
+
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (r cancelReadCloser) Close() error {
+	defer r.cancel()
+	return r.ReadCloser.Close()
+}
+
+// cancelWithResponse releases the context of the call when the body of the response is closed,
+// or at once if there is no response.
+func cancelWithResponse(resp *http.Response, cancel context.CancelFunc) *http.Response {
+	if resp == nil || resp.Body == nil {
+		cancel()
+		return resp
+	}
+	resp.Body = cancelReadCloser{ReadCloser: resp.Body, cancel: cancel}
+	return resp
+}
+
+func (wosClient WosClient) withExtensionConfigs(extensions []extensionOptions) (WosClient, error) {
+	var conf *config
+	for _, extension := range extensions {
+		if _extensionConfig, ok := extension.(extensionConfig); ok {
+			if conf == nil {
+				_conf := *wosClient.conf
+				conf = &_conf
+			}
+			if err := _extensionConfig(conf); err != nil {
+				return wosClient, err
+			}
+		}
+	}
+	if conf != nil {
+		wosClient.conf = conf
+	}
+	return wosClient, nil
+}
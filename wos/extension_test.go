@@ -0,0 +1,241 @@
+package wos
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newMultipartServer serves the multipart upload operations, each part is counted in parts when it arrives and is delayed by partDelay.
+func newMultipartServer(t *testing.T, partDelay time.Duration, parts *int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		switch {
+		case r.Method == http.MethodPost && query.Has("uploads"):
+			w.Write([]byte("<InitiateMultipartUploadResult><Bucket>bucket</Bucket><Key>key</Key><UploadId>upload-id</UploadId></InitiateMultipartUploadResult>"))
+		case r.Method == http.MethodPut && query.Has("partNumber"):
+			atomic.AddInt32(parts, 1)
+			time.Sleep(partDelay)
+			io.Copy(ioutil.Discard, r.Body)
+			w.Header().Set("ETag", `"etag-`+query.Get("partNumber")+`"`)
+		case r.Method == http.MethodPost && query.Has("uploadId"):
+			io.Copy(ioutil.Discard, r.Body)
+			w.Write([]byte("<CompleteMultipartUploadResult><Bucket>bucket</Bucket><Key>key</Key><ETag>etag</ETag></CompleteMultipartUploadResult>"))
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newUploadFileInput(t *testing.T, size int) *UploadFileInput {
+	path := filepath.Join(t.TempDir(), "upload")
+	if err := ioutil.WriteFile(path, bytes.Repeat([]byte("a"), size), 0600); err != nil {
+		t.Fatal(err)
+	}
+	input := &UploadFileInput{UploadFile: path, PartSize: MIN_PART_SIZE, TaskNum: 1}
+	input.Bucket, input.Key = "bucket", "key"
+	return input
+}
+
+func TestWithCallEndpoint(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+	}))
+	defer server.Close()
+	client := newTestClient(t, "http://127.0.0.1:1")
+
+	cases := []struct {
+		name     string
+		endpoint string
+		valid    bool
+	}{
+		{"valid", server.URL, true},
+		{"invalid port", strings.Replace(server.URL, "127.0.0.1:", "127.0.0.1:x", 1), false},
+		{"port out of range", "http://127.0.0.1:65536", false},
+		{"unsupported scheme", "ftp://127.0.0.1", false},
+		{"no host", "http://", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			atomic.StoreInt32(&requests, 0)
+			_, err := client.HeadBucket("bucket", WithCallEndpoint(c.endpoint))
+			if (err == nil) != c.valid {
+				t.Fatalf("HeadBucket error = %v, want valid %t", err, c.valid)
+			}
+			if sent := atomic.LoadInt32(&requests) > 0; sent != c.valid {
+				t.Errorf("request sent = %t, want %t", sent, c.valid)
+			}
+		})
+	}
+}
+
+func TestUploadFileTimeoutCoversParts(t *testing.T) {
+	var parts int32
+	server := newMultipartServer(t, 80*time.Millisecond, &parts)
+	client := newTestClient(t, server.URL, WithMaxRetryCount(0))
+
+	// each part is in time, the parts are not
+	_, err := client.UploadFile(newUploadFileInput(t, 3*MIN_PART_SIZE), WithTimeout(150*time.Millisecond))
+	if err == nil {
+		t.Fatal("UploadFile succeeded, want the timeout of the call")
+	}
+	if uploaded := atomic.LoadInt32(&parts); uploaded >= 3 {
+		t.Errorf("%d parts are uploaded after the timeout", uploaded)
+	}
+}
+
+func TestUploadFileRateLimitSharedByParts(t *testing.T) {
+	var parts int32
+	server := newMultipartServer(t, 0, &parts)
+	client := newTestClient(t, server.URL)
+
+	input := newUploadFileInput(t, 2*MIN_PART_SIZE)
+	input.TaskNum = 2
+	begin := time.Now()
+	if _, err := client.UploadFile(input, WithRateLimit(MIN_PART_SIZE*10)); err != nil {
+		t.Fatal(err)
+	}
+	// the two parts take 100ms each if they are limited separately
+	if elapsed := time.Since(begin); elapsed < 180*time.Millisecond {
+		t.Errorf("UploadFile took %v, want at least 200ms with the shared rate limit", elapsed)
+	}
+}
+
+// newDownloadServer serves an object of size bytes, each GET is counted in parts when it arrives and is delayed by partDelay.
+func newDownloadServer(t *testing.T, size int, partDelay time.Duration, parts *int32) *httptest.Server {
+	data, modified := bytes.Repeat([]byte("a"), size), time.Unix(1700000000, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"etag"`)
+		if r.Method == http.MethodGet {
+			atomic.AddInt32(parts, 1)
+			time.Sleep(partDelay)
+		}
+		http.ServeContent(w, r, "key", modified, bytes.NewReader(data))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestWithContextCancelsTransfer(t *testing.T) {
+	const partCount, partDelay = 4, 50 * time.Millisecond
+	cases := []struct {
+		name string
+		// transfer runs the transfer with the checkpoint enabled and returns the path of the checkpoint file
+		transfer func(t *testing.T, client *WosClient, ctx context.Context) (string, error)
+		// completed returns the number of the completed parts in the checkpoint file
+		completed func(t *testing.T, checkpointFile string) int
+		newServer func(t *testing.T, parts *int32) *httptest.Server
+	}{
+		{
+			name: "upload",
+			newServer: func(t *testing.T, parts *int32) *httptest.Server {
+				return newMultipartServer(t, partDelay, parts)
+			},
+			transfer: func() func(t *testing.T, client *WosClient, ctx context.Context) (string, error) {
+				var input *UploadFileInput
+				return func(t *testing.T, client *WosClient, ctx context.Context) (string, error) {
+					if input == nil {
+						input = newUploadFileInput(t, partCount*MIN_PART_SIZE)
+						input.EnableCheckpoint = true
+					}
+					_, err := client.UploadFile(input, WithContext(ctx))
+					return input.CheckpointFile, err
+				}
+			}(),
+			completed: func(t *testing.T, checkpointFile string) (completed int) {
+				ufc := &UploadCheckpoint{}
+				if err := loadCheckpointFile(checkpointFile, ufc); err != nil {
+					t.Fatal(err)
+				}
+				for _, part := range ufc.UploadParts {
+					if part.IsCompleted {
+						completed++
+					}
+				}
+				return
+			},
+		},
+		{
+			name: "download",
+			newServer: func(t *testing.T, parts *int32) *httptest.Server {
+				return newDownloadServer(t, partCount*MIN_PART_SIZE, partDelay, parts)
+			},
+			transfer: func() func(t *testing.T, client *WosClient, ctx context.Context) (string, error) {
+				var input *DownloadFileInput
+				return func(t *testing.T, client *WosClient, ctx context.Context) (string, error) {
+					if input == nil {
+						input = &DownloadFileInput{DownloadFile: filepath.Join(t.TempDir(), "download"), PartSize: MIN_PART_SIZE, TaskNum: 1, EnableCheckpoint: true}
+						input.Bucket, input.Key = "bucket", "key"
+					}
+					_, err := client.DownloadFile(input, WithContext(ctx))
+					return input.CheckpointFile, err
+				}
+			}(),
+			completed: func(t *testing.T, checkpointFile string) (completed int) {
+				dfc := &DownloadCheckpoint{}
+				if err := loadCheckpointFile(checkpointFile, dfc); err != nil {
+					t.Fatal(err)
+				}
+				for _, part := range dfc.DownloadParts {
+					if part.IsCompleted {
+						completed++
+					}
+				}
+				return
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var parts int32
+			server := c.newServer(t, &parts)
+			client := newTestClient(t, server.URL)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go func() {
+				// the third part is in flight
+				for atomic.LoadInt32(&parts) < 3 {
+					time.Sleep(time.Millisecond)
+				}
+				cancel()
+			}()
+			checkpointFile, err := c.transfer(t, client, ctx)
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("error = %v, want %v", err, context.Canceled)
+			}
+			// the workers of the pool are stopped when the call returns
+			time.Sleep(3 * partDelay)
+			if got := atomic.LoadInt32(&parts); got != 3 {
+				t.Errorf("%d parts are sent, want 3", got)
+			}
+			completed := c.completed(t, checkpointFile)
+			if completed == 0 || completed == partCount {
+				t.Fatalf("%d parts are completed in the checkpoint, want some of %d", completed, partCount)
+			}
+
+			// only the incomplete parts are sent again
+			atomic.StoreInt32(&parts, 0)
+			if _, err = c.transfer(t, client, context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			if got := int(atomic.LoadInt32(&parts)); got != partCount-completed {
+				t.Errorf("%d parts are sent on resume, want %d", got, partCount-completed)
+			}
+		})
+	}
+}
@@ -2,10 +2,9 @@ package wos
 
 import (
 	"bytes"
+	"context"
 	"errors"
-	"fmt"
 	"io"
-	"math/rand"
 	"net"
 	"net/http"
 	"net/url"
@@ -88,17 +87,37 @@ func (wosClient WosClient) _doActionWithBucketAndKey(action, method, bucketName,
 }
 
 func (wosClient WosClient) doAction(action, method, bucketName, objectKey string, input ISerializable, output IBaseModel, xmlResult bool, repeatable bool, extensions []extensionOptions) error {
+	resp, err := wosClient.doRequest(action, method, bucketName, objectKey, input, output, xmlResult, repeatable, extensions)
+	if resp != nil {
+		_err := resp.Body.Close()
+		wosClient.checkAndLogErr(_err, LEVEL_WARN, "Failed to close resp body")
+	}
+	return err
+}
+
+// doRequest sends the request of an operation and parses the response into output,
+// the response is returned unparsed if output is nil.
+func (wosClient WosClient) doRequest(action, method, bucketName, objectKey string, input ISerializable, output IBaseModel, xmlResult bool, repeatable bool, extensions []extensionOptions) (*http.Response, error) {
 
 	var resp *http.Response
 	var respError error
-	doLog(LEVEL_INFO, "Enter method %s...", action)
-	start := GetCurrentTimestamp()
+	begin := time.Now()
+	wosClient, err := wosClient.withExtensionConfigs(extensions)
+	if err != nil {
+		return nil, err
+	}
+	wosClient, cancel := wosClient.withCallTimeout()
+	wosClient.call = wosClient.newCall(action, bucketName, objectKey)
+	wosClient.logf(LEVEL_INFO, "Enter method %s...", action)
 
 	params, headers, data, err := input.trans(wosClient.conf.signature == SignatureWos)
 	if err != nil {
-		return err
+		cancel()
+		return nil, err
 	}
 
+	wosClient, span := wosClient.startSpan(action, method, bucketName, objectKey, params)
+
 	if params == nil {
 		params = make(map[string]string)
 	}
@@ -111,12 +130,13 @@ func (wosClient WosClient) doAction(action, method, bucketName, objectKey string
 		if extensionHeader, ok := extension.(extensionHeaders); ok {
 			_err := extensionHeader(headers, wosClient.conf.signature == SignatureWos)
 			if _err != nil {
-				doLog(LEVEL_INFO, fmt.Sprintf("set header with error: %v", _err))
+				wosClient.logf(LEVEL_INFO, "set header with error: %v", _err)
 			}
-		} else {
-			doLog(LEVEL_INFO, "Unsupported extensionOptions")
+		} else if _, ok := extension.(extensionConfig); !ok {
+			wosClient.logf(LEVEL_INFO, "Unsupported extensionOptions")
 		}
 	}
+	setHeaders(headers, HEADER_CLIENT_REQUEST_ID, []string{wosClient.call.clientRequestID}, wosClient.conf.signature == SignatureWos)
 
 	switch method {
 	case HTTP_GET:
@@ -132,22 +152,31 @@ func (wosClient WosClient) doAction(action, method, bucketName, objectKey string
 	case HTTP_OPTIONS:
 		resp, respError = wosClient.doHTTPOptions(bucketName, objectKey, params, headers, data, repeatable)
 	default:
-		respError = errors.New("Unexpect http method error")
+		// the other methods, such as PATCH sent by Do, are sent as is
+		resp, respError = wosClient.doHTTP(method, bucketName, objectKey, params, prepareHeaders(headers, false, wosClient.conf.signature == SignatureWos), data, repeatable)
 	}
+	wosClient.finishUpload(respError)
+	wosClient.wrapResponseBody(resp)
+	resp = cancelWithResponse(resp, cancel)
+	wosClient.observeOperation(method, resp, respError, time.Since(begin))
 	if respError == nil && output != nil {
-		respError = ParseResponseToBaseModel(resp, output, xmlResult, wosClient.conf.signature == SignatureWos)
+		respError = wosClient.parseResponseToBaseModel(resp, output, xmlResult, wosClient.conf.signature == SignatureWos)
 		if respError != nil {
-			doLog(LEVEL_WARN, "Parse response to BaseModel with error: %v", respError)
+			wosClient.logf(LEVEL_WARN, "Parse response to BaseModel with error: %v", respError)
 		}
-	} else {
-		doLog(LEVEL_WARN, "Do http request with error: %v", respError)
+	} else if respError != nil {
+		wosClient.log(LEVEL_WARN, "Do http request with error", resultLogFields(resp, respError)...)
 	}
+	respError = wosClient.finishCall(output, respError, begin)
 
-	if isDebugLogEnabled() {
-		doLog(LEVEL_DEBUG, "End method %s, wosclient cost %d ms", action, (GetCurrentTimestamp() - start))
-	}
+	endSpan(span, resp, respError)
 
-	return respError
+	wosClient.log(LEVEL_DEBUG, "End method", append(resultLogFields(resp, respError), latencyField(time.Since(begin)))...)
+
+	if output != nil {
+		return nil, respError
+	}
+	return resp, respError
 }
 
 func (wosClient WosClient) doHTTPGet(bucketName, objectKey string, params map[string]string,
@@ -188,40 +217,56 @@ func prepareAgentHeader(clientUserAgent string) string {
 	return userAgent
 }
 
-func (wosClient WosClient) getSignedURLResponse(action string, output IBaseModel, xmlResult bool, resp *http.Response, err error, start int64) (respError error) {
+func (wosClient WosClient) getSignedURLResponse(output IBaseModel, xmlResult bool, resp *http.Response, err error, begin time.Time) (respError error) {
 	var msg interface{}
+	var _resp *http.Response
 	if err != nil {
 		respError = err
 		resp = nil
 	} else {
-		doLog(LEVEL_DEBUG, "Response headers: %v", resp.Header)
-		if resp.StatusCode >= 300 {
-			respError = ParseResponseToWosError(resp, wosClient.conf.signature == SignatureWos)
+		wosClient.logf(LEVEL_DEBUG, "Response headers: %v", wosClient.getRedactor().redactHeaders(resp.Header))
+		if resp.StatusCode >= 300 && !isPostObjectRedirect(output, resp) {
+			respError = wosClient.parseResponseToWosError(resp, wosClient.conf.signature == SignatureWos)
 			msg = resp.Status
 			resp = nil
 		} else {
+			_resp = resp
+			if isPostObjectRedirect(output, resp) {
+				// the body of the redirect is not the result of the upload
+				_err := resp.Body.Close()
+				wosClient.checkAndLogErr(_err, LEVEL_WARN, "Failed to close resp body")
+				resp.Body = http.NoBody
+			}
 			if output != nil {
-				respError = ParseResponseToBaseModel(resp, output, xmlResult, wosClient.conf.signature == SignatureWos)
+				respError = wosClient.parseResponseToBaseModel(resp, output, xmlResult, wosClient.conf.signature == SignatureWos)
 			}
 			if respError != nil {
-				doLog(LEVEL_WARN, "Parse response to BaseModel with error: %v", respError)
+				wosClient.logf(LEVEL_WARN, "Parse response to BaseModel with error: %v", respError)
 			}
 		}
 	}
 
 	if msg != nil {
-		doLog(LEVEL_ERROR, "Failed to send request with reason:%v", msg)
+		wosClient.log(LEVEL_ERROR, "Failed to send request", resultLogFields(nil, respError)...)
 	}
 
-	if isDebugLogEnabled() {
-		doLog(LEVEL_DEBUG, "End method %s, wosclient cost %d ms", action, (GetCurrentTimestamp() - start))
-	}
+	wosClient.log(LEVEL_DEBUG, "End method", append(resultLogFields(_resp, respError), latencyField(time.Since(begin)))...)
 	return
 }
 
-func (wosClient WosClient) doHTTPWithSignedURL(action, method string, signedURL string, actualSignedRequestHeaders http.Header, data io.Reader, output IBaseModel, xmlResult bool) (respError error) {
+func (wosClient WosClient) doHTTPWithSignedURL(action, method string, signedURL string, actualSignedRequestHeaders http.Header, data io.Reader, output IBaseModel, xmlResult bool, extensions []extensionOptions) (respError error) {
+	wosClient, err := wosClient.withExtensionConfigs(extensions)
+	if err != nil {
+		return err
+	}
+	wosClient, cancel := wosClient.withCallTimeout()
+	// the client request id is only logged, a header out of the signed ones may break the signature of the url
+	wosClient.call = wosClient.newCall(action, "", "")
+	wosClient, span := wosClient.startSpan(action, method, "", "", nil)
 	req, err := http.NewRequest(method, signedURL, data)
 	if err != nil {
+		cancel()
+		endSpan(span, nil, err)
 		return err
 	}
 	if wosClient.conf.ctx != nil {
@@ -229,26 +274,7 @@ func (wosClient WosClient) doHTTPWithSignedURL(action, method string, signedURL
 	}
 	var resp *http.Response
 
-	var isSecurityToken bool
-	var securityToken string
-	var query []string
-	parmas := strings.Split(signedURL, "?")
-	if len(parmas) > 1 {
-		query = strings.Split(parmas[1], "&")
-		for _, value := range query {
-			if strings.HasPrefix(value, HEADER_STS_TOKEN_AMZ+"=") || strings.HasPrefix(value, HEADER_STS_TOKEN_WOS+"=") {
-				if value[len(HEADER_STS_TOKEN_AMZ)+1:] != "" {
-					securityToken = value[len(HEADER_STS_TOKEN_AMZ)+1:]
-					isSecurityToken = true
-				}
-			}
-		}
-	}
-	logSignedURL := signedURL
-	if isSecurityToken {
-		logSignedURL = strings.Replace(logSignedURL, securityToken, "******", -1)
-	}
-	doLog(LEVEL_INFO, "Do %s with signedUrl %s...", action, logSignedURL)
+	wosClient.logf(LEVEL_INFO, "Do %s with signedUrl %s...", action, wosClient.getRedactor().redactURL(signedURL))
 
 	req.Header = actualSignedRequestHeaders
 	if value, ok := req.Header[HEADER_HOST_CAMEL]; ok {
@@ -269,32 +295,59 @@ func (wosClient WosClient) doHTTPWithSignedURL(action, method string, signedURL
 
 	userAgent := prepareAgentHeader(wosClient.conf.userAgent)
 	req.Header[HEADER_USER_AGENT_CAMEL] = []string{userAgent}
-	start := GetCurrentTimestamp()
-	resp, err = wosClient.httpClient.Do(req)
-	if isInfoLogEnabled() {
-		doLog(LEVEL_INFO, "Do http request cost %d ms", (GetCurrentTimestamp() - start))
+	if err = wosClient.afterSign(req); err != nil {
+		cancel()
+		endSpan(span, nil, err)
+		return err
 	}
+	begin := time.Now()
+	resp, err = wosClient.sendRequest(req, 1, false)
+	wosClient.finishUpload(err)
+	wosClient.wrapResponseBody(resp)
+	resp = cancelWithResponse(resp, cancel)
+	wosClient.observeOperation(method, resp, err, time.Since(begin))
+	endSpan(span, resp, err)
 
-	respError = wosClient.getSignedURLResponse(action, output, xmlResult, resp, err, start)
+	respError = wosClient.getSignedURLResponse(output, xmlResult, resp, err, begin)
+	respError = wosClient.finishCall(output, respError, begin)
 
 	return
 }
 
-func prepareData(headers map[string][]string, data interface{}) (io.Reader, error) {
+// sendRequest sends a single attempt of the request.
+func (wosClient WosClient) sendRequest(req *http.Request, attempt int, retry bool) (*http.Response, error) {
+	wosClient.wrapRequestBody(req)
+	req, span := wosClient.startAttemptSpan(req, attempt, retry)
+	req, trace := wosClient.startAttemptTrace(req)
+	dump := wosClient.startWireDump(req)
+	start := time.Now()
+	resp, err := wosClient.sendRequestWithMetrics(req, attempt, retry)
+	wosClient.observeClockSkew(resp)
+	wosClient.finishWireDump(dump, req, resp, attempt)
+	wosClient.finishAttemptTrace(trace, resp)
+	if wosClient.logEnabled(LEVEL_INFO) {
+		fields := append([]LogField{{Key: LogFieldAttempt, Value: attempt}, latencyField(time.Since(start))}, resultLogFields(resp, err)...)
+		wosClient.log(LEVEL_INFO, "Do http request", fields...)
+	}
+	endSpan(span, resp, err)
+	return resp, err
+}
+
+func (wosClient WosClient) prepareData(headers map[string][]string, data interface{}) (io.Reader, error) {
 	var _data io.Reader
 	if data != nil {
 		if dataStr, ok := data.(string); ok {
-			doLog(LEVEL_DEBUG, "Do http request with string: %s", dataStr)
+			wosClient.logf(LEVEL_DEBUG, "Do http request with string: %s", dataStr)
 			headers["Content-Length"] = []string{IntToString(len(dataStr))}
 			_data = strings.NewReader(dataStr)
 		} else if dataByte, ok := data.([]byte); ok {
-			doLog(LEVEL_DEBUG, "Do http request with byte array")
+			wosClient.logf(LEVEL_DEBUG, "Do http request with byte array")
 			headers["Content-Length"] = []string{IntToString(len(dataByte))}
 			_data = bytes.NewReader(dataByte)
 		} else if dataReader, ok := data.(io.Reader); ok {
 			_data = dataReader
 		} else {
-			doLog(LEVEL_WARN, "Data is not a valid io.Reader")
+			wosClient.logf(LEVEL_WARN, "Data is not a valid io.Reader")
 			return nil, errors.New("Data is not a valid io.Reader")
 		}
 	}
@@ -335,31 +388,13 @@ func (wosClient WosClient) getRequest(redirectURL, requestURL string, redirectFl
 	if err != nil {
 		return nil, err
 	}
-	doLog(LEVEL_DEBUG, "Do request with url [%s] and method [%s]", requestURL, method)
+	wosClient.logf(LEVEL_DEBUG, "Do request with url [%s] and method [%s]", wosClient.getRedactor().redactURL(requestURL), method)
 	return req, nil
 }
 
-func logHeaders(headers map[string][]string, signature SignatureType) {
-	if isDebugLogEnabled() {
-		auth := headers[HEADER_AUTH_CAMEL]
-		delete(headers, HEADER_AUTH_CAMEL)
-
-		var isSecurityToken bool
-		var securityToken []string
-		if securityToken, isSecurityToken = headers[HEADER_STS_TOKEN_AMZ]; isSecurityToken {
-			headers[HEADER_STS_TOKEN_AMZ] = []string{"******"}
-		} else if securityToken, isSecurityToken = headers[HEADER_STS_TOKEN_WOS]; isSecurityToken {
-			headers[HEADER_STS_TOKEN_WOS] = []string{"******"}
-		}
-		doLog(LEVEL_DEBUG, "Request headers: %v", headers)
-		headers[HEADER_AUTH_CAMEL] = auth
-		if isSecurityToken {
-			if signature == SignatureWos {
-				headers[HEADER_STS_TOKEN_WOS] = securityToken
-			} else {
-				headers[HEADER_STS_TOKEN_AMZ] = securityToken
-			}
-		}
+func (wosClient WosClient) logHeaders(headers map[string][]string) {
+	if wosClient.logEnabled(LEVEL_DEBUG) {
+		wosClient.logf(LEVEL_DEBUG, "Request headers: %v", wosClient.getRedactor().redactHeaders(headers))
 	}
 }
 
@@ -389,7 +424,7 @@ func prepareReq(headers map[string][]string, req, lastRequest *http.Request, cli
 }
 
 func canNotRetry(repeatable bool, statusCode int) bool {
-	if !repeatable || (statusCode >= 400 && statusCode < 500) || statusCode == 304 {
+	if !repeatable || statusCode == 304 {
 		return true
 	}
 	return false
@@ -411,16 +446,15 @@ func setRedirectFlag(statusCode int, method string) (redirectFlag bool) {
 	return
 }
 
-func prepareRetry(resp *http.Response, headers map[string][]string, _data io.Reader, msg interface{}) (io.Reader, *http.Response, error) {
+func (wosClient WosClient) prepareRetry(resp *http.Response, headers map[string][]string, _data io.Reader) (io.Reader, *http.Response, error) {
 	if resp != nil {
 		_err := resp.Body.Close()
-		checkAndLogErr(_err, LEVEL_WARN, "Failed to close resp body")
+		wosClient.checkAndLogErr(_err, LEVEL_WARN, "Failed to close resp body")
 		resp = nil
 	}
 	if _, ok := headers[HEADER_AUTH_CAMEL]; ok {
 		delete(headers, HEADER_AUTH_CAMEL)
 	}
-	doLog(LEVEL_WARN, "Failed to send request with reason:%v, will try again", msg)
 	if r, ok := _data.(*strings.Reader); ok {
 		_, err := r.Seek(0, 0)
 		if err != nil {
@@ -444,7 +478,7 @@ func prepareRetry(resp *http.Response, headers map[string][]string, _data io.Rea
 		_, err = fd.Seek(r.mark, 0)
 		if err != nil {
 			errMsg := fd.Close()
-			checkAndLogErr(errMsg, LEVEL_WARN, "Failed to close with reason: %v", errMsg)
+			wosClient.checkAndLogErr(errMsg, LEVEL_WARN, "Failed to close with reason: %v", errMsg)
 			return nil, nil, err
 		}
 	} else if r, ok := _data.(*readerWrapper); ok {
@@ -463,70 +497,142 @@ func (wosClient WosClient) doHTTP(method, bucketName, objectKey string, params m
 
 	method = strings.ToUpper(method)
 
+	for key, value := range wosClient.conf.requestHeaders {
+		headers[key] = value
+	}
+	if params == nil && len(wosClient.conf.requestParams) > 0 {
+		params = make(map[string]string, len(wosClient.conf.requestParams))
+	}
+	for key, value := range wosClient.conf.requestParams {
+		params[key] = value
+	}
+
 	var redirectURL string
 	var requestURL string
 	maxRetryCount := wosClient.conf.maxRetryCount
 	maxRedirectCount := wosClient.conf.maxRedirectCount
 
-	_data, _err := prepareData(headers, data)
+	_data, _err := wosClient.prepareData(headers, data)
 	if _err != nil {
 		return nil, _err
 	}
+	if wosClient, _data, _err = wosClient.withPayloadSigning(_data, headers); _err != nil {
+		return nil, _err
+	}
 
 	var lastRequest *http.Request
 	redirectFlag := false
+	firstAttempt := time.Now()
+	retryTokens := 0
+	retry := false
+	skewRetried := false
+	var failedEndpoints map[*poolEndpoint]bool
 	for i, redirectCount := 0, 0; i <= maxRetryCount; i++ {
-		req, err := wosClient.getRequest(redirectURL, requestURL, redirectFlag, _data,
+		attemptClient, endpoint := wosClient.pickEndpoint(failedEndpoints)
+		if diagnostics := wosClient.call.diagnostics; diagnostics != nil {
+			diagnostics.Endpoint = attemptClient.conf.endpoint
+		}
+		if err := attemptClient.beforeSign(method, bucketName, objectKey, params, headers, i+1); err != nil {
+			return nil, err
+		}
+		req, err := attemptClient.getRequest(redirectURL, requestURL, redirectFlag, _data,
 			method, bucketName, objectKey, params, headers)
 		if err != nil {
 			return nil, err
 		}
 
-		logHeaders(headers, wosClient.conf.signature)
+		wosClient.logHeaders(headers)
 
 		lastRequest = prepareReq(headers, req, lastRequest, wosClient.conf.userAgent)
+		if err = attemptClient.afterSign(req); err != nil {
+			return nil, err
+		}
 
-		start := GetCurrentTimestamp()
-		resp, err = wosClient.httpClient.Do(req)
-		doLog(LEVEL_INFO, "Do http request cost %d ms", (GetCurrentTimestamp() - start))
+		attemptStart := time.Now()
+		resp, err = attemptClient.sendRequest(req, i+1, retry)
+		if endpoint != nil && redirectURL == "" {
+			statusCode := 0
+			if resp != nil {
+				statusCode = resp.StatusCode
+			}
+			if wosClient.conf.endpointPool.report(&attemptClient, endpoint, statusCode, err, time.Since(attemptStart)) {
+				if failedEndpoints == nil {
+					failedEndpoints = make(map[*poolEndpoint]bool)
+				}
+				failedEndpoints[endpoint] = true
+			}
+		}
 
 		var msg interface{}
+		var delay time.Duration
+		rc := &RetryContext{Method: method, Attempt: i + 1 - redirectCount, Elapsed: time.Since(firstAttempt)}
 		if err != nil {
 			msg = err
 			respError = err
 			resp = nil
-			if !repeatable {
+			if !repeatable || isContextDone(wosClient.conf.ctx) {
 				break
 			}
+			rc.Err = err
 		} else {
-			doLog(LEVEL_DEBUG, "Response headers: %v", resp.Header)
+			wosClient.logf(LEVEL_DEBUG, "Response headers: %v", wosClient.getRedactor().redactHeaders(resp.Header))
 			if resp.StatusCode < 300 {
 				respError = nil
+				wosClient.releaseRetryTokens(retryTokens)
 				break
 			} else if canNotRetry(repeatable, resp.StatusCode) {
-				respError = ParseResponseToWosError(resp, wosClient.conf.signature == SignatureWos)
+				respError = wosClient.parseResponseToWosError(resp, wosClient.conf.signature == SignatureWos)
 				resp = nil
 				break
 			} else if resp.StatusCode >= 300 && resp.StatusCode < 400 {
 				location := resp.Header.Get(HEADER_LOCATION_CAMEL)
 				if isRedirectErr(location, redirectCount, maxRedirectCount) {
 					redirectURL = location
-					doLog(LEVEL_WARN, "Redirect request to %s", redirectURL)
+					if diagnostics := wosClient.call.diagnostics; diagnostics != nil {
+						diagnostics.RedirectChain = append(diagnostics.RedirectChain, wosClient.getRedactor().redactURL(location))
+					}
+					wosClient.logf(LEVEL_WARN, "Redirect request to %s", redirectURL)
 					msg = resp.Status
 					maxRetryCount++
 					redirectCount++
 					redirectFlag = setRedirectFlag(resp.StatusCode, method)
+					rc = nil
 				} else {
-					respError = ParseResponseToWosError(resp, wosClient.conf.signature == SignatureWos)
+					respError = wosClient.parseResponseToWosError(resp, wosClient.conf.signature == SignatureWos)
 					resp = nil
 					break
 				}
 			} else {
 				msg = resp.Status
+				respError = wosClient.parseResponseToWosError(resp, wosClient.conf.signature == SignatureWos)
+				rc.StatusCode = resp.StatusCode
+				rc.Header = resp.Header
+				if wosError, ok := respError.(WosError); ok {
+					rc.ErrorCode = wosError.Code
+				}
+				resp = nil
+				if !skewRetried && isClockSkewError(respError) {
+					// re-sign the request once with the offset detected from the response
+					skewRetried = true
+					resetDateHeaders(headers)
+					maxRetryCount++
+					rc = nil
+				}
+			}
+		}
+		if rc != nil && i != maxRetryCount {
+			var cost int
+			var ok bool
+			if delay, cost, ok = wosClient.shouldRetry(rc); !ok {
+				break
 			}
+			retryTokens += cost
 		}
+		retry = rc != nil
 		if i != maxRetryCount {
-			_data, resp, err = prepareRetry(resp, headers, _data, msg)
+			wosClient.log(LEVEL_WARN, "Failed to send request, will try again",
+				LogField{Key: LogFieldAttempt, Value: i + 1}, LogField{Key: LogFieldError, Value: msg})
+			_data, resp, err = wosClient.prepareRetry(resp, headers, _data)
 			if err != nil {
 				return nil, err
 			}
@@ -534,15 +640,17 @@ func (wosClient WosClient) doHTTP(method, bucketName, objectKey string, params m
 				if _fd, _ok := r.reader.(*os.File); _ok {
 					defer func() {
 						errMsg := _fd.Close()
-						checkAndLogErr(errMsg, LEVEL_WARN, "Failed to close with reason: %v", errMsg)
+						wosClient.checkAndLogErr(errMsg, LEVEL_WARN, "Failed to close with reason: %v", errMsg)
 					}()
 				}
 			}
-			time.Sleep(time.Duration(float64(i+2) * rand.Float64() * float64(time.Second)))
+			if err = sleepWithContext(wosClient.conf.ctx, delay); err != nil {
+				return nil, err
+			}
 		} else {
-			doLog(LEVEL_ERROR, "Failed to send request with reason:%v", msg)
+			wosClient.logf(LEVEL_ERROR, "Failed to send request with reason:%v", msg)
 			if resp != nil {
-				respError = ParseResponseToWosError(resp, wosClient.conf.signature == SignatureWos)
+				respError = wosClient.parseResponseToWosError(resp, wosClient.conf.signature == SignatureWos)
 				resp = nil
 			}
 		}
@@ -554,6 +662,7 @@ type connDelegate struct {
 	conn          net.Conn
 	socketTimeout time.Duration
 	finalTimeout  time.Duration
+	logger        Logger
 }
 
 func getConnDelegate(conn net.Conn, socketTimeout int, finalTimeout int) *connDelegate {
@@ -561,41 +670,68 @@ func getConnDelegate(conn net.Conn, socketTimeout int, finalTimeout int) *connDe
 		conn:          conn,
 		socketTimeout: time.Second * time.Duration(socketTimeout),
 		finalTimeout:  time.Second * time.Duration(finalTimeout),
+		logger:        DefaultLogger(),
+	}
+}
+
+// SocketTimeoutDialContext wraps the dial function of a custom transport with the socket timeout of WosClient:
+// a read or a write of the connections fails if it is blocked longer than socketTimeout, and the connections
+// are expired finalTimeout after the last read or write. The net.Dialer is used if dialContext is nil.
+func SocketTimeoutDialContext(dialContext func(ctx context.Context, network, addr string) (net.Conn, error),
+	socketTimeout, finalTimeout time.Duration) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return socketTimeoutDialContext(dialContext, socketTimeout, finalTimeout, DefaultLogger())
+}
+
+// socketTimeoutDialContext is SocketTimeoutDialContext with the connections logging to logger.
+func socketTimeoutDialContext(dialContext func(ctx context.Context, network, addr string) (net.Conn, error),
+	socketTimeout, finalTimeout time.Duration, logger Logger) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if dialContext == nil {
+		dialContext = (&net.Dialer{}).DialContext
+	}
+	if finalTimeout < socketTimeout {
+		finalTimeout = socketTimeout
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &connDelegate{conn: conn, socketTimeout: socketTimeout, finalTimeout: finalTimeout, logger: logger}, nil
 	}
 }
 
 func (delegate *connDelegate) Read(b []byte) (n int, err error) {
 	setReadDeadlineErr := delegate.SetReadDeadline(time.Now().Add(delegate.socketTimeout))
-	flag := isDebugLogEnabled()
+	flag := delegate.logger.Enabled(LEVEL_DEBUG)
 
 	if setReadDeadlineErr != nil && flag {
-		doLog(LEVEL_DEBUG, "Failed to set read deadline with reason: %v, but it's ok", setReadDeadlineErr)
+		loggerf(delegate.logger, LEVEL_DEBUG, "Failed to set read deadline with reason: %v, but it's ok", setReadDeadlineErr)
 	}
 
 	n, err = delegate.conn.Read(b)
 	setReadDeadlineErr = delegate.SetReadDeadline(time.Now().Add(delegate.finalTimeout))
 	if setReadDeadlineErr != nil && flag {
-		doLog(LEVEL_DEBUG, "Failed to set read deadline with reason: %v, but it's ok", setReadDeadlineErr)
+		loggerf(delegate.logger, LEVEL_DEBUG, "Failed to set read deadline with reason: %v, but it's ok", setReadDeadlineErr)
 	}
 	return n, err
 }
 
 func (delegate *connDelegate) Write(b []byte) (n int, err error) {
 	setWriteDeadlineErr := delegate.SetWriteDeadline(time.Now().Add(delegate.socketTimeout))
-	flag := isDebugLogEnabled()
+	flag := delegate.logger.Enabled(LEVEL_DEBUG)
 	if setWriteDeadlineErr != nil && flag {
-		doLog(LEVEL_DEBUG, "Failed to set write deadline with reason: %v, but it's ok", setWriteDeadlineErr)
+		loggerf(delegate.logger, LEVEL_DEBUG, "Failed to set write deadline with reason: %v, but it's ok", setWriteDeadlineErr)
 	}
 
 	n, err = delegate.conn.Write(b)
 	finalTimeout := time.Now().Add(delegate.finalTimeout)
 	setWriteDeadlineErr = delegate.SetWriteDeadline(finalTimeout)
 	if setWriteDeadlineErr != nil && flag {
-		doLog(LEVEL_DEBUG, "Failed to set write deadline with reason: %v, but it's ok", setWriteDeadlineErr)
+		loggerf(delegate.logger, LEVEL_DEBUG, "Failed to set write deadline with reason: %v, but it's ok", setWriteDeadlineErr)
 	}
 	setReadDeadlineErr := delegate.SetReadDeadline(finalTimeout)
 	if setReadDeadlineErr != nil && flag {
-		doLog(LEVEL_DEBUG, "Failed to set read deadline with reason: %v, but it's ok", setReadDeadlineErr)
+		loggerf(delegate.logger, LEVEL_DEBUG, "Failed to set read deadline with reason: %v, but it's ok", setReadDeadlineErr)
 	}
 	return n, err
 }
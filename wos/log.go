@@ -1,13 +1,20 @@
 package wos
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 )
 
 // Level defines the level of the log
@@ -29,12 +36,51 @@ var logLevelMap = map[Level]string{
 	LEVEL_DEBUG: "[DEBUG]: ",
 }
 
+var logLevelNameMap = map[Level]string{
+	LEVEL_OFF:   "OFF",
+	LEVEL_ERROR: "ERROR",
+	LEVEL_WARN:  "WARN",
+	LEVEL_INFO:  "INFO",
+	LEVEL_DEBUG: "DEBUG",
+}
+
+const compressedLogSuffix = ".gz"
+
+// LogConfig defines the options of the logging function
+type LogConfig struct {
+	// LogFullPath is the path of the log file, no log file is written if it is empty.
+	LogFullPath string
+	// MaxLogSize is the size in bytes at which the log file is rotated, 30MB by default.
+	MaxLogSize int64
+	// Backups is the maximum number of the rotated log files kept, 10 by default. With RotateInterval the rotated
+	// log files are only counted if Backups is set, otherwise they are kept until MaxAge.
+	Backups int
+	Level   Level
+	// LogToConsole writes the log messages to the standard output as well.
+	LogToConsole bool
+	// CacheCnt is the number of the log messages cached before they are written to the log file, 50 by default.
+	CacheCnt int
+	// RotateInterval rotates the log file when a new interval begins, such as 24 * time.Hour for daily rotation.
+	// The intervals are aligned to UTC, 0 disables the rotation by time.
+	RotateInterval time.Duration
+	// Compress compresses the rotated log files with gzip.
+	Compress bool
+	// MaxAge removes the rotated log files older than it, 0 keeps them.
+	MaxAge time.Duration
+	// JSONFormat writes the log messages as JSON lines.
+	JSONFormat bool
+}
+
 type logConfType struct {
-	level        Level
-	logToConsole bool
-	logFullPath  string
-	maxLogSize   int64
-	backups      int
+	level          Level
+	logToConsole   bool
+	logFullPath    string
+	maxLogSize     int64
+	backups        int
+	rotateInterval time.Duration
+	compress       bool
+	maxAge         time.Duration
+	jsonFormat     bool
 }
 
 func getDefaultLogConf() logConfType {
@@ -50,25 +96,58 @@ func getDefaultLogConf() logConfType {
 var logConf logConfType
 
 type loggerWrapper struct {
-	fullPath   string
-	fd         *os.File
-	ch         chan string
-	wg         sync.WaitGroup
-	queue      []string
-	logger     *log.Logger
-	index      int
-	cacheCount int
-	closed     bool
+	fullPath    string
+	fd          *os.File
+	ch          chan string
+	wg          sync.WaitGroup
+	queue       []string
+	index       int
+	cacheCount  int
+	closed      bool
+	periodStart time.Time
 }
 
 func (lw *loggerWrapper) doInit() {
 	lw.queue = make([]string, 0, lw.cacheCount)
-	lw.logger = log.New(lw.fd, "", 0)
 	lw.ch = make(chan string, lw.cacheCount)
 	lw.wg.Add(1)
 	go lw.doWrite()
 }
 
+func getLogPeriodStart(t time.Time) time.Time {
+	if logConf.rotateInterval <= 0 {
+		return time.Time{}
+	}
+	return t.UTC().Truncate(logConf.rotateInterval)
+}
+
+// getRotatedPath returns the path which the log file is renamed to: the index of the backup if the log file is
+// rotated by size only, otherwise the beginning of the interval which the log file belongs to.
+func (lw *loggerWrapper) getRotatedPath() string {
+	if logConf.rotateInterval <= 0 {
+		if lw.index > logConf.backups {
+			lw.index = 1
+		}
+		rotatedPath := lw.fullPath + "." + IntToString(lw.index)
+		lw.index++
+		return rotatedPath
+	}
+
+	layout := "2006-01-02T15-04-05"
+	if logConf.rotateInterval%(24*time.Hour) == 0 {
+		layout = "2006-01-02"
+	}
+	rotatedPath := lw.fullPath + "." + lw.periodStart.Format(layout)
+	for i := 1; ; i++ {
+		_, err := os.Stat(rotatedPath)
+		_, _err := os.Stat(rotatedPath + compressedLogSuffix)
+		if os.IsNotExist(err) && os.IsNotExist(_err) {
+			return rotatedPath
+		}
+		rotatedPath = lw.fullPath + "." + lw.periodStart.Format(layout) + "-" + IntToString(i)
+	}
+}
+
 func (lw *loggerWrapper) rotate() {
 	stat, err := lw.fd.Stat()
 	if err != nil {
@@ -78,7 +157,16 @@ func (lw *loggerWrapper) rotate() {
 		}
 		panic(err)
 	}
-	if stat.Size() >= logConf.maxLogSize {
+	now := time.Now()
+	rotateByTime := false
+	if periodStart := getLogPeriodStart(now); !periodStart.Equal(lw.periodStart) {
+		if stat.Size() > 0 {
+			rotateByTime = true
+		} else {
+			lw.periodStart = periodStart
+		}
+	}
+	if stat.Size() >= logConf.maxLogSize || rotateByTime {
 		_err := lw.fd.Sync()
 		if _err != nil {
 			panic(err)
@@ -87,28 +175,38 @@ func (lw *loggerWrapper) rotate() {
 		if _err != nil {
 			doLog(LEVEL_WARN, "Failed to close file with reason: %v", _err)
 		}
-		if lw.index > logConf.backups {
-			lw.index = 1
-		}
-		_err = os.Rename(lw.fullPath, lw.fullPath+"."+IntToString(lw.index))
+		// renaming is atomic, the log file is either complete under the old name or under the new one
+		rotatedPath := lw.getRotatedPath()
+		_err = os.Rename(lw.fullPath, rotatedPath)
 		if _err != nil {
 			panic(err)
 		}
-		lw.index++
 
 		fd, err := os.OpenFile(lw.fullPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
 		if err != nil {
 			panic(err)
 		}
 		lw.fd = fd
-		lw.logger.SetOutput(lw.fd)
+		lw.periodStart = getLogPeriodStart(now)
+		cleanRotatedLogFilesAsync(lw.fullPath, logConf)
 	}
 }
 
 func (lw *loggerWrapper) doFlush() {
 	lw.rotate()
+	// write the cached messages at once, so that a crash leaves at most one partial line at the end of the file
+	var buf bytes.Buffer
 	for _, m := range lw.queue {
-		lw.logger.Println(m)
+		buf.WriteString(m)
+		if !strings.HasSuffix(m, "\n") {
+			buf.WriteByte('\n')
+		}
+	}
+	if _, err := lw.fd.Write(buf.Bytes()); err != nil {
+		// the log file is unwritable, such as the disk is full, which must not crash the process
+		fmt.Fprintf(os.Stderr, "%s [ERROR]: Failed to write %d messages to the log file %s with reason: %v\n",
+			FormatUtcNow("2006-01-02T15:04:05Z"), len(lw.queue), lw.fullPath, err)
+		return
 	}
 	err := lw.fd.Sync()
 	if err != nil {
@@ -150,6 +248,113 @@ func (lw *loggerWrapper) Printf(format string, v ...interface{}) {
 	}
 }
 
+// compressLogFile compresses the rotated log file to a temporary file and renames it,
+// so that a crash never leaves a partial compressed file.
+//
+// compressLogFile and cleanRotatedLogFiles run out of the goroutine writing the log file while it may be closed,
+// so they do not log the errors.
+func compressLogFile(path string) error {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tempPath := path + compressedLogSuffix + ".tmp"
+	dst, err := os.OpenFile(tempPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	writer := gzip.NewWriter(dst)
+	_, err = io.Copy(writer, src)
+	if err == nil {
+		err = writer.Close()
+	}
+	if err == nil {
+		err = dst.Sync()
+	}
+	if _err := dst.Close(); err == nil {
+		err = _err
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	// keep the modification time, which the retention by age depends on
+	os.Chtimes(tempPath, stat.ModTime(), stat.ModTime())
+	if err = os.Rename(tempPath, path+compressedLogSuffix); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// cleanLock serializes the cleanings of the rotated log files, cleanWg waits for them.
+var cleanLock sync.Mutex
+var cleanWg sync.WaitGroup
+
+// cleanRotatedLogFilesAsync cleans the rotated log files in the background, so that compressing them blocks neither
+// the messages nor InitLogWithConfig.
+func cleanRotatedLogFilesAsync(fullPath string, conf logConfType) {
+	if !conf.compress && conf.maxAge <= 0 && conf.rotateInterval <= 0 {
+		return
+	}
+	cleanWg.Add(1)
+	go func() {
+		defer cleanWg.Done()
+		cleanLock.Lock()
+		defer cleanLock.Unlock()
+		cleanRotatedLogFiles(fullPath, conf)
+	}()
+}
+
+// cleanRotatedLogFiles compresses the rotated log files if needed, including those left by a crash,
+// and removes the ones exceeding the retention.
+func cleanRotatedLogFiles(fullPath string, logConf logConfType) {
+	dir := filepath.Dir(fullPath)
+	prefix := filepath.Base(fullPath) + "."
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	rotated := make([]os.FileInfo, 0, len(infos))
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if strings.HasSuffix(name, ".tmp") {
+			// left by a crash during compression, the rotated log file is still there
+			os.Remove(filepath.Join(dir, name))
+			continue
+		}
+		if logConf.compress && !strings.HasSuffix(name, compressedLogSuffix) {
+			if compressLogFile(filepath.Join(dir, name)) == nil {
+				if _info, _err := os.Stat(filepath.Join(dir, name+compressedLogSuffix)); _err == nil {
+					info = _info
+				}
+			}
+		}
+		rotated = append(rotated, info)
+	}
+
+	sort.Slice(rotated, func(i, j int) bool {
+		return rotated[i].ModTime().After(rotated[j].ModTime())
+	})
+	now := time.Now()
+	for i, info := range rotated {
+		expired := logConf.maxAge > 0 && now.Sub(info.ModTime()) > logConf.maxAge
+		// the backups of the rotation by size are reused by index, those of the rotation by time are counted here
+		exceeded := logConf.rotateInterval > 0 && logConf.backups > 0 && i >= logConf.backups
+		if expired || exceeded {
+			os.Remove(filepath.Join(dir, info.Name()))
+		}
+	}
+}
+
 var consoleLogger *log.Logger
 var fileLogger *loggerWrapper
 var lock = new(sync.RWMutex)
@@ -186,13 +391,45 @@ func InitLog(logFullPath string, maxLogSize int64, backups int, level Level, log
 
 // InitLogWithCacheCnt enable logging function
 func InitLogWithCacheCnt(logFullPath string, maxLogSize int64, backups int, level Level, logToConsole bool, cacheCnt int) error {
+	return InitLogWithConfig(LogConfig{
+		LogFullPath:  logFullPath,
+		MaxLogSize:   maxLogSize,
+		Backups:      backups,
+		Level:        level,
+		LogToConsole: logToConsole,
+		CacheCnt:     cacheCnt,
+	})
+}
+
+// InitLogWithConfig enable logging function with the rotation, compression, retention and format options
+func InitLogWithConfig(config LogConfig) error {
 	lock.Lock()
 	defer lock.Unlock()
+	cacheCnt := config.CacheCnt
 	if cacheCnt <= 0 {
 		cacheCnt = 50
 	}
 	reset()
-	if fullPath := strings.TrimSpace(logFullPath); fullPath != "" {
+	if config.MaxLogSize > 0 {
+		logConf.maxLogSize = config.MaxLogSize
+	}
+	if config.Backups > 0 {
+		logConf.backups = config.Backups
+	}
+	if config.RotateInterval > 0 {
+		logConf.rotateInterval = config.RotateInterval
+		if config.Backups <= 0 {
+			// the retention of the rotation by time is decided by MaxAge
+			logConf.backups = 0
+		}
+	}
+	if config.MaxAge > 0 {
+		logConf.maxAge = config.MaxAge
+	}
+	logConf.compress = config.Compress
+	logConf.jsonFormat = config.JSONFormat
+
+	if fullPath := strings.TrimSpace(config.LogFullPath); fullPath != "" {
 		_fullPath, err := filepath.Abs(fullPath)
 		if err != nil {
 			return err
@@ -213,7 +450,8 @@ func InitLogWithCacheCnt(logFullPath string, maxLogSize int64, backups int, leve
 		walkFunc := func(path string, info os.FileInfo, err error) error {
 			if err == nil {
 				if name := info.Name(); strings.HasPrefix(name, prefix) {
-					if i := StringToInt(name[len(prefix):], 0); i >= index && info.ModTime().Unix() >= timeIndex {
+					suffix := strings.TrimSuffix(name[len(prefix):], compressedLogSuffix)
+					if i := StringToInt(suffix, 0); i >= index && info.ModTime().Unix() >= timeIndex {
 						timeIndex = info.ModTime().Unix()
 						index = i + 1
 					}
@@ -230,18 +468,23 @@ func InitLogWithCacheCnt(logFullPath string, maxLogSize int64, backups int, leve
 			return err
 		}
 
-		fileLogger = &loggerWrapper{fullPath: _fullPath, fd: fd, index: index, cacheCount: cacheCnt, closed: false}
+		periodStart := getLogPeriodStart(time.Now())
+		if stat.Size() > 0 {
+			// the existing log file belongs to the interval when it was last written
+			periodStart = getLogPeriodStart(stat.ModTime())
+		}
+		cleanRotatedLogFilesAsync(_fullPath, logConf)
+
+		fileLogger = &loggerWrapper{fullPath: _fullPath, fd: fd, index: index, cacheCount: cacheCnt, closed: false, periodStart: periodStart}
 		fileLogger.doInit()
 	}
-	if maxLogSize > 0 {
-		logConf.maxLogSize = maxLogSize
-	}
-	if backups > 0 {
-		logConf.backups = backups
-	}
-	logConf.level = level
-	if logToConsole {
-		consoleLogger = log.New(os.Stdout, "", log.LstdFlags)
+	logConf.level = config.Level
+	if config.LogToConsole {
+		if logConf.jsonFormat {
+			consoleLogger = log.New(os.Stdout, "", 0)
+		} else {
+			consoleLogger = log.New(os.Stdout, "", log.LstdFlags)
+		}
 	}
 	return nil
 }
@@ -254,7 +497,7 @@ func initLogFile(_fullPath string) (os.FileInfo, *os.File, error) {
 		return nil, nil, err
 	}
 
-	fd, err := os.OpenFile(_fullPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	fd, err := os.OpenFile(_fullPath, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -269,6 +512,15 @@ func initLogFile(_fullPath string) (os.FileInfo, *os.File, error) {
 			return nil, nil, err
 		}
 	}
+
+	// terminate the partial line left by a crash, so that it does not corrupt the next message
+	if size := stat.Size(); size > 0 {
+		last := make([]byte, 1)
+		if _, err = fd.ReadAt(last, size-1); err == nil && last[0] != '\n' {
+			_, err = fd.Write([]byte{'\n'})
+			checkAndLogErr(err, LEVEL_WARN, "Failed to terminate the partial line with reason: %v", err)
+		}
+	}
 	return stat, fd, nil
 }
 
@@ -287,28 +539,84 @@ func logEnabled() bool {
 
 // DoLog writes log messages to the logger
 func DoLog(level Level, format string, v ...interface{}) {
-	doLog(level, format, v...)
+	doLogWithDepth(2, level, format, v...)
 }
 
 func doLog(level Level, format string, v ...interface{}) {
+	doLogWithDepth(2, level, format, v...)
+}
+
+// doLogWithDepth writes log messages with the file and line of the caller at the depth of the call stack.
+func doLogWithDepth(depth int, level Level, format string, v ...interface{}) {
 	if logEnabled() && logConf.level <= level {
-		msg := fmt.Sprintf(format, v...)
-		if _, file, line, ok := runtime.Caller(1); ok {
-			index := strings.LastIndex(file, "/")
-			if index >= 0 {
-				file = file[index+1:]
-			}
-			msg = fmt.Sprintf("%s:%d|%s", file, line, msg)
+		writeLog(depth+1, level, fmt.Sprintf(format, v...), nil)
+	}
+}
+
+func writeLog(depth int, level Level, msg string, fields []LogField) {
+	var caller string
+	if _, file, line, ok := runtime.Caller(depth); ok {
+		index := strings.LastIndex(file, "/")
+		if index >= 0 {
+			file = file[index+1:]
 		}
-		prefix := logLevelMap[level]
+		caller = fmt.Sprintf("%s:%d", file, line)
+	}
+	nowDate := FormatUtcNow("2006-01-02T15:04:05Z")
+
+	if logConf.jsonFormat {
+		line := formatJSONLog(nowDate, level, caller, msg, fields)
 		if consoleLogger != nil {
-			consoleLogger.Printf("%s%s", prefix, msg)
+			consoleLogger.Print(line)
 		}
 		if fileLogger != nil {
-			nowDate := FormatUtcNow("2006-01-02T15:04:05Z")
-			fileLogger.Printf("%s %s%s", nowDate, prefix, msg)
+			fileLogger.Printf("%s", line)
+		}
+		return
+	}
+
+	msg = formatLogFields(msg, fields)
+	if caller != "" {
+		msg = caller + "|" + msg
+	}
+	prefix := logLevelMap[level]
+	if consoleLogger != nil {
+		consoleLogger.Printf("%s%s", prefix, msg)
+	}
+	if fileLogger != nil {
+		fileLogger.Printf("%s %s%s", nowDate, prefix, msg)
+	}
+}
+
+func formatJSONLog(nowDate string, level Level, caller, msg string, fields []LogField) string {
+	var buf bytes.Buffer
+	writeJSONField := func(key string, value interface{}) {
+		switch value.(type) {
+		case string, bool, int, int32, int64, uint, uint32, uint64, float32, float64:
+		default:
+			value = fmt.Sprint(value)
+		}
+		_key, _ := json.Marshal(key)
+		_value, err := json.Marshal(value)
+		if err != nil {
+			_value, _ = json.Marshal(fmt.Sprint(value))
 		}
+		buf.WriteByte(',')
+		buf.Write(_key)
+		buf.WriteByte(':')
+		buf.Write(_value)
+	}
+	buf.WriteString(`{"time":"` + nowDate + `"`)
+	writeJSONField("level", logLevelNameMap[level])
+	if caller != "" {
+		writeJSONField("caller", caller)
+	}
+	writeJSONField("msg", msg)
+	for _, field := range fields {
+		writeJSONField(field.Key, field.Value)
 	}
+	buf.WriteByte('}')
+	return buf.String()
 }
 
 func checkAndLogErr(err error, level Level, format string, v ...interface{}) {
@@ -0,0 +1,102 @@
+package wos
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestCleanRotatedLogFiles(t *testing.T) {
+	cases := []struct {
+		name string
+		conf logConfType
+		want []string
+	}{
+		{"compress", logConfType{compress: true}, []string{"wos.log.1.gz", "wos.log.2.gz", "wos.log.3.gz"}},
+		{"max age", logConfType{maxAge: 90 * time.Minute}, []string{"wos.log.1"}},
+		{"backups by time", logConfType{rotateInterval: time.Hour, backups: 2}, []string{"wos.log.1", "wos.log.2"}},
+		{"max age by time", logConfType{rotateInterval: time.Hour, maxAge: 150 * time.Minute}, []string{"wos.log.1", "wos.log.2"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			dir := t.TempDir()
+			fullPath := filepath.Join(dir, "wos.log")
+			for i, name := range []string{"wos.log", "wos.log.1", "wos.log.2", "wos.log.3", "wos.log.1.gz.tmp"} {
+				path := filepath.Join(dir, name)
+				if err := ioutil.WriteFile(path, []byte("message\n"), 0600); err != nil {
+					t.Fatal(err)
+				}
+				modTime := time.Now().Add(-time.Duration(i) * time.Hour)
+				if err := os.Chtimes(path, modTime, modTime); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			cleanRotatedLogFiles(fullPath, c.conf)
+
+			infos, err := ioutil.ReadDir(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, info := range infos {
+				if info.Name() != "wos.log" {
+					names = append(names, info.Name())
+				}
+			}
+			sort.Strings(names)
+			if len(names) != len(c.want) {
+				t.Fatalf("rotated log files = %v, want %v", names, c.want)
+			}
+			for i := range names {
+				if names[i] != c.want[i] {
+					t.Fatalf("rotated log files = %v, want %v", names, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestInitLogWithConfigBackups(t *testing.T) {
+	cases := []struct {
+		name    string
+		config  LogConfig
+		backups int
+	}{
+		{"by size", LogConfig{}, 10},
+		{"by size with backups", LogConfig{Backups: 3}, 3},
+		{"by time", LogConfig{RotateInterval: time.Hour}, 0},
+		{"by time with backups", LogConfig{RotateInterval: time.Hour, Backups: 3}, 3},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.config.LogFullPath = filepath.Join(t.TempDir(), "wos.log")
+			if err := InitLogWithConfig(c.config); err != nil {
+				t.Fatal(err)
+			}
+			backups := logConf.backups
+			CloseLog()
+			cleanWg.Wait()
+			if backups != c.backups {
+				t.Errorf("backups = %d, want %d", backups, c.backups)
+			}
+		})
+	}
+}
+
+func TestLoggerWrapperFlushWriteError(t *testing.T) {
+	fullPath := filepath.Join(t.TempDir(), "wos.log")
+	fd, err := os.OpenFile(fullPath, os.O_CREATE|os.O_RDONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	logConf = getDefaultLogConf()
+	lw := &loggerWrapper{fullPath: fullPath, fd: fd, queue: []string{"message"}}
+
+	// a read only log file fails the write, which must not panic
+	lw.doFlush()
+}
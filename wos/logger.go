@@ -0,0 +1,169 @@
+package wos
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Keys of the structured fields carried by the log messages of WosClient.
+const (
+	LogFieldOperation       = "operation"
+	LogFieldBucket          = "bucket"
+	LogFieldKey             = "key"
+	LogFieldRequestID       = "request_id"
+	LogFieldClientRequestID = "client_request_id"
+	LogFieldAttempt         = "attempt"
+	LogFieldLatency         = "latency"
+	LogFieldStatus          = "status"
+	LogFieldError           = "error"
+)
+
+// LogField is a key-value pair attached to a log message.
+type LogField struct {
+	Key   string
+	Value interface{}
+}
+
+// Logger defines interface with functions: Enabled, Log
+//
+// Enabled reports whether the messages of the level are logged, so that the fields are not built in vain.
+//
+// Log writes a message with the structured fields.
+type Logger interface {
+	Enabled(level Level) bool
+	Log(level Level, msg string, fields ...LogField)
+}
+
+// globalLogger is the Logger writing to the log files and the console set by InitLog.
+type globalLogger struct{}
+
+func (globalLogger) Enabled(level Level) bool {
+	return logEnabled() && logConf.level <= level
+}
+
+func (globalLogger) Log(level Level, msg string, fields ...LogField) {
+	if !logEnabled() || logConf.level > level {
+		return
+	}
+	// report the file and line of the first caller outside of this file
+	depth := 1
+	for {
+		if _, file, _, ok := runtime.Caller(depth); !ok || !strings.HasSuffix(file, "/logger.go") {
+			break
+		}
+		depth++
+	}
+	writeLog(depth+1, level, msg, fields)
+}
+
+// DefaultLogger returns the Logger writing to the log files and the console set by InitLog,
+// it is used by WosClient unless WithLogger is set.
+func DefaultLogger() Logger {
+	return globalLogger{}
+}
+
+func formatLogFields(msg string, fields []LogField) string {
+	if len(fields) == 0 {
+		return msg
+	}
+	var builder strings.Builder
+	builder.WriteString(msg)
+	for _, field := range fields {
+		builder.WriteString(" ")
+		builder.WriteString(field.Key)
+		builder.WriteString("=")
+		if value, ok := field.Value.(string); ok && strings.ContainsAny(value, " \"=") {
+			builder.WriteString(fmt.Sprintf("%q", value))
+		} else {
+			builder.WriteString(fmt.Sprintf("%v", field.Value))
+		}
+	}
+	return builder.String()
+}
+
+func (conf *config) getLogger() Logger {
+	if conf != nil && conf.logger != nil {
+		return conf.logger
+	}
+	return globalLogger{}
+}
+
+func (wosClient WosClient) getLogger() Logger {
+	return wosClient.conf.getLogger()
+}
+
+func (wosClient WosClient) logEnabled(level Level) bool {
+	return wosClient.getLogger().Enabled(level)
+}
+
+// log writes a message with the fields of the operation in progress followed by fields.
+func (wosClient WosClient) log(level Level, msg string, fields ...LogField) {
+	logger := wosClient.getLogger()
+	if !logger.Enabled(level) {
+		return
+	}
+	call := wosClient.call
+	_fields := make([]LogField, 0, len(fields)+4)
+	if call.action != "" {
+		_fields = append(_fields, LogField{Key: LogFieldOperation, Value: call.action})
+	}
+	if call.bucketName != "" {
+		_fields = append(_fields, LogField{Key: LogFieldBucket, Value: call.bucketName})
+	}
+	if call.objectKey != "" {
+		_fields = append(_fields, LogField{Key: LogFieldKey, Value: call.objectKey})
+	}
+	if call.clientRequestID != "" {
+		_fields = append(_fields, LogField{Key: LogFieldClientRequestID, Value: call.clientRequestID})
+	}
+	logger.Log(level, msg, append(_fields, fields...)...)
+}
+
+// logf writes a formatted message with the fields of the operation in progress.
+func (wosClient WosClient) logf(level Level, format string, v ...interface{}) {
+	if !wosClient.logEnabled(level) {
+		return
+	}
+	wosClient.log(level, fmt.Sprintf(format, v...))
+}
+
+// loggerf writes a formatted message to logger.
+func loggerf(logger Logger, level Level, format string, v ...interface{}) {
+	if logger.Enabled(level) {
+		logger.Log(level, fmt.Sprintf(format, v...))
+	}
+}
+
+// checkAndLogErr writes a formatted message with the fields of the operation in progress if err is not nil.
+func (wosClient WosClient) checkAndLogErr(err error, level Level, format string, v ...interface{}) {
+	if err != nil {
+		wosClient.logf(level, format, v...)
+	}
+}
+
+func latencyField(latency time.Duration) LogField {
+	return LogField{Key: LogFieldLatency, Value: latency}
+}
+
+// resultLogFields returns the status, the request id and the error of a response.
+func resultLogFields(resp *http.Response, err error) []LogField {
+	fields := make([]LogField, 0, 3)
+	if resp != nil {
+		fields = append(fields, LogField{Key: LogFieldStatus, Value: resp.StatusCode})
+		if requestID := getResponseRequestID(resp.Header); requestID != "" {
+			fields = append(fields, LogField{Key: LogFieldRequestID, Value: requestID})
+		}
+	} else if wosError, ok := err.(WosError); ok {
+		fields = append(fields, LogField{Key: LogFieldStatus, Value: wosError.StatusCode})
+		if wosError.RequestId != "" {
+			fields = append(fields, LogField{Key: LogFieldRequestID, Value: wosError.RequestId})
+		}
+	}
+	if err != nil {
+		fields = append(fields, LogField{Key: LogFieldError, Value: err})
+	}
+	return fields
+}
@@ -0,0 +1,46 @@
+//go:build go1.21
+// +build go1.21
+
+package wos
+
+import (
+	"context"
+	"log/slog"
+)
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger creates a Logger writing to the slog.Logger, the fields are written as slog attributes.
+func NewSlogLogger(logger *slog.Logger) Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return slogLogger{logger: logger}
+}
+
+func toSlogLevel(level Level) slog.Level {
+	switch {
+	case level <= LEVEL_DEBUG:
+		return slog.LevelDebug
+	case level <= LEVEL_INFO:
+		return slog.LevelInfo
+	case level <= LEVEL_WARN:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
+
+func (l slogLogger) Enabled(level Level) bool {
+	return l.logger.Enabled(context.Background(), toSlogLevel(level))
+}
+
+func (l slogLogger) Log(level Level, msg string, fields ...LogField) {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, field := range fields {
+		attrs = append(attrs, slog.Any(field.Key, field.Value))
+	}
+	l.logger.LogAttrs(context.Background(), toSlogLevel(level), msg, attrs...)
+}
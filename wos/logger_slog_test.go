@@ -0,0 +1,48 @@
+//go:build go1.21
+// +build go1.21
+
+package wos
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestSlogLogger(t *testing.T) {
+	cases := []struct {
+		name    string
+		level   Level
+		enabled bool
+		want    string
+	}{
+		{"debug", LEVEL_DEBUG, false, ""},
+		{"info", LEVEL_INFO, true, "INFO"},
+		{"warn", LEVEL_WARN, true, "WARN"},
+		{"error", LEVEL_ERROR, true, "ERROR"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			buffer := &bytes.Buffer{}
+			logger := NewSlogLogger(slog.New(slog.NewJSONHandler(buffer, &slog.HandlerOptions{Level: slog.LevelInfo})))
+			if enabled := logger.Enabled(c.level); enabled != c.enabled {
+				t.Errorf("Enabled = %t, want %t", enabled, c.enabled)
+			}
+			logger.Log(c.level, "message", LogField{Key: "bucket", Value: "bucket"}, LogField{Key: "attempt", Value: 2})
+			if c.want == "" {
+				if buffer.Len() != 0 {
+					t.Errorf("logged %s", buffer.String())
+				}
+				return
+			}
+			record := map[string]interface{}{}
+			if err := json.Unmarshal(buffer.Bytes(), &record); err != nil {
+				t.Fatal(err)
+			}
+			if record["level"] != c.want || record["msg"] != "message" || record["bucket"] != "bucket" || record["attempt"] != float64(2) {
+				t.Errorf("record %v", record)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package wos
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type logEntry struct {
+	level  Level
+	msg    string
+	fields map[string]interface{}
+}
+
+// captureLogger keeps the messages logged through it.
+type captureLogger struct {
+	lock    sync.Mutex
+	entries []logEntry
+}
+
+func (logger *captureLogger) Enabled(level Level) bool {
+	return true
+}
+
+func (logger *captureLogger) Log(level Level, msg string, fields ...LogField) {
+	entry := logEntry{level: level, msg: msg, fields: make(map[string]interface{}, len(fields))}
+	for _, field := range fields {
+		entry.fields[field.Key] = field.Value
+	}
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+	logger.entries = append(logger.entries, entry)
+}
+
+func (logger *captureLogger) find(substr string) *logEntry {
+	logger.lock.Lock()
+	defer logger.lock.Unlock()
+	for i := range logger.entries {
+		if strings.Contains(logger.entries[i].msg, substr) {
+			return &logger.entries[i]
+		}
+	}
+	return nil
+}
+
+func TestFormatLogFields(t *testing.T) {
+	cases := []struct {
+		fields []LogField
+		want   string
+	}{
+		{nil, "msg"},
+		{[]LogField{{Key: LogFieldAttempt, Value: 2}}, "msg attempt=2"},
+		{[]LogField{{Key: LogFieldKey, Value: "a b"}, {Key: LogFieldStatus, Value: 404}}, "msg key=\"a b\" status=404"},
+	}
+	for _, c := range cases {
+		if got := formatLogFields("msg", c.fields); got != c.want {
+			t.Errorf("formatLogFields = %q, want %q", got, c.want)
+		}
+	}
+}
+
+func TestClientLoggerCarriesCallFields(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("<Error><Code>NoSuchUpload</Code></Error>"))
+	}))
+	defer server.Close()
+
+	logger := &captureLogger{}
+	client := newTestClient(t, server.URL, WithLogger(logger))
+	_, err := client.CompleteMultipartUpload(&CompleteMultipartUploadInput{Bucket: "bucket", Key: "key", UploadId: "upload", Parts: []Part{{PartNumber: 1, ETag: "etag"}}},
+		WithClientRequestID("request-1"))
+	if err == nil {
+		t.Fatal("CompleteMultipartUpload succeeded")
+	}
+	entry := logger.find("Do http request with string")
+	if entry == nil {
+		t.Fatal("the request body is not logged through the client logger")
+	}
+	if entry.fields[LogFieldOperation] != "CompleteMultipartUpload" || entry.fields[LogFieldClientRequestID] != "request-1" {
+		t.Errorf("fields = %v", entry.fields)
+	}
+}
+
+func TestClientLoggerResumableTransfer(t *testing.T) {
+	dir := t.TempDir()
+	checkpointFile := filepath.Join(dir, "checkpoint")
+	if err := os.Mkdir(checkpointFile, 0755); err != nil {
+		t.Fatal(err)
+	}
+	uploadFile := filepath.Join(dir, "upload")
+	if err := os.WriteFile(uploadFile, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := &captureLogger{}
+	client := newTestClient(t, "http://127.0.0.1:1", WithLogger(logger))
+	input := &UploadFileInput{UploadFile: uploadFile, EnableCheckpoint: true, CheckpointFile: checkpointFile}
+	input.Bucket, input.Key = "bucket", "key"
+	if _, err := client.UploadFile(input); err == nil {
+		t.Fatal("UploadFile succeeded with a folder as the checkpoint file")
+	}
+	if entry := logger.find("Checkpoint file can not be a folder"); entry == nil || entry.level != LEVEL_ERROR {
+		t.Errorf("the checkpoint error is not logged through the client logger: %v", entry)
+	}
+}
@@ -0,0 +1,433 @@
+package wos
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OperationMetrics describes a finished operation of WosClient, such as PutObject.
+type OperationMetrics struct {
+	Action string
+	Method string
+	// StatusCode is the HTTP status code of the last response, 0 if no response was received.
+	StatusCode int
+	Err        error
+	Latency    time.Duration
+}
+
+// AttemptMetrics describes a single HTTP attempt of an operation.
+type AttemptMetrics struct {
+	Action string
+	Method string
+	// Attempt is the number of the attempt, starting from 1, redirects included.
+	Attempt int
+	// Retry reports whether the attempt is a retry of a failed one.
+	Retry bool
+	// StatusCode is the HTTP status code of the response, 0 if no response was received.
+	StatusCode int
+	Err        error
+	Latency    time.Duration
+	// GotConn reports whether a connection was obtained, ConnReused whether it came from the idle pool.
+	GotConn    bool
+	ConnReused bool
+}
+
+// MetricsCollector defines interface with functions: ObserveOperation, ObserveAttempt, ObserveBytes, ObservePool
+//
+// ObserveOperation is called once after each operation, ObserveAttempt after each HTTP attempt.
+//
+// ObserveBytes is called when a request body has been sent or a response body is closed.
+//
+// ObservePool is called with running true when the RoutinePool used by UploadFile and DownloadFile starts,
+// and with running false after it is shut down. The workers and the working tasks of the pool can be read
+// with GetWorkerCnt and GetCurrentWorkingCnt while it is running.
+//
+// The functions are called concurrently and must not block.
+type MetricsCollector interface {
+	ObserveOperation(m *OperationMetrics)
+	ObserveAttempt(m *AttemptMetrics)
+	ObserveBytes(action string, sent, received int64)
+	ObservePool(name string, pool Pool, running bool)
+}
+
+// DefaultLatencyBuckets are the upper bounds of the latency histogram buckets, in seconds.
+var DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// LatencyHistogram is a snapshot of a latency histogram, Counts[i] is the number of the observations
+// not greater than Buckets[i] seconds, and Count includes the observations greater than all buckets.
+type LatencyHistogram struct {
+	Buckets []float64
+	Counts  []uint64
+	Count   uint64
+	Sum     time.Duration
+}
+
+// MetricsSnapshot is a copy of the metrics held by InMemoryMetricsCollector.
+// The status code 0 counts the operations or attempts which received no response.
+type MetricsSnapshot struct {
+	Operations        map[string]map[int]uint64
+	Attempts          map[string]map[int]uint64
+	Retries           map[string]uint64
+	Latencies         map[string]LatencyHistogram
+	BytesSent         map[string]int64
+	BytesReceived     map[string]int64
+	NewConnections    uint64
+	ReusedConnections uint64
+	PoolWorkers       map[string]int64
+	PoolWorking       map[string]int64
+}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    time.Duration
+}
+
+// InMemoryMetricsCollector is a MetricsCollector keeping the metrics in memory,
+// which can be exported in the Prometheus text format with WritePrometheus.
+type InMemoryMetricsCollector struct {
+	lock              sync.Mutex
+	buckets           []float64
+	operations        map[string]map[int]uint64
+	attempts          map[string]map[int]uint64
+	retries           map[string]uint64
+	latencies         map[string]*histogram
+	bytesSent         map[string]int64
+	bytesReceived     map[string]int64
+	newConnections    uint64
+	reusedConnections uint64
+	// pools holds the running pools by name, the name is kept after its pools are shut down
+	pools map[string]map[Pool]bool
+}
+
+// NewInMemoryMetricsCollector creates an InMemoryMetricsCollector instance, DefaultLatencyBuckets is used
+// if buckets is empty
+func NewInMemoryMetricsCollector(buckets ...float64) *InMemoryMetricsCollector {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	_buckets := make([]float64, len(buckets))
+	copy(_buckets, buckets)
+	sort.Float64s(_buckets)
+	return &InMemoryMetricsCollector{
+		buckets:       _buckets,
+		operations:    make(map[string]map[int]uint64),
+		attempts:      make(map[string]map[int]uint64),
+		retries:       make(map[string]uint64),
+		latencies:     make(map[string]*histogram),
+		bytesSent:     make(map[string]int64),
+		bytesReceived: make(map[string]int64),
+		pools:         make(map[string]map[Pool]bool),
+	}
+}
+
+func addStatusCount(counts map[string]map[int]uint64, action string, statusCode int) {
+	statusCounts, ok := counts[action]
+	if !ok {
+		statusCounts = make(map[int]uint64)
+		counts[action] = statusCounts
+	}
+	statusCounts[statusCode]++
+}
+
+// ObserveOperation implements MetricsCollector
+func (collector *InMemoryMetricsCollector) ObserveOperation(m *OperationMetrics) {
+	collector.lock.Lock()
+	defer collector.lock.Unlock()
+	addStatusCount(collector.operations, m.Action, m.StatusCode)
+	h, ok := collector.latencies[m.Action]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(collector.buckets))}
+		collector.latencies[m.Action] = h
+	}
+	seconds := m.Latency.Seconds()
+	for i, bound := range collector.buckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += m.Latency
+}
+
+// ObserveAttempt implements MetricsCollector
+func (collector *InMemoryMetricsCollector) ObserveAttempt(m *AttemptMetrics) {
+	collector.lock.Lock()
+	defer collector.lock.Unlock()
+	addStatusCount(collector.attempts, m.Action, m.StatusCode)
+	if m.Retry {
+		collector.retries[m.Action]++
+	}
+	if m.GotConn {
+		if m.ConnReused {
+			collector.reusedConnections++
+		} else {
+			collector.newConnections++
+		}
+	}
+}
+
+// ObserveBytes implements MetricsCollector
+func (collector *InMemoryMetricsCollector) ObserveBytes(action string, sent, received int64) {
+	collector.lock.Lock()
+	defer collector.lock.Unlock()
+	collector.bytesSent[action] += sent
+	collector.bytesReceived[action] += received
+}
+
+// ObservePool implements MetricsCollector
+func (collector *InMemoryMetricsCollector) ObservePool(name string, pool Pool, running bool) {
+	collector.lock.Lock()
+	defer collector.lock.Unlock()
+	pools, ok := collector.pools[name]
+	if !ok {
+		pools = make(map[Pool]bool)
+		collector.pools[name] = pools
+	}
+	if running {
+		pools[pool] = true
+	} else {
+		delete(pools, pool)
+	}
+}
+
+func copyStatusCounts(counts map[string]map[int]uint64) map[string]map[int]uint64 {
+	ret := make(map[string]map[int]uint64, len(counts))
+	for action, statusCounts := range counts {
+		_statusCounts := make(map[int]uint64, len(statusCounts))
+		for statusCode, count := range statusCounts {
+			_statusCounts[statusCode] = count
+		}
+		ret[action] = _statusCounts
+	}
+	return ret
+}
+
+func copyInt64Map(values map[string]int64) map[string]int64 {
+	ret := make(map[string]int64, len(values))
+	for key, value := range values {
+		ret[key] = value
+	}
+	return ret
+}
+
+// Snapshot returns a copy of the metrics
+func (collector *InMemoryMetricsCollector) Snapshot() *MetricsSnapshot {
+	collector.lock.Lock()
+	defer collector.lock.Unlock()
+	snapshot := &MetricsSnapshot{
+		Operations:        copyStatusCounts(collector.operations),
+		Attempts:          copyStatusCounts(collector.attempts),
+		Retries:           make(map[string]uint64, len(collector.retries)),
+		Latencies:         make(map[string]LatencyHistogram, len(collector.latencies)),
+		BytesSent:         copyInt64Map(collector.bytesSent),
+		BytesReceived:     copyInt64Map(collector.bytesReceived),
+		NewConnections:    collector.newConnections,
+		ReusedConnections: collector.reusedConnections,
+		PoolWorkers:       make(map[string]int64, len(collector.pools)),
+		PoolWorking:       make(map[string]int64, len(collector.pools)),
+	}
+	for name, pools := range collector.pools {
+		var workers, working int64
+		for pool := range pools {
+			workers += pool.GetWorkerCnt()
+			working += pool.GetCurrentWorkingCnt()
+		}
+		snapshot.PoolWorkers[name], snapshot.PoolWorking[name] = workers, working
+	}
+	for action, count := range collector.retries {
+		snapshot.Retries[action] = count
+	}
+	for action, h := range collector.latencies {
+		counts := make([]uint64, len(h.counts))
+		copy(counts, h.counts)
+		snapshot.Latencies[action] = LatencyHistogram{Buckets: collector.buckets, Counts: counts, Count: h.count, Sum: h.sum}
+	}
+	return snapshot
+}
+
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch _m := m.(type) {
+	case map[string]map[int]uint64:
+		for key := range _m {
+			keys = append(keys, key)
+		}
+	case map[string]uint64:
+		for key := range _m {
+			keys = append(keys, key)
+		}
+	case map[string]int64:
+		for key := range _m {
+			keys = append(keys, key)
+		}
+	case map[string]LatencyHistogram:
+		for key := range _m {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func writeStatusCounts(w *bufio.Writer, name, help string, counts map[string]map[int]uint64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+	for _, action := range sortedKeys(counts) {
+		statusCodes := make([]int, 0, len(counts[action]))
+		for statusCode := range counts[action] {
+			statusCodes = append(statusCodes, statusCode)
+		}
+		sort.Ints(statusCodes)
+		for _, statusCode := range statusCodes {
+			status := "error"
+			if statusCode > 0 {
+				status = strconv.Itoa(statusCode)
+			}
+			fmt.Fprintf(w, "%s{action=\"%s\",status=\"%s\"} %d\n", name, escapeLabelValue(action), status, counts[action][statusCode])
+		}
+	}
+}
+
+func writeLabeledValues(w *bufio.Writer, name, help, metricType, label string, values map[string]int64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+	for _, key := range sortedKeys(values) {
+		fmt.Fprintf(w, "%s{%s=\"%s\"} %d\n", name, label, escapeLabelValue(key), values[key])
+	}
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition format, it can be used in
+// an http.Handler serving the metrics endpoint.
+func (collector *InMemoryMetricsCollector) WritePrometheus(writer io.Writer) error {
+	snapshot := collector.Snapshot()
+	w := bufio.NewWriter(writer)
+
+	writeStatusCounts(w, "wos_client_operations_total", "Number of operations by action and final status.", snapshot.Operations)
+
+	name := "wos_client_operation_duration_seconds"
+	fmt.Fprintf(w, "# HELP %s Latency of operations by action, retries included.\n# TYPE %s histogram\n", name, name)
+	for _, action := range sortedKeys(snapshot.Latencies) {
+		h := snapshot.Latencies[action]
+		_action := escapeLabelValue(action)
+		for i, bound := range h.Buckets {
+			fmt.Fprintf(w, "%s_bucket{action=\"%s\",le=\"%s\"} %d\n", name, _action, strconv.FormatFloat(bound, 'g', -1, 64), h.Counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket{action=\"%s\",le=\"+Inf\"} %d\n", name, _action, h.Count)
+		fmt.Fprintf(w, "%s_sum{action=\"%s\"} %s\n", name, _action, strconv.FormatFloat(h.Sum.Seconds(), 'g', -1, 64))
+		fmt.Fprintf(w, "%s_count{action=\"%s\"} %d\n", name, _action, h.Count)
+	}
+
+	writeStatusCounts(w, "wos_client_attempts_total", "Number of HTTP attempts by action and status.", snapshot.Attempts)
+
+	retries := make(map[string]int64, len(snapshot.Retries))
+	for action, count := range snapshot.Retries {
+		retries[action] = int64(count)
+	}
+	writeLabeledValues(w, "wos_client_retries_total", "Number of retried attempts by action.", "counter", "action", retries)
+	writeLabeledValues(w, "wos_client_sent_bytes_total", "Number of request body bytes sent by action.", "counter", "action", snapshot.BytesSent)
+	writeLabeledValues(w, "wos_client_received_bytes_total", "Number of response body bytes received by action.", "counter", "action", snapshot.BytesReceived)
+
+	name = "wos_client_connections_total"
+	fmt.Fprintf(w, "# HELP %s Number of connections used by attempts, by whether they were reused.\n# TYPE %s counter\n", name, name)
+	fmt.Fprintf(w, "%s{reused=\"false\"} %d\n%s{reused=\"true\"} %d\n", name, snapshot.NewConnections, name, snapshot.ReusedConnections)
+
+	writeLabeledValues(w, "wos_client_pool_workers", "Number of workers of the running routine pools.", "gauge", "pool", snapshot.PoolWorkers)
+	writeLabeledValues(w, "wos_client_pool_working_tasks", "Number of tasks running in the routine pools.", "gauge", "pool", snapshot.PoolWorking)
+	return w.Flush()
+}
+
+type countingReadCloser struct {
+	io.ReadCloser
+	count    int64
+	reported int32
+	report   func(count int64)
+}
+
+func (c *countingReadCloser) Read(p []byte) (n int, err error) {
+	n, err = c.ReadCloser.Read(p)
+	atomic.AddInt64(&c.count, int64(n))
+	return
+}
+
+func (c *countingReadCloser) Close() error {
+	if atomic.CompareAndSwapInt32(&c.reported, 0, 1) {
+		c.report(atomic.LoadInt64(&c.count))
+	}
+	return c.ReadCloser.Close()
+}
+
+// sendRequestWithMetrics sends a single attempt through the middlewares, and reports it to the metrics collector.
+func (wosClient WosClient) sendRequestWithMetrics(req *http.Request, attempt int, retry bool) (*http.Response, error) {
+	metrics := wosClient.conf.metrics
+	if metrics == nil {
+		resp, err := wosClient.httpClient.Do(req)
+		return wosClient.afterAttempt(req, resp, err)
+	}
+
+	action := wosClient.call.action
+	if req.Body != nil && req.Body != http.NoBody {
+		req.Body = &countingReadCloser{ReadCloser: req.Body, report: func(count int64) {
+			metrics.ObserveBytes(action, count, 0)
+		}}
+	}
+	m := &AttemptMetrics{Action: action, Method: req.Method, Attempt: attempt, Retry: retry}
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			m.GotConn = true
+			m.ConnReused = info.Reused
+		},
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	start := time.Now()
+	resp, err := wosClient.httpClient.Do(req)
+	resp, err = wosClient.afterAttempt(req, resp, err)
+	m.Latency = time.Since(start)
+	m.Err = err
+	if resp != nil {
+		m.StatusCode = resp.StatusCode
+		resp.Body = &countingReadCloser{ReadCloser: resp.Body, report: func(count int64) {
+			metrics.ObserveBytes(action, 0, count)
+		}}
+	}
+	metrics.ObserveAttempt(m)
+	return resp, err
+}
+
+func (wosClient WosClient) observeOperation(method string, resp *http.Response, err error, latency time.Duration) {
+	metrics := wosClient.conf.metrics
+	if metrics == nil {
+		return
+	}
+	m := &OperationMetrics{Action: wosClient.call.action, Method: method, Err: err, Latency: latency}
+	if resp != nil {
+		m.StatusCode = resp.StatusCode
+	} else if wosError, ok := err.(WosError); ok {
+		m.StatusCode = wosError.StatusCode
+	}
+	metrics.ObserveOperation(m)
+}
+
+// observePool reports the RoutinePool named name as running, the returned function reports it as shut down.
+func (wosClient WosClient) observePool(name string, pool Pool) func() {
+	metrics := wosClient.conf.metrics
+	if metrics == nil {
+		return func() {}
+	}
+	metrics.ObservePool(name, pool, true)
+	return func() {
+		metrics.ObservePool(name, pool, false)
+	}
+}
@@ -0,0 +1,221 @@
+package wos
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestInMemoryMetricsCollector(t *testing.T) {
+	cases := []struct {
+		name    string
+		observe func(collector *InMemoryMetricsCollector)
+		check   func(t *testing.T, snapshot *MetricsSnapshot)
+	}{
+		{
+			name: "operations",
+			observe: func(collector *InMemoryMetricsCollector) {
+				collector.ObserveOperation(&OperationMetrics{Action: "GetObject", StatusCode: 200, Latency: 50 * time.Millisecond})
+				collector.ObserveOperation(&OperationMetrics{Action: "GetObject", StatusCode: 404, Latency: 2 * time.Second})
+				collector.ObserveOperation(&OperationMetrics{Action: "GetObject", Err: errors.New("reset"), Latency: 5 * time.Second})
+			},
+			check: func(t *testing.T, snapshot *MetricsSnapshot) {
+				if want := map[int]uint64{0: 1, 200: 1, 404: 1}; !reflect.DeepEqual(snapshot.Operations["GetObject"], want) {
+					t.Errorf("Operations = %v, want %v", snapshot.Operations["GetObject"], want)
+				}
+				h := snapshot.Latencies["GetObject"]
+				if want := []uint64{1, 1, 2}; !reflect.DeepEqual(h.Counts, want) || h.Count != 3 {
+					t.Errorf("latency counts = %v/%d, want %v/3", h.Counts, h.Count, want)
+				}
+				if h.Sum != 7050*time.Millisecond {
+					t.Errorf("latency sum = %v", h.Sum)
+				}
+			},
+		},
+		{
+			name: "attempts",
+			observe: func(collector *InMemoryMetricsCollector) {
+				collector.ObserveAttempt(&AttemptMetrics{Action: "PutObject", Attempt: 1, StatusCode: 503, GotConn: true})
+				collector.ObserveAttempt(&AttemptMetrics{Action: "PutObject", Attempt: 2, Retry: true, StatusCode: 200, GotConn: true, ConnReused: true})
+				collector.ObserveAttempt(&AttemptMetrics{Action: "PutObject", Attempt: 3, Retry: true, Err: errors.New("dial")})
+			},
+			check: func(t *testing.T, snapshot *MetricsSnapshot) {
+				if want := map[int]uint64{0: 1, 200: 1, 503: 1}; !reflect.DeepEqual(snapshot.Attempts["PutObject"], want) {
+					t.Errorf("Attempts = %v, want %v", snapshot.Attempts["PutObject"], want)
+				}
+				if snapshot.Retries["PutObject"] != 2 || snapshot.NewConnections != 1 || snapshot.ReusedConnections != 1 {
+					t.Errorf("retries = %d, connections = %d/%d", snapshot.Retries["PutObject"], snapshot.NewConnections, snapshot.ReusedConnections)
+				}
+			},
+		},
+		{
+			name: "bytes",
+			observe: func(collector *InMemoryMetricsCollector) {
+				collector.ObserveBytes("PutObject", 10, 0)
+				collector.ObserveBytes("PutObject", 5, 3)
+			},
+			check: func(t *testing.T, snapshot *MetricsSnapshot) {
+				if snapshot.BytesSent["PutObject"] != 15 || snapshot.BytesReceived["PutObject"] != 3 {
+					t.Errorf("bytes = %d/%d, want 15/3", snapshot.BytesSent["PutObject"], snapshot.BytesReceived["PutObject"])
+				}
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			collector := NewInMemoryMetricsCollector(1, 0.1, 2)
+			c.observe(collector)
+			snapshot := collector.Snapshot()
+			c.check(t, snapshot)
+
+			// the snapshot is a copy
+			collector.ObserveOperation(&OperationMetrics{Action: "GetObject", StatusCode: 200})
+			collector.ObserveBytes("PutObject", 1, 1)
+			c.check(t, snapshot)
+		})
+	}
+}
+
+func TestInMemoryMetricsCollectorPools(t *testing.T) {
+	collector := NewInMemoryMetricsCollector()
+	pool := NewRoutinePool(2, 2)
+	collector.ObservePool("upload", pool, true)
+	release := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		pool.ExecuteFunc(func() interface{} {
+			<-release
+			return nil
+		})
+	}
+	deadline := time.Now().Add(time.Second)
+	for pool.GetCurrentWorkingCnt() != 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	// the gauges are read from the pool
+	if snapshot := collector.Snapshot(); snapshot.PoolWorkers["upload"] != 2 || snapshot.PoolWorking["upload"] != 2 {
+		t.Errorf("pool = %d/%d, want 2/2", snapshot.PoolWorkers["upload"], snapshot.PoolWorking["upload"])
+	}
+	close(release)
+	pool.ShutDown()
+	collector.ObservePool("upload", pool, false)
+	if snapshot := collector.Snapshot(); len(snapshot.PoolWorkers) != 1 || snapshot.PoolWorkers["upload"] != 0 || snapshot.PoolWorking["upload"] != 0 {
+		t.Errorf("pool after shut down = %v/%v, want 0/0", snapshot.PoolWorkers, snapshot.PoolWorking)
+	}
+}
+
+func TestWritePrometheus(t *testing.T) {
+	collector := NewInMemoryMetricsCollector(0.1, 1)
+	collector.ObserveOperation(&OperationMetrics{Action: `Get"Object`, StatusCode: 200, Latency: 500 * time.Millisecond})
+	collector.ObserveOperation(&OperationMetrics{Action: `Get"Object`, Err: errors.New("reset"), Latency: 2 * time.Second})
+	collector.ObserveAttempt(&AttemptMetrics{Action: `Get"Object`, StatusCode: 200, Retry: true, GotConn: true})
+	collector.ObserveBytes(`Get"Object`, 0, 42)
+	pool := NewRoutinePool(1, 1)
+	defer pool.ShutDown()
+	collector.ObservePool("download", pool, true)
+
+	buffer := &bytes.Buffer{}
+	if err := collector.WritePrometheus(buffer); err != nil {
+		t.Fatal(err)
+	}
+	want := `# HELP wos_client_operations_total Number of operations by action and final status.
+# TYPE wos_client_operations_total counter
+wos_client_operations_total{action="Get\"Object",status="error"} 1
+wos_client_operations_total{action="Get\"Object",status="200"} 1
+# HELP wos_client_operation_duration_seconds Latency of operations by action, retries included.
+# TYPE wos_client_operation_duration_seconds histogram
+wos_client_operation_duration_seconds_bucket{action="Get\"Object",le="0.1"} 0
+wos_client_operation_duration_seconds_bucket{action="Get\"Object",le="1"} 1
+wos_client_operation_duration_seconds_bucket{action="Get\"Object",le="+Inf"} 2
+wos_client_operation_duration_seconds_sum{action="Get\"Object"} 2.5
+wos_client_operation_duration_seconds_count{action="Get\"Object"} 2
+# HELP wos_client_attempts_total Number of HTTP attempts by action and status.
+# TYPE wos_client_attempts_total counter
+wos_client_attempts_total{action="Get\"Object",status="200"} 1
+# HELP wos_client_retries_total Number of retried attempts by action.
+# TYPE wos_client_retries_total counter
+wos_client_retries_total{action="Get\"Object"} 1
+# HELP wos_client_sent_bytes_total Number of request body bytes sent by action.
+# TYPE wos_client_sent_bytes_total counter
+wos_client_sent_bytes_total{action="Get\"Object"} 0
+# HELP wos_client_received_bytes_total Number of response body bytes received by action.
+# TYPE wos_client_received_bytes_total counter
+wos_client_received_bytes_total{action="Get\"Object"} 42
+# HELP wos_client_connections_total Number of connections used by attempts, by whether they were reused.
+# TYPE wos_client_connections_total counter
+wos_client_connections_total{reused="false"} 1
+wos_client_connections_total{reused="true"} 0
+# HELP wos_client_pool_workers Number of workers of the running routine pools.
+# TYPE wos_client_pool_workers gauge
+wos_client_pool_workers{pool="download"} 0
+# HELP wos_client_pool_working_tasks Number of tasks running in the routine pools.
+# TYPE wos_client_pool_working_tasks gauge
+wos_client_pool_working_tasks{pool="download"} 0
+`
+	if got := buffer.String(); got != want {
+		t.Errorf("WritePrometheus =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestClientMetrics(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+	collector := NewInMemoryMetricsCollector()
+	client := newTestClient(t, server.URL, WithMetrics(collector))
+
+	output, err := client.GetObject(&GetObjectInput{GetObjectMetadataInput: GetObjectMetadataInput{Bucket: "bucket", Key: "key"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.ReadAll(output.Body)
+	output.Body.Close()
+
+	snapshot, action := collector.Snapshot(), "GetObject"
+	if want := map[int]uint64{200: 1}; !reflect.DeepEqual(snapshot.Operations[action], want) {
+		t.Errorf("Operations = %v, want %v", snapshot.Operations[action], want)
+	}
+	if want := map[int]uint64{200: 1, 503: 1}; !reflect.DeepEqual(snapshot.Attempts[action], want) {
+		t.Errorf("Attempts = %v, want %v", snapshot.Attempts[action], want)
+	}
+	if snapshot.Retries[action] != 1 || snapshot.BytesReceived[action] < 5 {
+		t.Errorf("retries = %d, received bytes = %d", snapshot.Retries[action], snapshot.BytesReceived[action])
+	}
+}
+
+func TestUploadFileMetricsPool(t *testing.T) {
+	var parts int32
+	server := newMultipartServer(t, 50*time.Millisecond, &parts)
+	collector := NewInMemoryMetricsCollector()
+	client := newTestClient(t, server.URL, WithMetrics(collector))
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.UploadFile(newUploadFileInput(t, 2*MIN_PART_SIZE))
+		done <- err
+	}()
+	deadline := time.Now().Add(time.Second)
+	for collector.Snapshot().PoolWorking["UploadFile"] != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("the working task of the pool is not reported")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if snapshot := collector.Snapshot(); snapshot.PoolWorkers["UploadFile"] != 0 || snapshot.PoolWorking["UploadFile"] != 0 {
+		t.Errorf("pool after UploadFile = %d/%d, want 0/0", snapshot.PoolWorkers["UploadFile"], snapshot.PoolWorking["UploadFile"])
+	}
+}
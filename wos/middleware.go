@@ -0,0 +1,122 @@
+package wos
+
+import (
+	"errors"
+	"net/http"
+)
+
+// MiddlewareRequest describes a request of WosClient before it is signed.
+// Changes to Params and Headers are signed and sent with the request.
+type MiddlewareRequest struct {
+	// Action is the name of the operation, such as PutObject.
+	Action     string
+	Method     string
+	BucketName string
+	ObjectKey  string
+	Params     map[string]string
+	Headers    map[string][]string
+	// Attempt is the number of the attempt about to be sent, starting from 1.
+	Attempt int
+}
+
+// Middleware defines interface with functions: BeforeSign, AfterSign, AfterAttempt
+//
+// BeforeSign is called before each attempt is signed, returning an error aborts the call.
+// It is not called for the requests with a signed url, which are signed already.
+//
+// AfterSign is called with the signed request before it is sent, returning an error aborts the call.
+// Changing the signed parts of the request makes the signature invalid.
+//
+// AfterAttempt is called after each attempt with the response or the transport error, and returns
+// the response and the error that WosClient handles, so that it can replace them. A middleware replacing
+// a response must close the body of the original one. If both a response and an error are returned at last,
+// the body of the response is closed and the error is handled.
+type Middleware interface {
+	BeforeSign(req *MiddlewareRequest) error
+	AfterSign(req *http.Request) error
+	AfterAttempt(req *http.Request, resp *http.Response, err error) (*http.Response, error)
+}
+
+// MiddlewareFuncs implements Middleware with functions, a nil function is skipped.
+type MiddlewareFuncs struct {
+	BeforeSignFunc   func(req *MiddlewareRequest) error
+	AfterSignFunc    func(req *http.Request) error
+	AfterAttemptFunc func(req *http.Request, resp *http.Response, err error) (*http.Response, error)
+}
+
+// BeforeSign implements Middleware
+func (funcs MiddlewareFuncs) BeforeSign(req *MiddlewareRequest) error {
+	if funcs.BeforeSignFunc == nil {
+		return nil
+	}
+	return funcs.BeforeSignFunc(req)
+}
+
+// AfterSign implements Middleware
+func (funcs MiddlewareFuncs) AfterSign(req *http.Request) error {
+	if funcs.AfterSignFunc == nil {
+		return nil
+	}
+	return funcs.AfterSignFunc(req)
+}
+
+// AfterAttempt implements Middleware
+func (funcs MiddlewareFuncs) AfterAttempt(req *http.Request, resp *http.Response, err error) (*http.Response, error) {
+	if funcs.AfterAttemptFunc == nil {
+		return resp, err
+	}
+	return funcs.AfterAttemptFunc(req, resp, err)
+}
+
+func (wosClient WosClient) beforeSign(method, bucketName, objectKey string, params map[string]string,
+	headers map[string][]string, attempt int) error {
+	if len(wosClient.conf.middlewares) == 0 {
+		return nil
+	}
+	req := &MiddlewareRequest{
+		Action:     wosClient.call.action,
+		Method:     method,
+		BucketName: bucketName,
+		ObjectKey:  objectKey,
+		Params:     params,
+		Headers:    headers,
+		Attempt:    attempt,
+	}
+	for _, middleware := range wosClient.conf.middlewares {
+		if err := middleware.BeforeSign(req); err != nil {
+			wosClient.logf(LEVEL_WARN, "Request is aborted by middleware before signing with reason: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (wosClient WosClient) afterSign(req *http.Request) error {
+	for _, middleware := range wosClient.conf.middlewares {
+		if err := middleware.AfterSign(req); err != nil {
+			wosClient.logf(LEVEL_WARN, "Request is aborted by middleware after signing with reason: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// afterAttempt calls the middlewares in reverse order, so that the first middleware sees the final result.
+func (wosClient WosClient) afterAttempt(req *http.Request, resp *http.Response, err error) (*http.Response, error) {
+	middlewares := wosClient.conf.middlewares
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		resp, err = middlewares[i].AfterAttempt(req, resp, err)
+	}
+	if err == nil && resp == nil {
+		return nil, errors.New("Middleware returns neither response nor error")
+	}
+	if err != nil && resp != nil {
+		if resp.Body != nil {
+			if _err := resp.Body.Close(); _err != nil {
+				wosClient.logf(LEVEL_WARN, "Failed to close resp body with reason: %v", _err)
+			}
+		}
+		return nil, err
+	}
+	return resp, err
+}
@@ -0,0 +1,91 @@
+package wos
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+type closeTrackingBody struct {
+	io.Reader
+	closed int32
+}
+
+func (body *closeTrackingBody) Close() error {
+	atomic.StoreInt32(&body.closed, 1)
+	return nil
+}
+
+func TestMiddlewareChain(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Tenant") != "t1" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("ETag", "\"etag\"")
+	}))
+	defer server.Close()
+
+	var order []string
+	tenant := MiddlewareFuncs{
+		BeforeSignFunc: func(req *MiddlewareRequest) error {
+			if req.Method == HTTP_DELETE {
+				return errors.New("read only")
+			}
+			req.Headers["X-Tenant"] = []string{"t1"}
+			order = append(order, "before-1")
+			return nil
+		},
+		AfterAttemptFunc: func(req *http.Request, resp *http.Response, err error) (*http.Response, error) {
+			order = append(order, "after-1")
+			return resp, err
+		},
+	}
+	second := MiddlewareFuncs{
+		AfterSignFunc: func(req *http.Request) error {
+			order = append(order, "sign-2")
+			return nil
+		},
+		AfterAttemptFunc: func(req *http.Request, resp *http.Response, err error) (*http.Response, error) {
+			order = append(order, "after-2")
+			return resp, err
+		},
+	}
+	client := newTestClient(t, server.URL, WithMiddleware(tenant, second))
+
+	if _, err := client.PutObject(&PutObjectInput{PutObjectBasicInput: PutObjectBasicInput{ObjectOperationInput: ObjectOperationInput{Bucket: "bucket", Key: "key"}}, Body: strings.NewReader("data")}); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(order, ","); got != "before-1,sign-2,after-2,after-1" {
+		t.Errorf("order = %s", got)
+	}
+	if _, err := client.DeleteObject(&DeleteObjectInput{Bucket: "bucket", Key: "key"}); err == nil || err.Error() != "read only" {
+		t.Errorf("DeleteObject error = %v, want read only", err)
+	}
+}
+
+func TestMiddlewareAfterAttemptResponseAndError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	body := &closeTrackingBody{Reader: strings.NewReader("replaced")}
+	reject := MiddlewareFuncs{AfterAttemptFunc: func(req *http.Request, resp *http.Response, err error) (*http.Response, error) {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: body}, errors.New("rejected")
+	}}
+	client := newTestClient(t, server.URL, WithMiddleware(reject), WithMaxRetryCount(0))
+
+	_, err := client.GetObjectMetadata(&GetObjectMetadataInput{Bucket: "bucket", Key: "key"})
+	if err == nil || err.Error() != "rejected" {
+		t.Fatalf("GetObjectMetadata error = %v, want rejected", err)
+	}
+	if atomic.LoadInt32(&body.closed) != 1 {
+		t.Error("the body of the response returned with an error is not closed")
+	}
+}
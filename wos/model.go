@@ -11,7 +11,9 @@ import (
 type BaseModel struct {
 	StatusCode      int                 `xml:"-"`
 	RequestId       string              `xml:"RequestId" json:"request_id"`
+	ClientRequestId string              `xml:"-" json:"client_request_id,omitempty"`
 	ResponseHeaders map[string][]string `xml:"-"`
+	Diagnostics     *Diagnostics        `xml:"-" json:"-"`
 }
 
 // Bucket defines bucket properties
@@ -773,6 +775,29 @@ type CreateSignedUrlOutput struct {
 	ActualSignedRequestHeaders http.Header
 }
 
+// SignedUrlInfo is the result of ParseSignedUrl function.
+type SignedUrlInfo struct {
+	Signature SignatureType
+	Algorithm string
+	AccessKey string
+	Bucket    string
+	Key       string
+	// SubResources are the query parameters of the url except the ones of the signature and the security token.
+	SubResources map[string]string
+	// HasSecurityToken reports whether the url carries the security token of temporary credentials.
+	HasSecurityToken bool
+	// MethodHints are the http methods the url may be signed for, the method is signed but not carried by the url.
+	MethodHints []HttpMethodType
+	// SignedAt is the date of the V4 and WOS signatures, it is zero for the V2 signature.
+	SignedAt time.Time
+	Expires  time.Time
+	Region   string
+	// CredentialScope is the date/region/service/terminal of the V4 and WOS signatures.
+	CredentialScope string
+	// SignedHeaders are the headers signed by the V4 and WOS signatures, the V2 signature does not list them.
+	SignedHeaders []string
+}
+
 // CreateBrowserBasedSignatureInput is the input parameter of CreateBrowserBasedSignature function.
 type CreateBrowserBasedSignatureInput struct {
 	Bucket     string
@@ -922,3 +947,13 @@ type JobResponse struct {
 	FileType         string `json:"file_type"`
 	IgnoreSameKey    bool   `json:"ignore_same_key"`
 }
+
+// RawRequest is the input parameter of Do function
+type RawRequest struct {
+	Method  string
+	Bucket  string
+	Key     string
+	Params  map[string]string
+	Headers map[string][]string
+	Body    io.Reader
+}
@@ -0,0 +1,222 @@
+package wos
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DEFAULT_MULTIPART_SESSION_EXPIRES is the seconds in which the signed URLs of a multipart upload session expire
+// by default.
+const DEFAULT_MULTIPART_SESSION_EXPIRES = 3600
+
+// CreateMultipartUploadSessionInput is the input parameter of CreateMultipartUploadSession function.
+type CreateMultipartUploadSessionInput struct {
+	InitiateMultipartUploadInput
+	// Size is the size of the object in bytes.
+	Size int64
+	// PartSize is the size of each part except the last one, it is enlarged if the object needs more than 10000 parts.
+	PartSize int64
+	// Expires is the seconds in which the signed URLs expire, 3600 by default.
+	Expires int
+}
+
+// MultipartUploadSessionPart defines the range and the signed UploadPart URL of a part.
+type MultipartUploadSessionPart struct {
+	PartNumber                 int
+	Offset                     int64
+	Size                       int64
+	SignedUrl                  string
+	ActualSignedRequestHeaders http.Header
+}
+
+// MultipartUploadSession is the result of CreateMultipartUploadSession function, it is handed to the client which
+// uploads the parts with the signed URLs.
+type MultipartUploadSession struct {
+	Bucket     string
+	Key        string
+	UploadId   string
+	Size       int64
+	PartSize   int64
+	Expiration time.Time
+	Parts      []MultipartUploadSessionPart
+	// Complete is the signed CompleteMultipartUpload URL, the client posts the XML of the parts to it.
+	Complete CreateSignedUrlOutput
+	// Abort is the signed AbortMultipartUpload URL.
+	Abort CreateSignedUrlOutput
+}
+
+// CreateMultipartUploadSession initiates a multipart upload and signs the URLs with which a browser or a mobile
+// client uploads the parts, completes and aborts the upload without the credentials.
+//
+// All the URLs are signed at the same time and expire together.
+func (wosClient WosClient) CreateMultipartUploadSession(input *CreateMultipartUploadSessionInput, extensions ...extensionOptions) (output *MultipartUploadSession, err error) {
+	if input == nil {
+		return nil, errors.New("CreateMultipartUploadSessionInput is nil")
+	}
+	if input.Size < 0 {
+		return nil, errors.New("Size is negative")
+	}
+	partSize, partCount, err := getSessionPartSize(input.Size, input.PartSize)
+	if err != nil {
+		return nil, err
+	}
+	expires := input.Expires
+	if expires <= 0 {
+		expires = DEFAULT_MULTIPART_SESSION_EXPIRES
+	}
+
+	initiateOutput, err := wosClient.InitiateMultipartUpload(&input.InitiateMultipartUploadInput, extensions...)
+	if err != nil {
+		return nil, err
+	}
+	bucket, key, uploadID := input.Bucket, input.Key, initiateOutput.UploadId
+
+	signer := wosClient
+	signer.call.signingTime = wosClient.now().Truncate(time.Second)
+	sign := func(method HttpMethodType, params map[string]string) (*CreateSignedUrlOutput, error) {
+		return signer.CreateSignedUrl(&CreateSignedUrlInput{Method: method, Bucket: bucket, Key: key, Expires: expires, QueryParams: params}, extensions...)
+	}
+
+	output = &MultipartUploadSession{
+		Bucket:     bucket,
+		Key:        key,
+		UploadId:   uploadID,
+		Size:       input.Size,
+		PartSize:   partSize,
+		Expiration: signer.call.signingTime.Add(time.Duration(expires) * time.Second),
+		Parts:      make([]MultipartUploadSessionPart, 0, partCount),
+	}
+	for i := 0; i < partCount; i++ {
+		part := MultipartUploadSessionPart{PartNumber: i + 1, Offset: int64(i) * partSize, Size: partSize}
+		if remaining := input.Size - part.Offset; remaining < partSize {
+			part.Size = remaining
+		}
+		signed, _err := sign(HttpMethodPut, map[string]string{"uploadId": uploadID, "partNumber": IntToString(part.PartNumber)})
+		if _err != nil {
+			return nil, wosClient.abortSession(bucket, key, uploadID, _err, extensions)
+		}
+		part.SignedUrl, part.ActualSignedRequestHeaders = signed.SignedUrl, signed.ActualSignedRequestHeaders
+		output.Parts = append(output.Parts, part)
+	}
+	complete, err := sign(HttpMethodPost, map[string]string{"uploadId": uploadID})
+	if err != nil {
+		return nil, wosClient.abortSession(bucket, key, uploadID, err, extensions)
+	}
+	abort, err := sign(HttpMethodDelete, map[string]string{"uploadId": uploadID})
+	if err != nil {
+		return nil, wosClient.abortSession(bucket, key, uploadID, err, extensions)
+	}
+	output.Complete, output.Abort = *complete, *abort
+	return
+}
+
+// getSessionPartSize returns the part size and the count of the parts of an object, an empty object has one part.
+func getSessionPartSize(size, partSize int64) (int64, int, error) {
+	if partSize <= 0 {
+		partSize = DEFAULT_PART_SIZE
+	} else if partSize < MIN_PART_SIZE {
+		partSize = MIN_PART_SIZE
+	}
+	if count := (size + partSize - 1) / partSize; count > MAX_PART_NUM {
+		partSize = (size + MAX_PART_NUM - 1) / MAX_PART_NUM
+	}
+	if partSize > MAX_PART_SIZE {
+		return 0, 0, fmt.Errorf("The size %d of the object is too large", size)
+	}
+	count := int((size + partSize - 1) / partSize)
+	if count == 0 {
+		count = 1
+	}
+	return partSize, count, nil
+}
+
+func (wosClient WosClient) abortSession(bucket, key, uploadID string, err error, extensions []extensionOptions) error {
+	_, abortErr := wosClient.AbortMultipartUpload(&AbortMultipartUploadInput{Bucket: bucket, Key: key, UploadId: uploadID}, extensions...)
+	if abortErr != nil {
+		wosClient.logf(LEVEL_WARN, "Failed to abort multipart upload %s with error: %v", uploadID, abortErr)
+	}
+	return err
+}
+
+// CompleteMultipartUploadSessionInput is the input parameter of CompleteMultipartUploadSession function.
+type CompleteMultipartUploadSessionInput struct {
+	Bucket   string
+	Key      string
+	UploadId string
+	// Parts are the part numbers and the ETags reported by the client.
+	Parts []Part
+	// PartCount and Size are checked against the uploaded parts if positive, such as the ones of the session.
+	PartCount int
+	Size      int64
+}
+
+// CompleteMultipartUploadSession validates the parts reported by the client of a multipart upload session against
+// the parts listed by ListParts and completes the upload.
+//
+// The parts must be numbered from 1 without gaps and their ETags must match the uploaded ones, the upload is not
+// completed otherwise and the caller may retry or abort it.
+func (wosClient WosClient) CompleteMultipartUploadSession(input *CompleteMultipartUploadSessionInput, extensions ...extensionOptions) (output *CompleteMultipartUploadOutput, err error) {
+	if input == nil {
+		return nil, errors.New("CompleteMultipartUploadSessionInput is nil")
+	}
+	if input.UploadId == "" {
+		return nil, errors.New("UploadId is empty")
+	}
+	if len(input.Parts) == 0 {
+		return nil, errors.New("Parts is empty")
+	}
+	if input.PartCount > 0 && len(input.Parts) != input.PartCount {
+		return nil, fmt.Errorf("%d parts are reported, %d are expected", len(input.Parts), input.PartCount)
+	}
+
+	uploaded := make(map[int]Part)
+	listInput := &ListPartsInput{Bucket: input.Bucket, Key: input.Key, UploadId: input.UploadId}
+	for {
+		listOutput, _err := wosClient.ListParts(listInput, extensions...)
+		if _err != nil {
+			return nil, _err
+		}
+		for _, part := range listOutput.Parts {
+			uploaded[part.PartNumber] = part
+		}
+		if !listOutput.IsTruncated {
+			break
+		}
+		// a marker which does not advance would list the same parts forever
+		if listOutput.NextPartNumberMarker <= listInput.PartNumberMarker {
+			return nil, fmt.Errorf("The next part number marker %d of the truncated parts does not advance the marker %d",
+				listOutput.NextPartNumberMarker, listInput.PartNumberMarker)
+		}
+		listInput.PartNumberMarker = listOutput.NextPartNumberMarker
+	}
+
+	reported := make(map[int]bool, len(input.Parts))
+	parts := make([]Part, 0, len(input.Parts))
+	var size int64
+	for _, part := range input.Parts {
+		if part.PartNumber < 1 || part.PartNumber > len(input.Parts) {
+			return nil, fmt.Errorf("Part %d is out of the range [1, %d]", part.PartNumber, len(input.Parts))
+		}
+		if reported[part.PartNumber] {
+			return nil, fmt.Errorf("Part %d is reported more than once", part.PartNumber)
+		}
+		reported[part.PartNumber] = true
+		uploadedPart, ok := uploaded[part.PartNumber]
+		if !ok {
+			return nil, fmt.Errorf("Part %d is not uploaded", part.PartNumber)
+		}
+		if !strings.EqualFold(strings.Trim(part.ETag, "\""), strings.Trim(uploadedPart.ETag, "\"")) {
+			return nil, fmt.Errorf("The ETag %s of part %d does not match the uploaded %s", part.ETag, part.PartNumber, uploadedPart.ETag)
+		}
+		size += uploadedPart.Size
+		parts = append(parts, Part{PartNumber: part.PartNumber, ETag: uploadedPart.ETag})
+	}
+	if input.Size > 0 && size != input.Size {
+		return nil, fmt.Errorf("The size %d of the uploaded parts does not match the expected %d", size, input.Size)
+	}
+
+	return wosClient.CompleteMultipartUpload(&CompleteMultipartUploadInput{Bucket: input.Bucket, Key: input.Key, UploadId: input.UploadId, Parts: parts}, extensions...)
+}
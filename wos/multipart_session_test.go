@@ -0,0 +1,101 @@
+package wos
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCreateMultipartUploadSessionKeepsInput(t *testing.T) {
+	var parts int32
+	server := newMultipartServer(t, 0, &parts)
+	client := newTestClient(t, server.URL)
+
+	input := &CreateMultipartUploadSessionInput{Size: MIN_PART_SIZE + 1, PartSize: MIN_PART_SIZE}
+	input.Bucket, input.Key = "bucket", "key"
+	before := time.Now()
+	session, err := client.CreateMultipartUploadSession(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if input.Expires != 0 {
+		t.Errorf("input Expires = %d, want 0", input.Expires)
+	}
+	if len(session.Parts) != 2 {
+		t.Fatalf("%d parts, want 2", len(session.Parts))
+	}
+	expiration := before.Truncate(time.Second).Add(DEFAULT_MULTIPART_SESSION_EXPIRES * time.Second)
+	if session.Expiration.Before(expiration) || session.Expiration.After(expiration.Add(time.Minute)) {
+		t.Errorf("Expiration = %v, want about %v", session.Expiration, expiration)
+	}
+}
+
+func TestCompleteMultipartUploadSessionMarker(t *testing.T) {
+	cases := []struct {
+		name string
+		// next returns the next marker of the page after the marker, 0 for the last page.
+		next    func(marker int) int
+		wantErr string
+	}{
+		{name: "advancing marker", next: func(marker int) int {
+			if marker < 2 {
+				return marker + 1
+			}
+			return 0
+		}},
+		{name: "unchanged marker", next: func(marker int) int { return 1 }, wantErr: "does not advance"},
+		{name: "empty marker", next: func(marker int) int { return -1 }, wantErr: "does not advance"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var lists int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				query := r.URL.Query()
+				switch r.Method {
+				case http.MethodGet:
+					if atomic.AddInt32(&lists, 1) > 10 {
+						w.WriteHeader(http.StatusInternalServerError)
+						return
+					}
+					marker := 0
+					fmt.Sscanf(query.Get("part-number-marker"), "%d", &marker)
+					body := fmt.Sprintf("<ListPartsResult><Part><PartNumber>%d</PartNumber><ETag>\"etag-%d\"</ETag><Size>1</Size></Part>",
+						marker+1, marker+1)
+					switch next := c.next(marker); {
+					case next > 0:
+						body += fmt.Sprintf("<IsTruncated>true</IsTruncated><NextPartNumberMarker>%d</NextPartNumberMarker>", next)
+					case next < 0:
+						body += "<IsTruncated>true</IsTruncated>"
+					}
+					w.Write([]byte(body + "</ListPartsResult>"))
+				case http.MethodPost:
+					io.Copy(ioutil.Discard, r.Body)
+					w.Write([]byte("<CompleteMultipartUploadResult><Bucket>bucket</Bucket><Key>key</Key><ETag>etag</ETag></CompleteMultipartUploadResult>"))
+				default:
+					w.WriteHeader(http.StatusNotImplemented)
+				}
+			}))
+			defer server.Close()
+			client := newTestClient(t, server.URL)
+
+			_, err := client.CompleteMultipartUploadSession(&CompleteMultipartUploadSessionInput{Bucket: "bucket", Key: "key", UploadId: "upload-id",
+				Parts: []Part{{PartNumber: 1, ETag: "etag-1"}, {PartNumber: 2, ETag: "etag-2"}, {PartNumber: 3, ETag: "etag-3"}}, Size: 3})
+			if c.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Fatalf("CompleteMultipartUploadSession error = %v, want %q", err, c.wantErr)
+			}
+			if got := atomic.LoadInt32(&lists); got > 3 {
+				t.Errorf("ListParts called %d times", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package wos
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+)
+
+// getPayloadHash returns the hex encoded SHA-256 of the body from its current position without consuming it,
+// an empty string is returned if the body can not be read again, such as a non-seekable stream.
+func getPayloadHash(data io.Reader) (string, error) {
+	if data == nil {
+		return EMPTY_CONTENT_SHA256, nil
+	}
+	seeker, reader := getPayloadReader(data)
+	if seeker == nil {
+		return "", nil
+	}
+	offset, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	if _, err = io.Copy(hash, reader); err != nil {
+		return "", err
+	}
+	if _, err = seeker.Seek(offset, io.SeekStart); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// getPayloadReader returns the seekable reader of the body and the reader of the remaining bytes to be sent,
+// the seekable reader is nil if the body is not seekable.
+func getPayloadReader(data io.Reader) (io.ReadSeeker, io.Reader) {
+	switch r := data.(type) {
+	case *fileReaderWrapper:
+		return r.readerWrapper.payloadReader()
+	case *readerWrapper:
+		return r.payloadReader()
+	case io.ReadSeeker:
+		return r, r
+	}
+	return nil, nil
+}
+
+// payloadReader returns the seekable reader wrapped and the reader of the remaining bytes to be sent.
+func (rw *readerWrapper) payloadReader() (io.ReadSeeker, io.Reader) {
+	seeker, ok := rw.reader.(io.ReadSeeker)
+	if !ok {
+		return nil, nil
+	}
+	if rw.totalCount >= 0 {
+		return seeker, io.LimitReader(seeker, rw.totalCount-rw.readedCount)
+	}
+	return seeker, seeker
+}
+
+// withPayloadSigning returns a copy of the client signing the SHA-256 of the body set by WithPayloadSigning,
+// a non-seekable body is encoded in the aws-chunked encoding if WithStreamingSigning is set, otherwise the
+// UNSIGNED-PAYLOAD is signed.
+func (wosClient WosClient) withPayloadSigning(data io.Reader, headers map[string][]string) (WosClient, io.Reader, error) {
+	conf := wosClient.conf
+	if conf.signature == SignatureV2 || !conf.payloadSigning && conf.streamingChunkSize <= 0 {
+		return wosClient, data, nil
+	}
+	if seeker, _ := getPayloadReader(data); data == nil || seeker != nil {
+		if conf.payloadSigning {
+			payloadHash, err := getPayloadHash(data)
+			if err != nil {
+				return wosClient, data, err
+			}
+			wosClient.call.payloadHash = payloadHash
+		}
+		return wosClient, data, nil
+	}
+	if conf.streamingChunkSize <= 0 {
+		wosClient.logf(LEVEL_DEBUG, "The body is not seekable, sign the request with %s", UNSIGNED_PAYLOAD)
+		return wosClient, data, nil
+	}
+	decodedLength := int64(-1)
+	if r, ok := data.(*readerWrapper); ok && r.totalCount >= 0 {
+		decodedLength = r.totalCount - r.readedCount
+	} else if value, ok := headers[HEADER_CONTENT_LENGTH_CAMEL]; ok && len(value) > 0 {
+		decodedLength = StringToInt64(value[0], -1)
+	}
+	signer := newStreamingSigner(conf.signature == SignatureWos, conf.streamingChunkSize, decodedLength)
+	signer.prepareHeaders(headers)
+	wosClient.call.streaming = signer
+	return wosClient, newChunkedReader(signer, data), nil
+}
@@ -0,0 +1,93 @@
+package wos
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGetPayloadHash(t *testing.T) {
+	sum := func(data string) string {
+		hash := sha256.Sum256([]byte(data))
+		return hex.EncodeToString(hash[:])
+	}
+	cases := []struct {
+		name string
+		data func() io.Reader
+		want string
+	}{
+		{"nil", func() io.Reader { return nil }, EMPTY_CONTENT_SHA256},
+		{"seekable", func() io.Reader { return strings.NewReader("hello") }, sum("hello")},
+		{"seeked", func() io.Reader {
+			reader := strings.NewReader("hello")
+			reader.Seek(2, io.SeekStart)
+			return reader
+		}, sum("llo")},
+		{"limited wrapper", func() io.Reader {
+			return &readerWrapper{reader: strings.NewReader("hello"), totalCount: 4}
+		}, sum("hell")},
+		{"non-seekable", func() io.Reader { return ioutil.NopCloser(strings.NewReader("hello")) }, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			data := c.data()
+			got, err := getPayloadHash(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != c.want {
+				t.Errorf("getPayloadHash = %s, want %s", got, c.want)
+			}
+			// the body is not consumed
+			if seeker, reader := getPayloadReader(data); seeker != nil {
+				if rest, _ := ioutil.ReadAll(reader); c.want != EMPTY_CONTENT_SHA256 && sum(string(rest)) != c.want {
+					t.Errorf("the body is consumed, %q is left", rest)
+				}
+			}
+		})
+	}
+}
+
+func TestPutObjectPayloadSigning(t *testing.T) {
+	cases := []struct {
+		name    string
+		body    io.Reader
+		payload string
+	}{
+		{"seekable", bytes.NewReader([]byte("hello")), "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
+		{"non-seekable", ioutil.NopCloser(strings.NewReader("hello")), UNSIGNED_PAYLOAD},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var payload string
+			var verifyErr error
+			verifier := NewVerifier(testCredentials, "")
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if _, verifyErr = verifier.Verify(r); verifyErr == nil {
+					// the body is verified at its end
+					_, verifyErr = io.Copy(ioutil.Discard, r.Body)
+				}
+				payload = r.Header.Get(HEADER_CONTENT_SHA256_AMZ)
+			}))
+			defer server.Close()
+			client := newTestClient(t, server.URL, WithSignature(SignatureV4), WithPayloadSigning(true))
+			input := &PutObjectInput{}
+			input.Bucket, input.Key, input.Body = "bucket", "key", c.body
+			if _, err := client.PutObject(input); err != nil {
+				t.Fatal(err)
+			}
+			if verifyErr != nil {
+				t.Fatalf("Verify error = %v", verifyErr)
+			}
+			if payload != c.payload {
+				t.Errorf("payload %s, want %s", payload, c.payload)
+			}
+		})
+	}
+}
@@ -268,15 +268,17 @@ func (pool *RoutinePool) checkStatus(t Task) error {
 
 func (pool *RoutinePool) dispatcher() {
 	pool.shutDownWg.Add(1)
+	// the queues are captured since ShutDown clears the fields of the pool while the dispatcher is exiting
+	dispatchQueue, taskQueue := pool.dispatchQueue, pool.taskQueue
 	go func() {
 		for {
-			task, ok := <-pool.dispatchQueue
+			task, ok := <-dispatchQueue
 			if !ok {
 				break
 			}
 
 			if task == closeQueue {
-				close(pool.taskQueue)
+				close(taskQueue)
 				pool.shutDownWg.Done()
 				continue
 			}
@@ -285,7 +287,7 @@ func (pool *RoutinePool) dispatcher() {
 				pool.addWorker()
 			}
 
-			pool.taskQueue <- task
+			taskQueue <- task
 		}
 	}()
 }
@@ -0,0 +1,45 @@
+package wos
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRoutinePoolShutDownWhileDispatching(t *testing.T) {
+	cases := []struct {
+		name     string
+		workers  int
+		cacheCnt int
+	}{
+		{"unbuffered", 2, 0},
+		{"buffered", 2, 4},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			pool := NewRoutinePool(c.workers, c.cacheCnt)
+			var done int32
+			futures := make([]Future, 0, 8)
+			for i := 0; i < 8; i++ {
+				future, err := pool.SubmitFunc(func() interface{} {
+					time.Sleep(time.Millisecond)
+					return atomic.AddInt32(&done, 1)
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				futures = append(futures, future)
+			}
+			pool.ShutDown()
+			for _, future := range futures {
+				future.Get()
+			}
+			if got := atomic.LoadInt32(&done); got != 8 {
+				t.Errorf("%d tasks are done, want 8", got)
+			}
+			if _, err := pool.SubmitFunc(func() interface{} { return nil }); err != ErrPoolShutDown {
+				t.Errorf("Submit after ShutDown error = %v, want %v", err, ErrPoolShutDown)
+			}
+		})
+	}
+}
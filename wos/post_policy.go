@@ -0,0 +1,558 @@
+package wos
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	PostPolicyConditionEq                 = "eq"
+	PostPolicyConditionStartsWith         = "starts-with"
+	PostPolicyConditionContentLengthRange = "content-length-range"
+
+	postFieldKey                   = "key"
+	postFieldBucket                = "bucket"
+	postFieldACL                   = "acl"
+	postFieldContentType           = "Content-Type"
+	postFieldSuccessActionStatus   = "success_action_status"
+	postFieldSuccessActionRedirect = "success_action_redirect"
+	// PostPolicyFilenameVariable is replaced with the name of the uploaded file by the server
+	PostPolicyFilenameVariable = "${filename}"
+)
+
+type postPolicyCondition struct {
+	operator string
+	field    string
+	value    string
+	// headerPrefixed reports whether the field is prefixed by x-wos- or x-amz- according to the signature.
+	headerPrefixed bool
+	min, max       int64
+}
+
+// PostPolicy builds the policy of a browser based POST upload, the conditions restrict the fields of the form.
+// Each method returns the PostPolicy to chain the calls.
+type PostPolicy struct {
+	bucket     string
+	expires    time.Duration
+	expiration time.Time
+	conditions []postPolicyCondition
+	formFields map[string]string
+}
+
+// NewPostPolicy creates a new PostPolicy instance for the bucket, the policy expires in 300 seconds by default.
+func NewPostPolicy(bucketName string) *PostPolicy {
+	return &PostPolicy{bucket: strings.TrimSpace(bucketName), expires: 300 * time.Second, formFields: make(map[string]string)}
+}
+
+func (policy *PostPolicy) addCondition(condition postPolicyCondition, formValue *string) *PostPolicy {
+	for i, c := range policy.conditions {
+		if c.operator == condition.operator && strings.EqualFold(c.field, condition.field) && c.headerPrefixed == condition.headerPrefixed {
+			policy.conditions = append(policy.conditions[:i], policy.conditions[i+1:]...)
+			break
+		}
+	}
+	policy.conditions = append(policy.conditions, condition)
+	if formValue != nil && !condition.headerPrefixed {
+		policy.formFields[condition.field] = *formValue
+	}
+	return policy
+}
+
+// SetExpires sets the seconds in which the policy expires from the time it is signed.
+func (policy *PostPolicy) SetExpires(expires int) *PostPolicy {
+	policy.expires = time.Duration(expires) * time.Second
+	policy.expiration = time.Time{}
+	return policy
+}
+
+// SetExpiration sets the time at which the policy expires.
+func (policy *PostPolicy) SetExpiration(expiration time.Time) *PostPolicy {
+	policy.expiration = expiration
+	return policy
+}
+
+// SetKey restricts the object key to the key, it may contain ${filename}.
+func (policy *PostPolicy) SetKey(key string) *PostPolicy {
+	return policy.addCondition(postPolicyCondition{operator: PostPolicyConditionEq, field: postFieldKey, value: key}, &key)
+}
+
+// SetKeyStartsWith restricts the object key to start with the prefix, the key field of the form is the prefix
+// followed by ${filename}.
+func (policy *PostPolicy) SetKeyStartsWith(prefix string) *PostPolicy {
+	key := prefix + PostPolicyFilenameVariable
+	return policy.addCondition(postPolicyCondition{operator: PostPolicyConditionStartsWith, field: postFieldKey, value: prefix}, &key)
+}
+
+// SetContentType restricts the Content-Type of the object to the contentType.
+func (policy *PostPolicy) SetContentType(contentType string) *PostPolicy {
+	return policy.addCondition(postPolicyCondition{operator: PostPolicyConditionEq, field: postFieldContentType, value: contentType}, &contentType)
+}
+
+// SetContentTypeStartsWith restricts the Content-Type of the object to start with the prefix, such as "image/",
+// the Content-Type field is set by the form.
+func (policy *PostPolicy) SetContentTypeStartsWith(prefix string) *PostPolicy {
+	return policy.addCondition(postPolicyCondition{operator: PostPolicyConditionStartsWith, field: postFieldContentType, value: prefix}, nil)
+}
+
+// SetContentLengthRange restricts the size of the uploaded file in bytes.
+func (policy *PostPolicy) SetContentLengthRange(min, max int64) *PostPolicy {
+	return policy.addCondition(postPolicyCondition{operator: PostPolicyConditionContentLengthRange, field: PostPolicyConditionContentLengthRange, min: min, max: max}, nil)
+}
+
+// SetSuccessActionStatus sets the status code returned after a successful upload, 200, 201 or 204.
+func (policy *PostPolicy) SetSuccessActionStatus(status int) *PostPolicy {
+	value := strconv.Itoa(status)
+	return policy.addCondition(postPolicyCondition{operator: PostPolicyConditionEq, field: postFieldSuccessActionStatus, value: value}, &value)
+}
+
+// SetSuccessActionRedirect sets the url to which the client is redirected after a successful upload.
+func (policy *PostPolicy) SetSuccessActionRedirect(redirectURL string) *PostPolicy {
+	return policy.addCondition(postPolicyCondition{operator: PostPolicyConditionEq, field: postFieldSuccessActionRedirect, value: redirectURL}, &redirectURL)
+}
+
+// SetACL sets the canned ACL of the object.
+func (policy *PostPolicy) SetACL(acl AclType) *PostPolicy {
+	value := string(acl)
+	return policy.addCondition(postPolicyCondition{operator: PostPolicyConditionEq, field: postFieldACL, value: value}, &value)
+}
+
+// SetMetadata sets a user metadata of the object, the field is x-wos-meta-key or x-amz-meta-key according to the signature.
+func (policy *PostPolicy) SetMetadata(key, value string) *PostPolicy {
+	policy.formFields[PREFIX_META+key] = value
+	return policy.addCondition(postPolicyCondition{operator: PostPolicyConditionEq, field: PREFIX_META + key, value: value, headerPrefixed: true}, nil)
+}
+
+// SetMetadataStartsWith restricts a user metadata of the object to start with the prefix, the field is set by the form.
+func (policy *PostPolicy) SetMetadataStartsWith(key, prefix string) *PostPolicy {
+	return policy.addCondition(postPolicyCondition{operator: PostPolicyConditionStartsWith, field: PREFIX_META + key, value: prefix, headerPrefixed: true}, nil)
+}
+
+// AddCondition adds a condition of the eq or starts-with operator on a field of the form, the value of an eq
+// condition is set as the field of the form.
+func (policy *PostPolicy) AddCondition(operator, field, value string) *PostPolicy {
+	field = strings.TrimPrefix(field, "$")
+	var formValue *string
+	if operator == PostPolicyConditionEq {
+		formValue = &value
+	}
+	return policy.addCondition(postPolicyCondition{operator: operator, field: field, value: value}, formValue)
+}
+
+func (condition postPolicyCondition) render(headerPrefix string) interface{} {
+	field := condition.field
+	if condition.headerPrefixed {
+		field = headerPrefix + field
+	}
+	switch condition.operator {
+	case PostPolicyConditionContentLengthRange:
+		return []interface{}{condition.operator, condition.min, condition.max}
+	case PostPolicyConditionEq:
+		return map[string]string{field: condition.value}
+	default:
+		return []interface{}{condition.operator, "$" + field, condition.value}
+	}
+}
+
+// PostPolicyOutput defines the signed policy and the fields of the form of a browser based POST upload
+type PostPolicyOutput struct {
+	// Url is the url of the bucket to which the form is posted.
+	Url          string
+	OriginPolicy string
+	Policy       string
+	Signature    string
+	Expiration   time.Time
+	// FormFields are the fields of the form to send before the file field, including the policy, the credential
+	// and the signature.
+	FormFields map[string]string
+}
+
+// postPolicyDocument is the JSON document of the policy.
+type postPolicyDocument struct {
+	Expiration string        `json:"expiration"`
+	Conditions []interface{} `json:"conditions"`
+}
+
+// CreatePostPolicy signs the PostPolicy and returns the url and the fields of the form of a browser based POST upload.
+func (wosClient WosClient) CreatePostPolicy(policy *PostPolicy) (output *PostPolicyOutput, err error) {
+	if policy == nil {
+		return nil, errors.New("PostPolicy is nil")
+	}
+	if policy.bucket == "" {
+		return nil, errors.New("Bucket is empty")
+	}
+	sh := wosClient.getSecurity()
+	if sh.ak == "" || sh.sk == "" {
+		return nil, errors.New("No ak/sk provided")
+	}
+
+	date := wosClient.now()
+	shortDate := date.Format(SHORT_DATE_FORMAT)
+	expiration := policy.expiration.UTC()
+	if expiration.IsZero() {
+		expiration = date.Add(policy.expires)
+	}
+	isWos := wosClient.conf.signature == SignatureWos
+	isV2 := wosClient.conf.signature == SignatureV2
+	headerPrefix := HEADER_PREFIX
+	if isWos {
+		headerPrefix = HEADER_PREFIX_WOS
+	}
+
+	formFields := make(map[string]string, len(policy.formFields)+5)
+	for field, value := range policy.formFields {
+		if strings.HasPrefix(field, PREFIX_META) {
+			field = headerPrefix + field
+		}
+		formFields[field] = value
+	}
+	conditions := make([]interface{}, 0, len(policy.conditions)+4)
+	conditions = append(conditions, map[string]string{postFieldBucket: policy.bucket})
+	for _, condition := range policy.conditions {
+		conditions = append(conditions, condition.render(headerPrefix))
+	}
+
+	var credentialFields []string
+	if isV2 {
+		formFields[v2AccessKeyIDParam] = sh.ak
+	} else {
+		credential, _ := getCredential(sh.ak, wosClient.conf.region, shortDate, isWos)
+		algorithm := V4_HASH_PREFIX
+		if isWos {
+			algorithm = V4_WOS_HASH_PREFIX
+		}
+		formFields[headerPrefix+postAlgorithmField] = algorithm
+		formFields[headerPrefix+postCredentialField] = credential
+		formFields[headerPrefix+postDateField] = date.Format(LONG_DATE_FORMAT)
+		credentialFields = []string{headerPrefix + postAlgorithmField, headerPrefix + postCredentialField, headerPrefix + postDateField}
+	}
+	for _, field := range credentialFields {
+		conditions = append(conditions, map[string]string{field: formFields[field]})
+	}
+
+	originPolicy, err := json.Marshal(postPolicyDocument{Expiration: expiration.Format(ISO8601_DATE_FORMAT), Conditions: conditions})
+	if err != nil {
+		return nil, err
+	}
+	encodedPolicy := Base64Encode(originPolicy)
+	var signature string
+	if isV2 {
+		signature = Base64Encode(HmacSha1([]byte(sh.sk), []byte(encodedPolicy)))
+		formFields[postSignatureField] = signature
+	} else {
+		signature = getSignature(encodedPolicy, sh.sk, wosClient.conf.region, shortDate, isWos)
+		formFields[headerPrefix+postSignatureField] = signature
+	}
+	formFields[postPolicyField] = encodedPolicy
+
+	requestURL, _ := wosClient.conf.formatUrls(policy.bucket, "", nil, true)
+	output = &PostPolicyOutput{
+		Url:          requestURL,
+		OriginPolicy: string(originPolicy),
+		Policy:       encodedPolicy,
+		Signature:    signature,
+		Expiration:   expiration,
+		FormFields:   formFields,
+	}
+	return
+}
+
+// PostObjectInput is the input parameter of PostObject function.
+type PostObjectInput struct {
+	Bucket string
+	// Key is the key field of the form, it may contain ${filename} which is replaced by the server.
+	Key string
+	// Filename is the filename of the file field, it defaults to the base name of SourceFile.
+	Filename    string
+	ContentType string
+	Metadata    map[string]string
+	// SuccessActionStatus and SuccessActionRedirect set the response of a successful upload.
+	SuccessActionStatus   int
+	SuccessActionRedirect string
+	// Policy is signed by the client if set, otherwise FormFields must carry a signed policy or a policy allowing
+	// the fields of the input is signed.
+	Policy *PostPolicy
+	// FormFields are the fields of the form, such as the FormFields of PostPolicyOutput handed to browsers. The
+	// fields of the input take precedence over them.
+	FormFields map[string]string
+	// Body is the content of the file field, it is read once and ContentLength limits its size if positive.
+	Body          io.Reader
+	ContentLength int64
+	SourceFile    string
+}
+
+// PostObjectOutput is the result of PostObject function.
+type PostObjectOutput struct {
+	BaseModel
+	// Location is the url of the object, or the redirect url if success_action_redirect is set.
+	Location string `xml:"Location"`
+	Bucket   string `xml:"Bucket"`
+	Key      string `xml:"Key"`
+	ETag     string `xml:"ETag"`
+}
+
+var postFormQuoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// PostObject uploads an object with a multipart/form-data POST request like a browser does.
+//
+// The form carries the policy and the signature instead of the Authorization header, which makes PostObject suitable
+// for testing the policies handed to browsers and mobile apps. The file field is streamed from the body.
+func (wosClient WosClient) PostObject(input *PostObjectInput, extensions ...extensionOptions) (output *PostObjectOutput, err error) {
+	if input == nil {
+		return nil, errors.New("PostObjectInput is nil")
+	}
+	if strings.TrimSpace(input.Bucket) == "" {
+		return nil, errors.New("Bucket is empty")
+	}
+	if input.Body == nil && strings.TrimSpace(input.SourceFile) == "" {
+		return nil, errors.New("Body and SourceFile are both empty")
+	}
+
+	body, size := input.Body, int64(-1)
+	filename := input.Filename
+	if sourceFile := strings.TrimSpace(input.SourceFile); sourceFile != "" {
+		fd, _err := os.Open(sourceFile)
+		if _err != nil {
+			return nil, _err
+		}
+		defer func() {
+			errMsg := fd.Close()
+			if errMsg != nil {
+				wosClient.logf(LEVEL_WARN, "Failed to close file with reason: %v", errMsg)
+			}
+		}()
+		stat, _err := fd.Stat()
+		if _err != nil {
+			return nil, _err
+		}
+		body, size = fd, stat.Size()
+		if filename == "" {
+			filename = filepath.Base(sourceFile)
+		}
+	} else if lenReader, ok := body.(interface{ Len() int }); ok {
+		size = int64(lenReader.Len())
+	}
+	if input.ContentLength > 0 && (size < 0 || input.ContentLength < size) {
+		body, size = io.LimitReader(body, input.ContentLength), input.ContentLength
+	}
+	if filename == "" {
+		filename = input.Key[strings.LastIndex(input.Key, "/")+1:]
+		if filename == "" || strings.Contains(filename, PostPolicyFilenameVariable) {
+			filename = "file"
+		}
+	}
+
+	postURL, fields, err := wosClient.preparePostFields(input, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		if name != postFieldKey {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if _, ok := fields[postFieldKey]; ok {
+		// the key field precedes the others as browsers send it
+		names = append([]string{postFieldKey}, names...)
+	}
+	form := postForm{names: names, fields: fields, filename: filename, boundary: multipart.NewWriter(nil).Boundary()}
+
+	headers := http.Header{}
+	headers.Set(HEADER_CONTENT_TYPE_CAML, "multipart/form-data; boundary="+form.boundary)
+	if size >= 0 {
+		// the form is rendered with an empty file to count the length of the fields and the boundaries
+		counter := &countWriter{}
+		if err = form.write(counter, strings.NewReader("")); err != nil {
+			return nil, err
+		}
+		headers.Set(HEADER_CONTENT_LENGTH_CAMEL, Int64ToString(counter.count+size))
+	}
+	reader, pipeWriter := io.Pipe()
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		pipeWriter.CloseWithError(form.write(pipeWriter, body))
+	}()
+
+	output = &PostObjectOutput{}
+	err = wosClient.doHTTPWithSignedURL("PostObject", HTTP_POST, postURL, headers, reader, output, true, extensions)
+	// the request may fail before the body is read, closing the reader unblocks the writes to the pipe, and the
+	// body is not read after PostObject returns
+	reader.CloseWithError(err)
+	<-written
+	if err != nil {
+		output = nil
+	} else {
+		ParsePostObjectOutput(output, strings.Replace(fields[postFieldKey], PostPolicyFilenameVariable, filename, -1), input.Bucket)
+	}
+	return
+}
+
+// postForm renders the multipart/form-data body of PostObject, the file field is the last one.
+type postForm struct {
+	names    []string
+	fields   map[string]string
+	filename string
+	boundary string
+}
+
+func (form postForm) write(w io.Writer, file io.Reader) error {
+	writer := multipart.NewWriter(w)
+	if err := writer.SetBoundary(form.boundary); err != nil {
+		return err
+	}
+	for _, name := range form.names {
+		if err := writer.WriteField(name, form.fields[name]); err != nil {
+			return err
+		}
+	}
+	partHeader := make(textproto.MIMEHeader, 2)
+	partHeader.Set(HEADER_CONTENT_DISPOSITION_CAMEL, fmt.Sprintf(`form-data; name="%s"; filename="%s"`, postFileField, postFormQuoteEscaper.Replace(form.filename)))
+	partHeader.Set(HEADER_CONTENT_TYPE_CAML, form.fields[postFieldContentType])
+	part, err := writer.CreatePart(partHeader)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(part, file); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+type countWriter struct {
+	count int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.count += int64(len(p))
+	return len(p), nil
+}
+
+// preparePostFields returns the url and the fields of the form of PostObject, the policy is signed if the fields
+// do not carry one.
+func (wosClient WosClient) preparePostFields(input *PostObjectInput, filename string) (postURL string, fields map[string]string, err error) {
+	isWos := wosClient.conf.signature == SignatureWos
+	headerPrefix := HEADER_PREFIX
+	if isWos {
+		headerPrefix = HEADER_PREFIX_WOS
+	}
+	metaField := func(key string) string {
+		if strings.HasPrefix(strings.ToLower(key), HEADER_PREFIX) || strings.HasPrefix(strings.ToLower(key), HEADER_PREFIX_WOS) {
+			return key
+		}
+		return headerPrefix + PREFIX_META + key
+	}
+
+	fields = make(map[string]string, len(input.FormFields)+len(input.Metadata)+4)
+	for name, value := range input.FormFields {
+		fields[name] = value
+	}
+	if input.Key != "" {
+		fields[postFieldKey] = input.Key
+	}
+	if _, ok := fields[postFieldKey]; !ok {
+		return "", nil, errors.New("Key is empty")
+	}
+	for key, value := range input.Metadata {
+		fields[metaField(key)] = value
+	}
+	if input.SuccessActionStatus > 0 {
+		fields[postFieldSuccessActionStatus] = strconv.Itoa(input.SuccessActionStatus)
+	}
+	if input.SuccessActionRedirect != "" {
+		fields[postFieldSuccessActionRedirect] = input.SuccessActionRedirect
+	}
+	if input.ContentType != "" {
+		fields[postFieldContentType] = input.ContentType
+	}
+	if fields[postFieldContentType] == "" {
+		fields[postFieldContentType] = mimeTypes[strings.ToLower(filename[strings.LastIndex(filename, ".")+1:])]
+		if fields[postFieldContentType] == "" {
+			fields[postFieldContentType] = "application/octet-stream"
+		}
+	}
+
+	if _, ok := fields[postPolicyField]; !ok {
+		policy := input.Policy
+		if policy == nil {
+			// signs a policy allowing exactly the fields of the form
+			policy = NewPostPolicy(input.Bucket)
+			names := make([]string, 0, len(fields))
+			for name := range fields {
+				if lowerName := strings.ToLower(name); !postExemptFields[lowerName] && lowerName != postFieldBucket {
+					names = append(names, name)
+				}
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				policy.AddCondition(PostPolicyConditionEq, name, fields[name])
+			}
+		}
+		signed, _err := wosClient.CreatePostPolicy(policy)
+		if _err != nil {
+			return "", nil, _err
+		}
+		for name, value := range signed.FormFields {
+			if _, ok := fields[name]; !ok {
+				fields[name] = value
+			}
+		}
+		postURL = signed.Url
+	}
+	if postURL == "" {
+		postURL, _ = wosClient.conf.formatUrls(input.Bucket, "", nil, true)
+	}
+	return
+}
+
+// isPostObjectRedirect reports whether the response is the redirect of a successful PostObject with
+// success_action_redirect.
+func isPostObjectRedirect(output IBaseModel, resp *http.Response) bool {
+	_, ok := output.(*PostObjectOutput)
+	return ok && resp.StatusCode >= 300 && resp.StatusCode < 400 && resp.Header.Get(HEADER_LOCATION_CAMEL) != ""
+}
+
+// ParsePostObjectOutput sets PostObjectOutput field values with the response headers and the redirect url,
+// the bucket and the key default to the ones of the form.
+func ParsePostObjectOutput(output *PostObjectOutput, key, bucket string) {
+	if ret, ok := output.ResponseHeaders[HEADER_LOCATION_AMZ]; ok && output.Location == "" {
+		output.Location = ret[0]
+	}
+	if ret, ok := output.ResponseHeaders[HEADER_ETAG]; ok && output.ETag == "" {
+		output.ETag = ret[0]
+	}
+	if output.StatusCode >= 300 && output.StatusCode < 400 {
+		if redirectURL, err := url.Parse(output.Location); err == nil {
+			query := redirectURL.Query()
+			if value := query.Get(postFieldBucket); value != "" {
+				output.Bucket = value
+			}
+			if value := query.Get(postFieldKey); value != "" {
+				output.Key = value
+			}
+			if value := query.Get(HEADER_ETAG); value != "" {
+				output.ETag = value
+			}
+		}
+	}
+	if output.Bucket == "" {
+		output.Bucket = bucket
+	}
+	if output.Key == "" {
+		output.Key = key
+	}
+}
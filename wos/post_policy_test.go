@@ -0,0 +1,101 @@
+package wos
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPostObjectPolicyCoversFields(t *testing.T) {
+	var lock sync.Mutex
+	var fields map[string][]string
+	var verifyErr error
+	verifier := NewVerifier(testCredentials, "")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, err := verifier.Verify(r)
+		lock.Lock()
+		fields, verifyErr = r.MultipartForm.Value, err
+		lock.Unlock()
+		if err != nil {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	client := newTestClient(t, server.URL)
+
+	cases := []struct {
+		name        string
+		input       PostObjectInput
+		contentType string
+		wantErr     bool
+	}{
+		{name: "default content type", contentType: "text/plain",
+			input: PostObjectInput{Key: "dir/a.txt", Metadata: map[string]string{"owner": "alice"},
+				FormFields: map[string]string{"acl": "public-read"}}},
+		{name: "content type", contentType: "image/png",
+			input: PostObjectInput{Key: "a.txt", ContentType: "image/png", SuccessActionStatus: 204}},
+		{name: "field outside the policy", wantErr: true,
+			input: PostObjectInput{Key: "a.txt", Policy: NewPostPolicy("bucket").SetKey("a.txt"),
+				FormFields: map[string]string{"acl": "public-read"}}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			input := c.input
+			input.Bucket, input.Body = "bucket", strings.NewReader("data")
+			_, err := client.PostObject(&input)
+			lock.Lock()
+			defer lock.Unlock()
+			if c.wantErr {
+				if err == nil || verifyErr == nil || verifyErr.(VerifyError).Reason != VerifyReasonPolicyCondition {
+					t.Fatalf("PostObject error = %v, verify error = %v, want %s", err, verifyErr, VerifyReasonPolicyCondition)
+				}
+				return
+			}
+			if err != nil || verifyErr != nil {
+				t.Fatalf("PostObject error = %v, verify error = %v", err, verifyErr)
+			}
+			if got := fields[postFieldContentType]; len(got) != 1 || got[0] != c.contentType {
+				t.Errorf("Content-Type = %v, want %s", got, c.contentType)
+			}
+		})
+	}
+}
+
+// postBody counts the reads of the body after PostObject returned.
+type postBody struct {
+	returned int32
+	late     int32
+}
+
+func (body *postBody) Read(p []byte) (int, error) {
+	if atomic.LoadInt32(&body.returned) != 0 {
+		atomic.AddInt32(&body.late, 1)
+	}
+	p[0] = 'a'
+	return 1, nil
+}
+
+func TestPostObjectEarlyError(t *testing.T) {
+	client := newTestClient(t, "http://127.0.0.1:1")
+	body := &postBody{}
+	_, err := client.PostObject(&PostObjectInput{Bucket: "bucket", Key: "a.txt", Body: body},
+		WithCallEndpoint("ftp://wos.example.com"))
+	atomic.StoreInt32(&body.returned, 1)
+	if err == nil {
+		t.Fatal("PostObject succeeded with an invalid endpoint")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if late := atomic.LoadInt32(&body.late); late != 0 {
+		t.Errorf("body read %d times after PostObject returned", late)
+	}
+}
@@ -0,0 +1,204 @@
+package wos
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ProgressEventType defines type of the progress event
+type ProgressEventType int
+
+const (
+	// TransferStartedEvent is published before the first byte of the body is transferred
+	TransferStartedEvent ProgressEventType = iota + 1
+	// TransferDataEvent is published each time a part of the body is transferred
+	TransferDataEvent
+	// TransferCompletedEvent is published after the body is transferred
+	TransferCompletedEvent
+	// TransferFailedEvent is published if the transfer of the body fails
+	TransferFailedEvent
+)
+
+// ProgressEvent defines the progress of the transfer of a body, TotalBytes is -1 if the size is unknown.
+// ConsumedBytes starts from 0 again if the request is retried.
+type ProgressEvent struct {
+	ConsumedBytes int64
+	TotalBytes    int64
+	RWOnceBytes   int64
+	EventType     ProgressEventType
+}
+
+// ProgressListener defines interface with function: ProgressChanged
+//
+// ProgressChanged is called when the request body is sent or the response body is read,
+// it may be called from the goroutine of the http.Transport and must not block.
+type ProgressListener interface {
+	ProgressChanged(event *ProgressEvent)
+}
+
+type progressTracker struct {
+	listener ProgressListener
+	lock     sync.Mutex
+	started  bool
+	finished bool
+	consumed int64
+	total    int64
+}
+
+func newProgressTracker(listener ProgressListener) *progressTracker {
+	if listener == nil {
+		return nil
+	}
+	return &progressTracker{listener: listener}
+}
+
+// start publishes the started event once, a new start of a retried request resets the consumed bytes.
+func (t *progressTracker) start(total int64) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.consumed = 0
+	t.total = total
+	if !t.started {
+		t.started = true
+		t.listener.ProgressChanged(&ProgressEvent{TotalBytes: total, EventType: TransferStartedEvent})
+	}
+}
+
+func (t *progressTracker) transferred(n int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.consumed += int64(n)
+	t.listener.ProgressChanged(&ProgressEvent{ConsumedBytes: t.consumed, TotalBytes: t.total, RWOnceBytes: int64(n), EventType: TransferDataEvent})
+}
+
+func (t *progressTracker) finish(err error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if !t.started || t.finished {
+		return
+	}
+	t.finished = true
+	eventType := TransferCompletedEvent
+	if err != nil {
+		eventType = TransferFailedEvent
+	}
+	t.listener.ProgressChanged(&ProgressEvent{ConsumedBytes: t.consumed, TotalBytes: t.total, EventType: eventType})
+}
+
+// rateLimiter limits the average rate of the bytes read since its start.
+type rateLimiter struct {
+	ctx   context.Context
+	rate  int64
+	lock  sync.Mutex
+	start time.Time
+	count int64
+}
+
+func newRateLimiter(ctx context.Context, bytesPerSecond int64) *rateLimiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{ctx: ctx, rate: bytesPerSecond, start: time.Now()}
+}
+
+func (l *rateLimiter) wait(n int) error {
+	l.lock.Lock()
+	l.count += int64(n)
+	delay := time.Duration(float64(l.count)/float64(l.rate)*float64(time.Second)) - time.Since(l.start)
+	l.lock.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+	return sleepWithContext(l.ctx, delay)
+}
+
+// transferReadCloser reports the progress and limits the rate of the body read through it.
+type transferReadCloser struct {
+	io.ReadCloser
+	progress *progressTracker
+	limiter  *rateLimiter
+	// finishOnEOF publishes the completed event at the end of the body, it is set for the response body.
+	finishOnEOF bool
+}
+
+func (r *transferReadCloser) Read(p []byte) (n int, err error) {
+	if r.limiter != nil && int64(len(p)) > r.limiter.rate {
+		p = p[:r.limiter.rate]
+	}
+	n, err = r.ReadCloser.Read(p)
+	if n > 0 {
+		if r.progress != nil {
+			r.progress.transferred(n)
+		}
+		if r.limiter != nil {
+			if _err := r.limiter.wait(n); _err != nil && err == nil {
+				err = _err
+			}
+		}
+	}
+	if r.finishOnEOF && r.progress != nil && err != nil {
+		if err == io.EOF {
+			r.progress.finish(nil)
+		} else {
+			r.progress.finish(err)
+		}
+	}
+	return
+}
+
+// transferControl holds the progress listener and the rate limit of a call.
+type transferControl struct {
+	upload   *progressTracker
+	download *progressTracker
+	limiter  *rateLimiter
+}
+
+func (wosClient WosClient) newTransferControl() *transferControl {
+	conf := wosClient.conf
+	if conf.progressListener == nil && conf.rateLimit <= 0 {
+		return nil
+	}
+	limiter := conf.rateLimiter
+	if limiter == nil {
+		limiter = newRateLimiter(conf.ctx, conf.rateLimit)
+	}
+	return &transferControl{
+		upload:   newProgressTracker(conf.progressListener),
+		download: newProgressTracker(conf.progressListener),
+		limiter:  limiter,
+	}
+}
+
+// wrapRequestBody reports the progress and limits the rate of the body of an attempt.
+func (wosClient WosClient) wrapRequestBody(req *http.Request) {
+	control := wosClient.call.transfer
+	if control == nil || req.Body == nil || req.Body == http.NoBody {
+		return
+	}
+	if control.upload != nil {
+		control.upload.start(req.ContentLength)
+	}
+	req.Body = &transferReadCloser{ReadCloser: req.Body, progress: control.upload, limiter: control.limiter}
+}
+
+// wrapResponseBody reports the progress and limits the rate of the response body returned to the caller.
+func (wosClient WosClient) wrapResponseBody(resp *http.Response) {
+	control := wosClient.call.transfer
+	if control == nil || resp == nil || resp.Body == nil || resp.Body == http.NoBody || resp.ContentLength == 0 {
+		return
+	}
+	if control.download != nil {
+		control.download.start(resp.ContentLength)
+	}
+	resp.Body = &transferReadCloser{ReadCloser: resp.Body, progress: control.download, limiter: control.limiter, finishOnEOF: true}
+}
+
+// finishUpload publishes the completed or the failed event of the request body.
+func (wosClient WosClient) finishUpload(err error) {
+	if control := wosClient.call.transfer; control != nil && control.upload != nil {
+		control.upload.finish(err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"math/rand"
 	"net"
 	"net/http"
+	"net/http/httptrace"
 	"os"
 	"strings"
 	"sync"
@@ -27,14 +28,14 @@ type securityHolder struct {
 var emptySecurityHolder = securityHolder{}
 
 type securityProvider interface {
-	getSecurity() securityHolder
+	getSecurity(logger Logger) securityHolder
 }
 
 type BasicSecurityProvider struct {
 	val atomic.Value
 }
 
-func (bsp *BasicSecurityProvider) getSecurity() securityHolder {
+func (bsp *BasicSecurityProvider) getSecurity(logger Logger) securityHolder {
 	if sh, ok := bsp.val.Load().(securityHolder); ok {
 		return sh
 	}
@@ -57,7 +58,7 @@ type EnvSecurityProvider struct {
 	once   sync.Once
 }
 
-func (esp *EnvSecurityProvider) getSecurity() securityHolder {
+func (esp *EnvSecurityProvider) getSecurity(logger Logger) securityHolder {
 	//ensure run only once
 	esp.once.Do(func() {
 		esp.sh = securityHolder{
@@ -104,13 +105,22 @@ func (ecsSp *EcsSecurityProvider) loadTemporarySecurityHolder() (TemporarySecuri
 	}
 }
 
-func (ecsSp *EcsSecurityProvider) getAndSetSecurityWithOutLock() securityHolder {
+func (ecsSp *EcsSecurityProvider) getAndSetSecurityWithOutLock(logger Logger) securityHolder {
 	_sh := TemporarySecurityHolder{}
 	_sh.expireDate = time.Now().Add(time.Minute * 5)
 	retryCount := 0
 	for {
 		if req, err := http.NewRequest("GET", ecsRequestURL, nil); err == nil {
 			start := GetCurrentTimestamp()
+			var dialStart int64
+			req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+				ConnectStart: func(network, addr string) {
+					dialStart = GetCurrentTimestamp()
+				},
+				ConnectDone: func(network, addr string, err error) {
+					loggerf(logger, LEVEL_INFO, "Do http dial cost %d ms", (GetCurrentTimestamp() - dialStart))
+				},
+			}))
 			res, err := ecsSp.httpClient.Do(req)
 			if err == nil {
 				if data, _err := ioutil.ReadAll(res.Body); _err == nil {
@@ -123,16 +133,16 @@ func (ecsSp *EcsSecurityProvider) getAndSetSecurityWithOutLock() securityHolder
 						} `json:"credential"`
 					}{}
 
-					doLog(LEVEL_DEBUG, "Get the json data from ecs succeed")
+					loggerf(logger, LEVEL_DEBUG, "Get the json data from ecs succeed")
 
 					if jsonErr := json.Unmarshal(data, temp); jsonErr == nil {
 						_sh.ak = temp.Credential.AK
 						_sh.sk = temp.Credential.SK
 						_sh.expireDate = temp.Credential.ExpireDate.Add(time.Minute * -1)
 
-						doLog(LEVEL_INFO, "Get security from ecs succeed, AK:xxxx, SK:xxxx, SecurityToken:xxxx, ExprireDate %s", _sh.expireDate)
+						loggerf(logger, LEVEL_INFO, "Get security from ecs succeed, AK:xxxx, SK:xxxx, SecurityToken:xxxx, ExprireDate %s", _sh.expireDate)
 
-						doLog(LEVEL_INFO, "Get security from ecs succeed, cost %d ms", (GetCurrentTimestamp() - start))
+						loggerf(logger, LEVEL_INFO, "Get security from ecs succeed, cost %d ms", (GetCurrentTimestamp() - start))
 						break
 					} else {
 						err = jsonErr
@@ -142,11 +152,11 @@ func (ecsSp *EcsSecurityProvider) getAndSetSecurityWithOutLock() securityHolder
 				}
 			}
 
-			doLog(LEVEL_WARN, "Try to get security from ecs failed, cost %d ms, err %s", (GetCurrentTimestamp() - start), err.Error())
+			loggerf(logger, LEVEL_WARN, "Try to get security from ecs failed, cost %d ms, err %s", (GetCurrentTimestamp() - start), err.Error())
 		}
 
 		if retryCount >= ecsSp.retryCount {
-			doLog(LEVEL_WARN, "Try to get security from ecs failed and exceed the max retry count")
+			loggerf(logger, LEVEL_WARN, "Try to get security from ecs failed and exceed the max retry count")
 			break
 		}
 		sleepTime := float64(retryCount+2) * rand.Float64()
@@ -161,32 +171,32 @@ func (ecsSp *EcsSecurityProvider) getAndSetSecurityWithOutLock() securityHolder
 	return _sh.securityHolder
 }
 
-func (ecsSp *EcsSecurityProvider) getAndSetSecurity() securityHolder {
+func (ecsSp *EcsSecurityProvider) getAndSetSecurity(logger Logger) securityHolder {
 	ecsSp.lock.Lock()
 	defer ecsSp.lock.Unlock()
 	tsh, succeed := ecsSp.loadTemporarySecurityHolder()
 	if !succeed || time.Now().After(tsh.expireDate) {
-		return ecsSp.getAndSetSecurityWithOutLock()
+		return ecsSp.getAndSetSecurityWithOutLock(logger)
 	}
 	return tsh.securityHolder
 }
 
-func (ecsSp *EcsSecurityProvider) getSecurity() securityHolder {
+func (ecsSp *EcsSecurityProvider) getSecurity(logger Logger) securityHolder {
 	if tsh, succeed := ecsSp.loadTemporarySecurityHolder(); succeed {
 		if time.Now().Before(tsh.expireDate) {
 			//not expire
 			if time.Now().Add(time.Minute*5).After(tsh.expireDate) && atomic.CompareAndSwapInt32(&ecsSp.prefetch, 0, 1) {
 				//do prefetch
-				sh := ecsSp.getAndSetSecurityWithOutLock()
+				sh := ecsSp.getAndSetSecurityWithOutLock(logger)
 				atomic.CompareAndSwapInt32(&ecsSp.prefetch, 1, 0)
 				return sh
 			}
 			return tsh.securityHolder
 		}
-		return ecsSp.getAndSetSecurity()
+		return ecsSp.getAndSetSecurity(logger)
 	}
 
-	return ecsSp.getAndSetSecurity()
+	return ecsSp.getAndSetSecurity(logger)
 }
 
 func getInternalTransport() *http.Transport {
@@ -194,15 +204,10 @@ func getInternalTransport() *http.Transport {
 	timeout := 10
 	transport := &http.Transport{
 		Dial: func(network, addr string) (net.Conn, error) {
-			start := GetCurrentTimestamp()
 			conn, err := (&net.Dialer{
 				Timeout:  time.Second * time.Duration(timeout),
 				Resolver: net.DefaultResolver,
 			}).Dial(network, addr)
-
-			if isInfoLogEnabled() {
-				doLog(LEVEL_INFO, "Do http dial cost %d ms", (GetCurrentTimestamp() - start))
-			}
 			if err != nil {
 				return nil, err
 			}
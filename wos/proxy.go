@@ -0,0 +1,122 @@
+package wos
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// noProxyMatcher matches the hosts which are accessed without the proxy, the entries are in the format of
+// the NO_PROXY environment variable: "*", a host name matching itself and its sub-domains, an IP address
+// or a CIDR, each optionally followed by ":port".
+type noProxyMatcher struct {
+	all     bool
+	entries []noProxyEntry
+}
+
+type noProxyEntry struct {
+	host   string
+	ipNet  *net.IPNet
+	ip     net.IP
+	port   string
+	suffix bool
+}
+
+func getNoProxyFromEnvironment() []string {
+	value := os.Getenv("NO_PROXY")
+	if value == "" {
+		value = os.Getenv("no_proxy")
+	}
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+func newNoProxyMatcher(noProxy []string) *noProxyMatcher {
+	matcher := &noProxyMatcher{}
+	for _, entry := range noProxy {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if entry == "*" {
+			matcher.all = true
+			continue
+		}
+		if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+			matcher.entries = append(matcher.entries, noProxyEntry{ipNet: ipNet})
+			continue
+		}
+		host, port := entry, ""
+		if _host, _port, err := net.SplitHostPort(entry); err == nil {
+			host, port = _host, _port
+		}
+		host = strings.TrimPrefix(strings.Trim(host, "[]"), "*")
+		if ip := net.ParseIP(host); ip != nil {
+			matcher.entries = append(matcher.entries, noProxyEntry{ip: ip, port: port})
+			continue
+		}
+		matcher.entries = append(matcher.entries, noProxyEntry{host: strings.TrimPrefix(host, "."), port: port, suffix: true})
+	}
+	return matcher
+}
+
+func (matcher *noProxyMatcher) match(requestURL *url.URL) bool {
+	if matcher.all {
+		return true
+	}
+	host := strings.ToLower(requestURL.Hostname())
+	port := requestURL.Port()
+	if port == "" {
+		if requestURL.Scheme == "https" {
+			port = "443"
+		} else {
+			port = "80"
+		}
+	}
+	ip := net.ParseIP(host)
+	for _, entry := range matcher.entries {
+		if entry.port != "" && entry.port != port {
+			continue
+		}
+		switch {
+		case entry.ipNet != nil:
+			if ip != nil && entry.ipNet.Contains(ip) {
+				return true
+			}
+		case entry.ip != nil:
+			if ip != nil && entry.ip.Equal(ip) {
+				return true
+			}
+		case host == entry.host || strings.HasSuffix(host, "."+entry.host):
+			return true
+		}
+	}
+	return false
+}
+
+// getProxyFunc returns the proxy function of the transport, the credentials set by WithProxyCredentials
+// override the user info of the proxy URL.
+func (conf *config) getProxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	proxyURL, err := url.Parse(conf.proxyURL)
+	if err != nil {
+		return nil, err
+	}
+	if conf.proxyUsername != "" {
+		proxyURL.User = url.UserPassword(conf.proxyUsername, conf.proxyPassword)
+	}
+	noProxy := conf.noProxy
+	if noProxy == nil {
+		noProxy = getNoProxyFromEnvironment()
+	}
+	matcher := newNoProxyMatcher(noProxy)
+	return func(req *http.Request) (*url.URL, error) {
+		if matcher.match(req.URL) {
+			return nil, nil
+		}
+		return proxyURL, nil
+	}, nil
+}
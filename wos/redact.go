@@ -0,0 +1,279 @@
+package wos
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const redactedValue = "******"
+
+// The headers whose values are never written to the logs.
+var defaultRedactedHeaders = []string{
+	HEADER_AUTH_CAMEL,
+	"Proxy-Authorization",
+	HEADER_STS_TOKEN_AMZ,
+	HEADER_STS_TOKEN_WOS,
+	HEADER_PREFIX + HEADER_SSEC_KEY,
+	HEADER_PREFIX_WOS + HEADER_SSEC_KEY,
+	HEADER_PREFIX + HEADER_SSEC_COPY_SOURCE_KEY,
+	HEADER_PREFIX_WOS + HEADER_SSEC_COPY_SOURCE_KEY,
+}
+
+// The query parameters whose values are never written to the logs.
+var defaultRedactedQueryParams = []string{
+	"Signature",
+	PARAM_SIGNATURE_AMZ_CAMEL,
+	PARAM_SIGNATURE_WOS_CAMEL,
+	HEADER_STS_TOKEN_AMZ,
+	HEADER_STS_TOKEN_WOS,
+}
+
+// The XML elements whose contents are never written to the logs, such as those of the temporary credentials.
+var redactedXMLElements = regexp.MustCompile(`(?i)<(SecretAccessKey|SecretKey|SessionToken|SecurityToken|Signature|Policy)>[^<]*(</|$)`)
+
+// redactor masks the values of the sensitive headers and query parameters, the names are case-insensitive.
+type redactor struct {
+	headers     map[string]bool
+	queryParams map[string]bool
+}
+
+var defaultRedactor = newRedactor(defaultRedactedHeaders, defaultRedactedQueryParams)
+
+func newRedactor(headers, queryParams []string) *redactor {
+	r := &redactor{
+		headers:     make(map[string]bool, len(headers)),
+		queryParams: make(map[string]bool, len(queryParams)),
+	}
+	for _, header := range headers {
+		r.headers[strings.ToLower(strings.TrimSpace(header))] = true
+	}
+	for _, param := range queryParams {
+		r.queryParams[strings.ToLower(strings.TrimSpace(param))] = true
+	}
+	return r
+}
+
+// with returns a copy of the redactor masking the headers and the query parameters as well.
+func (r *redactor) with(headers, queryParams []string) *redactor {
+	_headers := make([]string, 0, len(r.headers)+len(headers))
+	for header := range r.headers {
+		_headers = append(_headers, header)
+	}
+	_queryParams := make([]string, 0, len(r.queryParams)+len(queryParams))
+	for param := range r.queryParams {
+		_queryParams = append(_queryParams, param)
+	}
+	return newRedactor(append(_headers, headers...), append(_queryParams, queryParams...))
+}
+
+func (r *redactor) isRedactedHeader(key string) bool {
+	return r.headers[strings.ToLower(key)]
+}
+
+func (r *redactor) isRedactedQueryParam(key string) bool {
+	if _key, err := url.QueryUnescape(key); err == nil {
+		key = _key
+	}
+	return r.queryParams[strings.ToLower(key)]
+}
+
+// redactHeaders returns a copy of headers with the sensitive values masked.
+func (r *redactor) redactHeaders(headers map[string][]string) map[string][]string {
+	ret := make(map[string][]string, len(headers))
+	for key, value := range headers {
+		if r.isRedactedHeader(key) {
+			ret[key] = []string{redactedValue}
+		} else {
+			ret[key] = value
+		}
+	}
+	return ret
+}
+
+// redactQuery masks the sensitive values of a raw query string and keeps the rest of it unchanged.
+func (r *redactor) redactQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return rawQuery
+	}
+	pairs := strings.Split(rawQuery, "&")
+	for i, pair := range pairs {
+		if index := strings.Index(pair, "="); index > 0 && r.isRedactedQueryParam(pair[:index]) {
+			pairs[i] = pair[:index+1] + redactedValue
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// redactURL masks the sensitive query values of a URL.
+func (r *redactor) redactURL(rawURL string) string {
+	index := strings.Index(rawURL, "?")
+	if index < 0 {
+		return rawURL
+	}
+	return rawURL[:index+1] + r.redactQuery(rawURL[index+1:])
+}
+
+// redactText masks the values of the sensitive headers and query parameters wherever they occur in text,
+// such as the string to sign and the canonical request.
+func (r *redactor) redactText(text string, headers map[string][]string, rawQuery string) string {
+	for key, value := range headers {
+		if !r.isRedactedHeader(key) {
+			continue
+		}
+		for _, v := range value {
+			if v != "" {
+				text = strings.Replace(text, v, redactedValue, -1)
+			}
+		}
+	}
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if index := strings.Index(pair, "="); index > 0 && index < len(pair)-1 && r.isRedactedQueryParam(pair[:index]) {
+			text = strings.Replace(text, pair[index+1:], redactedValue, -1)
+		}
+	}
+	return text
+}
+
+func (wosClient WosClient) getRedactor() *redactor {
+	if wosClient.conf != nil && wosClient.conf.redactor != nil {
+		return wosClient.conf.redactor
+	}
+	return defaultRedactor
+}
+
+// captureReadCloser keeps the first limit bytes read through it.
+type captureReadCloser struct {
+	io.ReadCloser
+	limit int
+	lock  sync.Mutex
+	buf   []byte
+	count int64
+}
+
+func (c *captureReadCloser) Read(p []byte) (n int, err error) {
+	n, err = c.ReadCloser.Read(p)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.count += int64(n)
+	if remain := c.limit - len(c.buf); remain > 0 && n > 0 {
+		if remain > n {
+			remain = n
+		}
+		c.buf = append(c.buf, p[:remain]...)
+	}
+	return
+}
+
+func (c *captureReadCloser) captured() ([]byte, int64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.buf, c.count
+}
+
+type peekedReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// startWireDump prepares the request to be dumped after it is sent, it returns nil if the wire dump is off.
+func (wosClient WosClient) startWireDump(req *http.Request) *captureReadCloser {
+	if !wosClient.conf.wireDump || !wosClient.logEnabled(LEVEL_DEBUG) {
+		return nil
+	}
+	capture := &captureReadCloser{limit: wosClient.conf.wireDumpBodyBytes}
+	if req.Body != nil && req.Body != http.NoBody {
+		capture.ReadCloser = req.Body
+		req.Body = capture
+	}
+	return capture
+}
+
+// finishWireDump writes the request line, the headers and the first bytes of the bodies of the request and the response.
+func (wosClient WosClient) finishWireDump(capture *captureReadCloser, req *http.Request, resp *http.Response, attempt int) {
+	if capture == nil {
+		return
+	}
+	r := wosClient.getRedactor()
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("%s %s %s\n", req.Method, r.redactURL(req.URL.RequestURI()), req.Proto))
+	builder.WriteString(fmt.Sprintf("Host: %s\n", host))
+	writeDumpHeaders(&builder, r.redactHeaders(req.Header))
+	var body []byte
+	var count int64
+	if capture.ReadCloser != nil {
+		body, count = capture.captured()
+	}
+	writeDumpBody(&builder, req.Header.Get(HEADER_CONTENT_TYPE_CAML), body, count)
+	wosClient.log(LEVEL_DEBUG, "Wire dump of request:\n"+strings.TrimSuffix(builder.String(), "\n"), LogField{Key: LogFieldAttempt, Value: attempt})
+
+	if resp == nil {
+		return
+	}
+	builder.Reset()
+	builder.WriteString(fmt.Sprintf("%s %s\n", resp.Proto, resp.Status))
+	writeDumpHeaders(&builder, r.redactHeaders(resp.Header))
+	body = nil
+	if resp.Body != nil && resp.Body != http.NoBody && capture.limit > 0 {
+		buf := make([]byte, capture.limit)
+		n, _ := io.ReadFull(resp.Body, buf)
+		body = buf[:n]
+		resp.Body = peekedReadCloser{Reader: io.MultiReader(bytes.NewReader(body), resp.Body), Closer: resp.Body}
+	}
+	writeDumpBody(&builder, resp.Header.Get(HEADER_CONTENT_TYPE_CAML), body, resp.ContentLength)
+	wosClient.log(LEVEL_DEBUG, "Wire dump of response:\n"+strings.TrimSuffix(builder.String(), "\n"), LogField{Key: LogFieldAttempt, Value: attempt})
+}
+
+func writeDumpHeaders(builder *strings.Builder, headers map[string][]string) {
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		builder.WriteString(fmt.Sprintf("%s: %s\n", key, strings.Join(headers[key], ",")))
+	}
+}
+
+// redactBody masks the sensitive contents of an XML body, it returns false for a form body,
+// which carries the policy, the signature and the security token of a post object request and is not dumped.
+func redactBody(contentType string, body []byte) ([]byte, bool) {
+	contentType = strings.ToLower(contentType)
+	if strings.HasPrefix(contentType, "multipart/form-data") || strings.HasPrefix(contentType, "application/x-www-form-urlencoded") {
+		return nil, false
+	}
+	if strings.Contains(contentType, "xml") || bytes.HasPrefix(bytes.TrimSpace(body), []byte("<")) {
+		return redactedXMLElements.ReplaceAll(body, []byte("<$1>"+redactedValue+"$2")), true
+	}
+	return body, true
+}
+
+func writeDumpBody(builder *strings.Builder, contentType string, body []byte, total int64) {
+	if len(body) == 0 {
+		return
+	}
+	body, ok := redactBody(contentType, body)
+	if !ok {
+		if total < 0 {
+			builder.WriteString("\n(form body omitted)")
+		} else {
+			builder.WriteString(fmt.Sprintf("\n(%d bytes of form body omitted)", total))
+		}
+		return
+	}
+	if total > int64(len(body)) {
+		builder.WriteString(fmt.Sprintf("\n%q (first %d of %d bytes)", body, len(body), total))
+		return
+	}
+	builder.WriteString(fmt.Sprintf("\n%q", body))
+}
@@ -0,0 +1,64 @@
+package wos
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRedactBody(t *testing.T) {
+	cases := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+		ok          bool
+	}{
+		{"multipart form", "multipart/form-data; boundary=x", "--x\r\npolicy", "", false},
+		{"url encoded form", "application/x-www-form-urlencoded", "Signature=abc", "", false},
+		{"xml", "application/xml", "<Credentials><SessionToken>token</SessionToken><Expiration>1</Expiration></Credentials>",
+			"<Credentials><SessionToken>******</SessionToken><Expiration>1</Expiration></Credentials>", true},
+		{"xml truncated", "", "<Error><Signature>abc", "<Error><Signature>******", true},
+		{"binary", "application/octet-stream", "data", "data", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			body, ok := redactBody(c.contentType, []byte(c.body))
+			if ok != c.ok || string(body) != c.want {
+				t.Errorf("redactBody = %q, %t, want %q, %t", body, ok, c.want, c.ok)
+			}
+		})
+	}
+}
+
+func TestClientRedactsStringToSignAndWireDump(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HEADER_CONTENT_TYPE_CAML, "application/xml")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("<Error><Code>AccessDenied</Code><SessionToken>session-token-value</SessionToken></Error>"))
+	}))
+	defer server.Close()
+	logger := &captureLogger{}
+	client := newTestClient(t, server.URL, WithSignature(SignatureV2), WithLogger(logger), WithWireDump(1024),
+		WithRedaction([]string{HEADER_PREFIX_META + "secret"}, nil))
+
+	input := &PutObjectInput{}
+	input.Bucket, input.Key = "bucket", "key"
+	input.Metadata = map[string]string{"secret": "metadata-secret-value"}
+	input.Body = strings.NewReader("data")
+	if _, err := client.PutObject(input); err == nil {
+		t.Fatal("PutObject succeeded, want an error")
+	}
+
+	entry := logger.find("The v2 auth stringToSign")
+	if entry == nil {
+		t.Fatal("the string to sign is not logged through the client logger")
+	}
+	if strings.Contains(entry.msg, "metadata-secret-value") || !strings.Contains(entry.msg, redactedValue) {
+		t.Errorf("the string to sign is not redacted by the client redactor: %s", entry.msg)
+	}
+	if entry = logger.find("Wire dump of response"); entry == nil || strings.Contains(entry.msg, "session-token-value") {
+		t.Errorf("the xml body of the response is not redacted: %v", entry)
+	}
+}
@@ -0,0 +1,276 @@
+package wos
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// RetryContext describes a failed attempt and is passed to RetryPolicy to decide whether to retry.
+type RetryContext struct {
+	// Method is the HTTP method of the request.
+	Method string
+	// Attempt is the number of attempts made so far, starting from 1.
+	Attempt int
+	// Elapsed is the time spent since the first attempt was sent.
+	Elapsed time.Duration
+	// StatusCode is the HTTP status code of the response, 0 if no response was received.
+	StatusCode int
+	// Header holds the response headers, nil if no response was received.
+	Header http.Header
+	// ErrorCode is the error code returned by WOS in the response body.
+	ErrorCode string
+	// Err is the transport error, nil if a response was received.
+	Err error
+}
+
+// RetryPolicy defines interface with function: ShouldRetry
+//
+// ShouldRetry reports whether the failed attempt described by rc should be retried,
+// and how long to wait before the next attempt.
+type RetryPolicy interface {
+	ShouldRetry(rc *RetryContext) (delay time.Duration, retry bool)
+}
+
+// StandardRetryPolicy retries throttling, server-side and network errors
+// with exponential backoff and full jitter.
+type StandardRetryPolicy struct {
+	// BaseDelay is the backoff unit of the first retry.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between two attempts.
+	MaxDelay time.Duration
+	// MaxElapsedTime stops retrying once the total time of the request exceeds it, 0 means no limit.
+	MaxElapsedTime time.Duration
+	// RetryableStatusCodes is the allowlist of HTTP status codes that can be retried.
+	RetryableStatusCodes map[int]bool
+	// RetryableErrorCodes is the allowlist of WOS error codes that can be retried.
+	RetryableErrorCodes map[string]bool
+	// HonorRetryAfter makes the policy wait at least as long as the Retry-After response header asks.
+	HonorRetryAfter bool
+	// RetryableError filters the transport errors that can be retried, all of them are retried if nil.
+	// IsRetryableError can be used to retry only the transient ones.
+	RetryableError func(err error) bool
+}
+
+// NewStandardRetryPolicy creates a StandardRetryPolicy instance with the default settings
+func NewStandardRetryPolicy() *StandardRetryPolicy {
+	return &StandardRetryPolicy{
+		BaseDelay:      DEFAULT_RETRY_BASE_DELAY,
+		MaxDelay:       DEFAULT_RETRY_MAX_DELAY,
+		MaxElapsedTime: DEFAULT_RETRY_MAX_ELAPSED_TIME,
+		RetryableStatusCodes: map[int]bool{
+			http.StatusInternalServerError: true,
+			http.StatusBadGateway:          true,
+			http.StatusServiceUnavailable:  true,
+			http.StatusGatewayTimeout:      true,
+		},
+		RetryableErrorCodes: map[string]bool{
+			"SlowDown":           true,
+			"InternalError":      true,
+			"ServiceUnavailable": true,
+			"RequestTimeout":     true,
+		},
+		HonorRetryAfter: true,
+	}
+}
+
+// ShouldRetry implements RetryPolicy
+func (policy *StandardRetryPolicy) ShouldRetry(rc *RetryContext) (time.Duration, bool) {
+	if rc.Err != nil {
+		if policy.RetryableError != nil && !policy.RetryableError(rc.Err) {
+			return 0, false
+		}
+	} else if !policy.RetryableStatusCodes[rc.StatusCode] && !policy.RetryableErrorCodes[rc.ErrorCode] {
+		return 0, false
+	}
+
+	delay := FullJitterBackoff(policy.BaseDelay, policy.MaxDelay, rc.Attempt)
+	if policy.HonorRetryAfter {
+		if retryAfter, ok := ParseRetryAfter(rc.Header, time.Now()); ok && retryAfter > delay {
+			delay = retryAfter
+		}
+	}
+
+	if policy.MaxElapsedTime > 0 && rc.Elapsed+delay > policy.MaxElapsedTime {
+		return 0, false
+	}
+	return delay, true
+}
+
+// FullJitterBackoff returns a random delay in [0, min(maxDelay, baseDelay*2^(attempt-1))).
+func FullJitterBackoff(baseDelay, maxDelay time.Duration, attempt int) time.Duration {
+	if baseDelay <= 0 || attempt <= 0 {
+		return 0
+	}
+	backoff := baseDelay
+	for i := 1; i < attempt; i++ {
+		backoff *= 2
+		if maxDelay > 0 && backoff >= maxDelay {
+			break
+		}
+	}
+	if maxDelay > 0 && backoff > maxDelay {
+		backoff = maxDelay
+	}
+	return time.Duration(rand.Int63n(int64(backoff)))
+}
+
+// ParseRetryAfter parses the Retry-After header, which is either a number of seconds or an HTTP date.
+func ParseRetryAfter(header http.Header, now time.Time) (time.Duration, bool) {
+	if header == nil {
+		return 0, false
+	}
+	value := header.Get(HEADER_RETRY_AFTER_CAMEL)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if delay := t.Sub(now); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// IsRetryableError checks whether the transport error is transient: a timeout, a connection reset/refused,
+// a broken pipe or a connection closed by the server.
+func IsRetryableError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if isTimeoutError(err) {
+		return true
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE)
+}
+
+func isTimeoutError(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// RetryTokenBucket limits the retries of a WosClient, so that retries cannot amplify an outage.
+//
+// Every retry takes tokens from the bucket and no retry is made when the bucket is empty.
+// Successful requests put tokens back.
+type RetryTokenBucket struct {
+	lock        sync.Mutex
+	capacity    int
+	available   int
+	retryCost   int
+	timeoutCost int
+}
+
+// NewRetryTokenBucket creates a RetryTokenBucket instance, a retry costs retryCost tokens,
+// or timeoutCost tokens if the attempt timed out
+func NewRetryTokenBucket(capacity, retryCost, timeoutCost int) *RetryTokenBucket {
+	if retryCost <= 0 {
+		retryCost = DEFAULT_RETRY_COST
+	}
+	if timeoutCost <= 0 {
+		timeoutCost = retryCost
+	}
+	return &RetryTokenBucket{
+		capacity:    capacity,
+		available:   capacity,
+		retryCost:   retryCost,
+		timeoutCost: timeoutCost,
+	}
+}
+
+// Available returns the number of tokens left in the bucket
+func (bucket *RetryTokenBucket) Available() int {
+	bucket.lock.Lock()
+	defer bucket.lock.Unlock()
+	return bucket.available
+}
+
+func (bucket *RetryTokenBucket) acquire(err error) (int, bool) {
+	cost := bucket.retryCost
+	if isTimeoutError(err) {
+		cost = bucket.timeoutCost
+	}
+	bucket.lock.Lock()
+	defer bucket.lock.Unlock()
+	if bucket.available < cost {
+		return 0, false
+	}
+	bucket.available -= cost
+	return cost, true
+}
+
+func (bucket *RetryTokenBucket) release(tokens int) {
+	bucket.lock.Lock()
+	defer bucket.lock.Unlock()
+	bucket.available += tokens
+	if bucket.available > bucket.capacity {
+		bucket.available = bucket.capacity
+	}
+}
+
+func isContextDone(ctx context.Context) bool {
+	return ctx != nil && ctx.Err() != nil
+}
+
+// sleepWithContext waits for the delay, and returns early with the context error when the context is done.
+func sleepWithContext(ctx context.Context, delay time.Duration) error {
+	if ctx == nil {
+		time.Sleep(delay)
+		return nil
+	}
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (wosClient WosClient) shouldRetry(rc *RetryContext) (delay time.Duration, cost int, retry bool) {
+	if wosClient.conf.retryPolicy == nil {
+		return 0, 0, false
+	}
+	delay, retry = wosClient.conf.retryPolicy.ShouldRetry(rc)
+	if !retry {
+		wosClient.logf(LEVEL_DEBUG, "Retry is stopped by the retry policy after %d attempts in %v", rc.Attempt, rc.Elapsed)
+		return 0, 0, false
+	}
+	if bucket := wosClient.conf.retryTokenBucket; bucket != nil {
+		if cost, retry = bucket.acquire(rc.Err); !retry {
+			wosClient.logf(LEVEL_WARN, "Retry token bucket is exhausted, give up retrying")
+			return 0, 0, false
+		}
+	}
+	return delay, cost, true
+}
+
+func (wosClient WosClient) releaseRetryTokens(cost int) {
+	if bucket := wosClient.conf.retryTokenBucket; bucket != nil {
+		if cost <= 0 {
+			cost = DEFAULT_RETRY_SUCCESS_REFUND
+		}
+		bucket.release(cost)
+	}
+}
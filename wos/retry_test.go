@@ -0,0 +1,119 @@
+package wos
+
+import (
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestStandardRetryPolicyShouldRetry(t *testing.T) {
+	transient := NewStandardRetryPolicy()
+	transient.RetryableError = IsRetryableError
+
+	cases := []struct {
+		name   string
+		policy *StandardRetryPolicy
+		rc     RetryContext
+		retry  bool
+	}{
+		{"dns error", NewStandardRetryPolicy(), RetryContext{Attempt: 1, Err: &net.DNSError{Err: "no such host", Name: "wos.example.com", IsNotFound: true}}, true},
+		{"tls error", NewStandardRetryPolicy(), RetryContext{Attempt: 1, Err: x509.UnknownAuthorityError{}}, true},
+		{"unexpected eof", NewStandardRetryPolicy(), RetryContext{Attempt: 1, Err: io.ErrUnexpectedEOF}, true},
+		{"connection reset", NewStandardRetryPolicy(), RetryContext{Attempt: 1, Err: syscall.ECONNRESET}, true},
+		{"filtered dns error", transient, RetryContext{Attempt: 1, Err: &net.DNSError{Err: "no such host", IsNotFound: true}}, false},
+		{"filtered connection reset", transient, RetryContext{Attempt: 1, Err: syscall.ECONNRESET}, true},
+		{"service unavailable", NewStandardRetryPolicy(), RetryContext{Attempt: 1, StatusCode: http.StatusServiceUnavailable}, true},
+		{"slow down", NewStandardRetryPolicy(), RetryContext{Attempt: 1, StatusCode: http.StatusBadRequest, ErrorCode: "SlowDown"}, true},
+		{"forbidden", NewStandardRetryPolicy(), RetryContext{Attempt: 1, StatusCode: http.StatusForbidden, ErrorCode: "AccessDenied"}, false},
+		{"max elapsed time", NewStandardRetryPolicy(), RetryContext{Attempt: 1, StatusCode: http.StatusInternalServerError, Elapsed: DEFAULT_RETRY_MAX_ELAPSED_TIME}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rc := c.rc
+			if _, retry := c.policy.ShouldRetry(&rc); retry != c.retry {
+				t.Errorf("ShouldRetry = %t, want %t", retry, c.retry)
+			}
+		})
+	}
+}
+
+func TestStandardRetryPolicyRetryAfter(t *testing.T) {
+	policy := NewStandardRetryPolicy()
+	policy.BaseDelay, policy.MaxDelay = time.Millisecond, time.Millisecond
+	header := http.Header{HEADER_RETRY_AFTER_CAMEL: []string{"3"}}
+	delay, retry := policy.ShouldRetry(&RetryContext{Attempt: 1, StatusCode: http.StatusServiceUnavailable, Header: header})
+	if !retry || delay != 3*time.Second {
+		t.Errorf("ShouldRetry = %v, %t, want 3s, true", delay, retry)
+	}
+}
+
+func TestIsRetryableError(t *testing.T) {
+	cases := []struct {
+		err   error
+		retry bool
+	}{
+		{nil, false},
+		{io.EOF, true},
+		{syscall.ECONNREFUSED, true},
+		{&net.DNSError{Err: "i/o timeout", IsTimeout: true}, true},
+		{&net.DNSError{Err: "no such host", IsNotFound: true}, false},
+		{errors.New("malformed"), false},
+	}
+	for _, c := range cases {
+		if retry := IsRetryableError(c.err); retry != c.retry {
+			t.Errorf("IsRetryableError(%v) = %t, want %t", c.err, retry, c.retry)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		value string
+		delay time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{"-1", 0, false},
+		{now.Add(10 * time.Second).Format(http.TimeFormat), 10 * time.Second, true},
+		{now.Add(-time.Second).Format(http.TimeFormat), 0, true},
+		{"soon", 0, false},
+	}
+	for _, c := range cases {
+		delay, ok := ParseRetryAfter(http.Header{HEADER_RETRY_AFTER_CAMEL: []string{c.value}}, now)
+		if delay != c.delay || ok != c.ok {
+			t.Errorf("ParseRetryAfter(%q) = %v, %t, want %v, %t", c.value, delay, ok, c.delay, c.ok)
+		}
+	}
+}
+
+func TestFullJitterBackoff(t *testing.T) {
+	for attempt := 1; attempt <= 10; attempt++ {
+		if delay := FullJitterBackoff(10*time.Millisecond, 80*time.Millisecond, attempt); delay < 0 || delay >= 80*time.Millisecond {
+			t.Errorf("FullJitterBackoff attempt %d = %v, out of [0, 80ms)", attempt, delay)
+		}
+	}
+	if delay := FullJitterBackoff(0, time.Second, 1); delay != 0 {
+		t.Errorf("FullJitterBackoff with zero base = %v, want 0", delay)
+	}
+}
+
+func TestRetryTokenBucket(t *testing.T) {
+	bucket := NewRetryTokenBucket(10, 5, 8)
+	if _, ok := bucket.acquire(errors.New("reset")); !ok {
+		t.Fatal("the first retry is refused")
+	}
+	if _, ok := bucket.acquire(&net.DNSError{IsTimeout: true}); ok {
+		t.Fatal("a timeout retry is allowed with 5 tokens left")
+	}
+	bucket.release(100)
+	if available := bucket.Available(); available != 10 {
+		t.Errorf("Available = %d, want 10", available)
+	}
+}
@@ -0,0 +1,172 @@
+package wos
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// signingKeyCache holds the signing keys of the V4 and WOS signatures of the current day, it is shared by the copies
+// of the config.
+type signingKeyCache struct {
+	lock      sync.Mutex
+	shortDate string
+	keys      map[string]signingKeyEntry
+}
+
+type signingKeyEntry struct {
+	sk  string
+	key []byte
+}
+
+// get returns the signing key of the region and the day, the keys of the previous days are dropped.
+func (cache *signingKeyCache) get(sk, region, shortDate string, isWos bool) []byte {
+	if cache == nil {
+		return getSigningKey(sk, region, shortDate, isWos)
+	}
+	name := region
+	if isWos {
+		name = "wos/" + region
+	}
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	if cache.shortDate != shortDate {
+		cache.shortDate = shortDate
+		cache.keys = make(map[string]signingKeyEntry)
+	}
+	if entry, ok := cache.keys[name]; ok && entry.sk == sk {
+		return entry.key
+	}
+	key := getSigningKey(sk, region, shortDate, isWos)
+	cache.keys[name] = signingKeyEntry{sk: sk, key: key}
+	return key
+}
+
+// getCachedSignature returns the V4 or WOS signature with the signing key cached for the day.
+func (wosClient WosClient) getCachedSignature(stringToSign, sk, region, shortDate string, isWos bool) string {
+	return Hex(HmacSha256(wosClient.conf.signingKeys.get(sk, region, shortDate, isWos), []byte(stringToSign)))
+}
+
+// signedUrlCache is the LRU cache of the signed urls set by WithSignedUrlCache.
+type signedUrlCache struct {
+	capacity  int
+	freshness time.Duration
+
+	lock    sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type signedUrlEntry struct {
+	key       string
+	sk        string
+	signedAt  time.Time
+	expiresAt time.Time
+	output    CreateSignedUrlOutput
+}
+
+func newSignedUrlCache(capacity int, freshness time.Duration) *signedUrlCache {
+	return &signedUrlCache{capacity: capacity, freshness: freshness, entries: make(map[string]*list.Element, capacity), order: list.New()}
+}
+
+func copySignedUrlOutput(output CreateSignedUrlOutput) *CreateSignedUrlOutput {
+	headers := make(http.Header, len(output.ActualSignedRequestHeaders))
+	for key, values := range output.ActualSignedRequestHeaders {
+		headers[key] = append([]string(nil), values...)
+	}
+	return &CreateSignedUrlOutput{SignedUrl: output.SignedUrl, ActualSignedRequestHeaders: headers}
+}
+
+// get returns the url signed for the key within the freshness window and not expired at now.
+func (cache *signedUrlCache) get(key, sk string, now time.Time) *CreateSignedUrlOutput {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	element, ok := cache.entries[key]
+	if !ok {
+		return nil
+	}
+	entry := element.Value.(*signedUrlEntry)
+	if entry.sk != sk || now.Sub(entry.signedAt) >= cache.freshness || !now.Before(entry.expiresAt) {
+		cache.order.Remove(element)
+		delete(cache.entries, key)
+		return nil
+	}
+	cache.order.MoveToFront(element)
+	return copySignedUrlOutput(entry.output)
+}
+
+func (cache *signedUrlCache) put(key, sk string, signedAt time.Time, expires int, output *CreateSignedUrlOutput) {
+	entry := &signedUrlEntry{key: key, sk: sk, signedAt: signedAt, expiresAt: signedAt.Add(time.Duration(expires) * time.Second), output: *copySignedUrlOutput(*output)}
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	if element, ok := cache.entries[key]; ok {
+		element.Value = entry
+		cache.order.MoveToFront(element)
+		return
+	}
+	cache.entries[key] = cache.order.PushFront(entry)
+	for cache.order.Len() > cache.capacity {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*signedUrlEntry).key)
+	}
+}
+
+// getSignedUrlCacheKey returns the key of the signed url of the input, which includes the endpoint and the signature
+// of the client so that the clients and the calls with WithCallEndpoint do not share the urls.
+func (wosClient WosClient) getSignedUrlCacheKey(ak string, input *CreateSignedUrlInput, params map[string]string, headers map[string][]string) string {
+	conf := wosClient.conf
+	parts := make([]string, 0, 10+len(params)+len(headers))
+	parts = append(parts, string(conf.signature), conf.region, ak, conf.urlHolder.scheme, conf.urlHolder.host, IntToString(conf.urlHolder.port),
+		fmt.Sprintf("%t/%t", conf.pathStyle, conf.cname), string(input.Method), input.Bucket, input.Key, IntToString(input.Expires))
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		parts = append(parts, "?"+key+"="+params[key])
+	}
+	keys = keys[:0]
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		parts = append(parts, key+":"+strings.Join(headers[key], ","))
+	}
+	return strings.Join(parts, "\n")
+}
+
+// CreateSignedUrls creates the signed urls of the inputs in a batch, the outputs are in the order of the inputs.
+//
+// The credentials are read once and the urls are signed at the same time, the V4 and WOS signing key of the day is
+// derived once for the region. The urls are served from the cache set by WithSignedUrlCache if it is enabled.
+func (wosClient WosClient) CreateSignedUrls(inputs []CreateSignedUrlInput, extensions ...extensionOptions) (outputs []*CreateSignedUrlOutput, err error) {
+	if len(inputs) == 0 {
+		return nil, errors.New("CreateSignedUrlInputs is empty")
+	}
+	signer, err := wosClient.withExtensionConfigs(extensions)
+	if err != nil {
+		return nil, err
+	}
+	sh := signer.getSecurity()
+	signer.call.security = &sh
+	signer.call.signingTime = signer.now().Truncate(time.Second)
+
+	outputs = make([]*CreateSignedUrlOutput, 0, len(inputs))
+	for i := range inputs {
+		input := inputs[i]
+		output, _err := signer.CreateSignedUrl(&input)
+		if _err != nil {
+			return nil, fmt.Errorf("Failed to create the signed url %d with error: %v", i, _err)
+		}
+		outputs = append(outputs, output)
+	}
+	return
+}
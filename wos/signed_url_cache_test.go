@@ -0,0 +1,146 @@
+package wos
+
+import (
+	"bytes"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSignedUrlCache(t *testing.T) {
+	signedAt := time.Unix(1700000000, 0)
+	cases := []struct {
+		name    string
+		expires int
+		sk      string
+		now     time.Time
+		hit     bool
+	}{
+		{"fresh", 300, "sk", signedAt.Add(30 * time.Second), true},
+		{"stale", 300, "sk", signedAt.Add(time.Minute), false},
+		// the url expires within the freshness
+		{"expired", 30, "sk", signedAt.Add(30 * time.Second), false},
+		{"rotated sk", 300, "new-sk", signedAt, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cache := newSignedUrlCache(2, time.Minute)
+			output := &CreateSignedUrlOutput{SignedUrl: "url", ActualSignedRequestHeaders: http.Header{"Host": {"wos.example.com"}}}
+			cache.put("key", "sk", signedAt, c.expires, output)
+			got := cache.get("key", c.sk, c.now)
+			if (got != nil) != c.hit {
+				t.Fatalf("get = %v, want hit %t", got, c.hit)
+			}
+			if !c.hit {
+				// the missed url is dropped
+				if got = cache.get("key", "sk", signedAt); got != nil {
+					t.Errorf("get after a miss = %v", got)
+				}
+				return
+			}
+			if got.SignedUrl != "url" || got.ActualSignedRequestHeaders.Get("Host") != "wos.example.com" {
+				t.Errorf("get = %v", got)
+			}
+		})
+	}
+}
+
+func TestSignedUrlCacheCopies(t *testing.T) {
+	signedAt := time.Unix(1700000000, 0)
+	cache := newSignedUrlCache(2, time.Minute)
+	output := &CreateSignedUrlOutput{SignedUrl: "url", ActualSignedRequestHeaders: http.Header{"Host": {"wos.example.com"}}}
+	cache.put("key", "sk", signedAt, 300, output)
+	output.ActualSignedRequestHeaders["Host"][0] = "put"
+
+	got := cache.get("key", "sk", signedAt)
+	got.ActualSignedRequestHeaders["Host"][0] = "get"
+	got.ActualSignedRequestHeaders.Set("X-Extra", "get")
+	if got = cache.get("key", "sk", signedAt); got.ActualSignedRequestHeaders.Get("Host") != "wos.example.com" || len(got.ActualSignedRequestHeaders) != 1 {
+		t.Errorf("cached headers = %v", got.ActualSignedRequestHeaders)
+	}
+}
+
+func TestSignedUrlCacheEviction(t *testing.T) {
+	signedAt := time.Unix(1700000000, 0)
+	cache := newSignedUrlCache(2, time.Minute)
+	for _, key := range []string{"a", "b"} {
+		cache.put(key, "sk", signedAt, 300, &CreateSignedUrlOutput{SignedUrl: key})
+	}
+	// a is the most recently used one
+	cache.get("a", "sk", signedAt)
+	cache.put("c", "sk", signedAt, 300, &CreateSignedUrlOutput{SignedUrl: "c"})
+	for key, cached := range map[string]bool{"a": true, "b": false, "c": true} {
+		if got := cache.get(key, "sk", signedAt); (got != nil) != cached {
+			t.Errorf("get(%s) = %v, want cached %t", key, got, cached)
+		}
+	}
+}
+
+func TestSigningKeyCache(t *testing.T) {
+	cache := &signingKeyCache{}
+	cases := []struct {
+		name      string
+		sk        string
+		region    string
+		shortDate string
+		isWos     bool
+	}{
+		{"v4", "sk", "region", "20261019", false},
+		{"wos", "sk", "region", "20261019", true},
+		{"other region", "sk", "other", "20261019", false},
+		{"rotated sk", "new-sk", "region", "20261019", false},
+		{"next day", "new-sk", "region", "20261020", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			want := getSigningKey(c.sk, c.region, c.shortDate, c.isWos)
+			for i := 0; i < 2; i++ {
+				if got := cache.get(c.sk, c.region, c.shortDate, c.isWos); !bytes.Equal(got, want) {
+					t.Fatalf("get = %x, want %x", got, want)
+				}
+			}
+		})
+	}
+	if len(cache.keys) != 1 {
+		t.Errorf("%d keys are cached after the day changed, want 1", len(cache.keys))
+	}
+}
+
+func TestCreateSignedUrlCache(t *testing.T) {
+	client := newTestClient(t, "http://wos.example.com", WithSignedUrlCache(10, time.Minute))
+	input := CreateSignedUrlInput{Method: HttpMethodGet, Bucket: "bucket", Key: "key", Expires: 300}
+	sign := func() string {
+		output, err := client.CreateSignedUrl(&input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return output.SignedUrl
+	}
+	advance := func(offset time.Duration) {
+		atomic.AddInt64(&client.conf.clockSkew.offset, int64(offset))
+	}
+
+	first := sign()
+	advance(30 * time.Second)
+	if got := sign(); got != first {
+		t.Errorf("url within the freshness %s, want %s", got, first)
+	}
+	outputs, err := client.CreateSignedUrls([]CreateSignedUrlInput{input, {Method: HttpMethodPut, Bucket: "bucket", Key: "key", Expires: 300}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outputs) != 2 || outputs[0].SignedUrl != first || outputs[1].SignedUrl == first {
+		t.Errorf("CreateSignedUrls = %v", outputs)
+	}
+
+	advance(time.Minute)
+	second := sign()
+	if second == first {
+		t.Error("stale url is returned")
+	}
+	client.Refresh("ak", "new-sk", "")
+	if got := sign(); got == second {
+		t.Error("url signed with the previous sk is returned")
+	}
+}
@@ -0,0 +1,171 @@
+package wos
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"strconv"
+)
+
+const (
+	streamingChunkSignatureLength = 64
+	streamingChunkSignatureFormat = "%x;chunk-signature=%s\r\n"
+	streamingPayloadSuffix        = "-PAYLOAD"
+	streamingTrailerSuffix        = "-TRAILER"
+	streamingTrailerSignature     = "trailer-signature"
+)
+
+// streamingSigner signs the chunks of a body sent in the aws-chunked encoding, the signature of each chunk
+// chains the previous one starting from the signature of the request, and the CRC32 of the body is sent
+// in a signed trailer.
+type streamingSigner struct {
+	isWos     bool
+	chunkSize int
+	// decodedLength is the length of the body before encoding, -1 if unknown.
+	decodedLength int64
+
+	signingKey []byte
+	longDate   string
+	scope      string
+	previous   string
+}
+
+func newStreamingSigner(isWos bool, chunkSize int, decodedLength int64) *streamingSigner {
+	return &streamingSigner{isWos: isWos, chunkSize: chunkSize, decodedLength: decodedLength}
+}
+
+func (s *streamingSigner) headerPrefix() string {
+	if s.isWos {
+		return HEADER_PREFIX_WOS
+	}
+	return HEADER_PREFIX
+}
+
+func (s *streamingSigner) algorithm() string {
+	if s.isWos {
+		return V4_WOS_HASH_PREFIX
+	}
+	return V4_HASH_PREFIX
+}
+
+func (s *streamingSigner) payload() string {
+	if s.isWos {
+		return STREAMING_PAYLOAD_TRAILER_WOS
+	}
+	return STREAMING_PAYLOAD_TRAILER
+}
+
+// prepareHeaders sets the headers of the aws-chunked encoding before the request is signed.
+func (s *streamingSigner) prepareHeaders(headers map[string][]string) {
+	contentEncoding := AWS_CHUNKED_ENCODING
+	if value, ok := headers[HEADER_CONTENT_ENCODING_CAMEL]; ok && len(value) > 0 && value[0] != "" {
+		contentEncoding += "," + value[0]
+	}
+	headers[HEADER_CONTENT_ENCODING_CAMEL] = []string{contentEncoding}
+	setHeaders(headers, HEADER_TRAILER, []string{s.headerPrefix() + HEADER_CHECKSUM_CRC32}, s.isWos)
+	if s.decodedLength >= 0 {
+		setHeaders(headers, HEADER_DECODED_CONTENT_LENGTH, []string{Int64ToString(s.decodedLength)}, s.isWos)
+		headers[HEADER_CONTENT_LENGTH_CAMEL] = []string{Int64ToString(s.encodedLength())}
+	} else {
+		delete(headers, HEADER_CONTENT_LENGTH_CAMEL)
+	}
+}
+
+// seed sets the signature of the request which the signature of the first chunk chains.
+func (s *streamingSigner) seed(signature, sk, region string, headers map[string][]string) {
+	t := getV4Date(headers, s.isWos)
+	shortDate := t.Format(SHORT_DATE_FORMAT)
+	s.signingKey = getSigningKey(sk, region, shortDate, s.isWos)
+	s.longDate = t.Format(LONG_DATE_FORMAT)
+	s.scope = getScope(region, shortDate, s.isWos)
+	s.previous = signature
+}
+
+func (s *streamingSigner) sign(suffix string, hash string) string {
+	stringToSign := s.algorithm() + suffix + "\n" + s.longDate + "\n" + s.scope + "\n" + s.previous + "\n"
+	if suffix == streamingPayloadSuffix {
+		stringToSign += EMPTY_CONTENT_SHA256 + "\n"
+	}
+	stringToSign += hash
+	s.previous = Hex(HmacSha256(s.signingKey, []byte(stringToSign)))
+	return s.previous
+}
+
+func (s *streamingSigner) chunkLength(size int) int64 {
+	return int64(len(strconv.FormatInt(int64(size), 16))+len(";chunk-signature=")+streamingChunkSignatureLength+2) + int64(size) + 2
+}
+
+// encodedLength returns the length of the encoded body, the decodedLength must be known.
+func (s *streamingSigner) encodedLength() int64 {
+	length := s.decodedLength / int64(s.chunkSize) * s.chunkLength(s.chunkSize)
+	if remain := int(s.decodedLength % int64(s.chunkSize)); remain > 0 {
+		length += s.chunkLength(remain)
+	}
+	// the final chunk has no data and is not followed by CRLF
+	length += s.chunkLength(0) - 2
+	checksum := base64.StdEncoding.EncodedLen(crc32.Size)
+	length += int64(len(s.headerPrefix()+HEADER_CHECKSUM_CRC32) + 1 + checksum + 2)
+	length += int64(len(s.headerPrefix()+streamingTrailerSignature) + 1 + streamingChunkSignatureLength + 2)
+	return length + 2
+}
+
+// chunkedReader encodes the body in the aws-chunked encoding with the signatures of the streamingSigner.
+type chunkedReader struct {
+	signer *streamingSigner
+	reader io.Reader
+	chunk  []byte
+	out    bytes.Buffer
+	crc    hash.Hash32
+	done   bool
+}
+
+func newChunkedReader(signer *streamingSigner, reader io.Reader) *chunkedReader {
+	return &chunkedReader{signer: signer, reader: reader, chunk: make([]byte, signer.chunkSize), crc: crc32.NewIEEE()}
+}
+
+func (r *chunkedReader) Read(p []byte) (int, error) {
+	for r.out.Len() == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.fill(); err != nil {
+			return 0, err
+		}
+	}
+	return r.out.Read(p)
+}
+
+func (r *chunkedReader) fill() error {
+	n, err := io.ReadFull(r.reader, r.chunk)
+	if n > 0 {
+		// the Write of a hash never returns an error
+		r.crc.Write(r.chunk[:n])
+		r.writeChunk(r.chunk[:n])
+		r.out.WriteString("\r\n")
+	}
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		r.writeChunk(nil)
+		r.writeTrailer()
+		r.done = true
+		return nil
+	}
+	return err
+}
+
+func (r *chunkedReader) writeChunk(data []byte) {
+	signature := r.signer.sign(streamingPayloadSuffix, HexSha256(data))
+	r.out.WriteString(fmt.Sprintf(streamingChunkSignatureFormat, len(data), signature))
+	r.out.Write(data)
+}
+
+func (r *chunkedReader) writeTrailer() {
+	prefix := r.signer.headerPrefix()
+	trailer := prefix + HEADER_CHECKSUM_CRC32 + ":" + base64.StdEncoding.EncodeToString(r.crc.Sum(nil))
+	signature := r.signer.sign(streamingTrailerSuffix, HexSha256([]byte(trailer+"\n")))
+	r.out.WriteString(trailer + "\r\n")
+	r.out.WriteString(prefix + streamingTrailerSignature + ":" + signature + "\r\n")
+	r.out.WriteString("\r\n")
+}
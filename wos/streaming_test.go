@@ -0,0 +1,194 @@
+package wos
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// decodeChunked decodes an aws-chunked body and verifies the chain of its signatures from the seed signature.
+func decodeChunked(body []byte, isWos bool, seed string, signingKey []byte, longDate, scope string) ([]byte, error) {
+	algorithm, prefix := V4_HASH_PREFIX, HEADER_PREFIX
+	if isWos {
+		algorithm, prefix = V4_WOS_HASH_PREFIX, HEADER_PREFIX_WOS
+	}
+	previous := seed
+	checkSignature := func(suffix, hash, signature string) error {
+		stringToSign := algorithm + suffix + "\n" + longDate + "\n" + scope + "\n" + previous + "\n"
+		if suffix == streamingPayloadSuffix {
+			stringToSign += EMPTY_CONTENT_SHA256 + "\n"
+		}
+		previous = Hex(HmacSha256(signingKey, []byte(stringToSign+hash)))
+		if signature != previous {
+			return fmt.Errorf("signature %s of the %s chunk, want %s", signature, suffix, previous)
+		}
+		return nil
+	}
+
+	reader := bufio.NewReader(bytes.NewReader(body))
+	var data []byte
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("chunk header: %v", err)
+		}
+		parts := strings.SplitN(strings.TrimSuffix(line, "\r\n"), ";chunk-signature=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("malformed chunk header %q", line)
+		}
+		size, err := strconv.ParseInt(parts[0], 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed chunk size %q", parts[0])
+		}
+		chunk := make([]byte, size)
+		if _, err = io.ReadFull(reader, chunk); err != nil {
+			return nil, fmt.Errorf("chunk data: %v", err)
+		}
+		if err = checkSignature(streamingPayloadSuffix, HexSha256(chunk), parts[1]); err != nil {
+			return nil, err
+		}
+		data = append(data, chunk...)
+		if size == 0 {
+			break
+		}
+		if crlf, _ := reader.ReadString('\n'); crlf != "\r\n" {
+			return nil, fmt.Errorf("chunk is followed by %q", crlf)
+		}
+	}
+
+	checksum, _ := reader.ReadString('\n')
+	checksum = strings.TrimSuffix(checksum, "\r\n")
+	if want := prefix + HEADER_CHECKSUM_CRC32 + ":" + base64.StdEncoding.EncodeToString(crc32IEEE(data)); checksum != want {
+		return nil, fmt.Errorf("trailer %q, want %q", checksum, want)
+	}
+	signature, _ := reader.ReadString('\n')
+	signature = strings.TrimSuffix(signature, "\r\n")
+	if !strings.HasPrefix(signature, prefix+streamingTrailerSignature+":") {
+		return nil, fmt.Errorf("trailer signature %q", signature)
+	}
+	if err := checkSignature(streamingTrailerSuffix, HexSha256([]byte(checksum+"\n")), strings.TrimPrefix(signature, prefix+streamingTrailerSignature+":")); err != nil {
+		return nil, err
+	}
+	if rest, _ := ioutil.ReadAll(reader); string(rest) != "\r\n" {
+		return nil, fmt.Errorf("body ends with %q", rest)
+	}
+	return data, nil
+}
+
+func crc32IEEE(data []byte) []byte {
+	hash := crc32.NewIEEE()
+	hash.Write(data)
+	return hash.Sum(nil)
+}
+
+func TestChunkedReader(t *testing.T) {
+	const chunkSize = 16
+	for _, isWos := range []bool{false, true} {
+		for _, size := range []int{0, 1, chunkSize - 1, chunkSize, 2*chunkSize + 3} {
+			t.Run(strconv.FormatBool(isWos)+"/"+strconv.Itoa(size), func(t *testing.T) {
+				data := bytes.Repeat([]byte("0123456789abcdef"), 4)[:size]
+				dateHeader := HEADER_DATE_AMZ
+				if isWos {
+					dateHeader = HEADER_DATE_WOS
+				}
+				headers := map[string][]string{dateHeader: {"20261019T120000Z"}, HEADER_CONTENT_LENGTH_CAMEL: {strconv.Itoa(size)}}
+				signer := newStreamingSigner(isWos, chunkSize, int64(size))
+				signer.prepareHeaders(headers)
+				signer.seed("seed", "sk", "region", headers)
+				signingKey, scope := getSigningKey("sk", "region", "20261019", isWos), getScope("region", "20261019", isWos)
+
+				encoded, err := ioutil.ReadAll(newChunkedReader(signer, bytes.NewReader(data)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := headers[HEADER_CONTENT_LENGTH_CAMEL][0]; got != strconv.Itoa(len(encoded)) {
+					t.Errorf("Content-Length %s, encoded %d bytes", got, len(encoded))
+				}
+				if !strings.HasPrefix(headers[HEADER_CONTENT_ENCODING_CAMEL][0], AWS_CHUNKED_ENCODING) {
+					t.Errorf("Content-Encoding %v", headers[HEADER_CONTENT_ENCODING_CAMEL])
+				}
+				if decoded, err := decodeChunked(encoded, isWos, "seed", signingKey, "20261019T120000Z", scope); err != nil || !bytes.Equal(decoded, data) {
+					t.Errorf("decoded %q with error %v, want %q", decoded, err, data)
+				}
+			})
+		}
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read(p []byte) (int, error) {
+	return 0, errors.New("broken body")
+}
+
+func TestChunkedReaderError(t *testing.T) {
+	signer := newStreamingSigner(false, 16, -1)
+	signer.seed("seed", "sk", "region", map[string][]string{HEADER_DATE_AMZ: {"20261019T120000Z"}})
+	if _, err := ioutil.ReadAll(newChunkedReader(signer, failingReader{})); err == nil || err.Error() != "broken body" {
+		t.Errorf("error = %v, want broken body", err)
+	}
+}
+
+func TestStreamingSigning(t *testing.T) {
+	data := bytes.Repeat([]byte("a"), MIN_STREAMING_CHUNK_SIZE*2+5)
+	cases := []struct {
+		name string
+		// send sends the body of an unknown length
+		send func(client *WosClient, body io.Reader) error
+	}{
+		{"PutObject", func(client *WosClient, body io.Reader) error {
+			input := &PutObjectInput{}
+			input.Bucket, input.Key, input.Body = "bucket", "key", body
+			_, err := client.PutObject(input)
+			return err
+		}},
+		{"UploadPart", func(client *WosClient, body io.Reader) error {
+			input := &UploadPartInput{Bucket: "bucket", Key: "key", PartNumber: 1, UploadId: "upload-id", Body: body}
+			_, err := client.UploadPart(input)
+			return err
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			verifier := NewVerifier(testCredentials, "")
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if _, err := verifier.Verify(r); err != nil {
+					t.Errorf("Verify error = %v", err)
+				}
+				if got := r.Header.Get(HEADER_CONTENT_SHA256_WOS); got != STREAMING_PAYLOAD_TRAILER_WOS {
+					t.Errorf("%s = %q, want %q", HEADER_CONTENT_SHA256_WOS, got, STREAMING_PAYLOAD_TRAILER_WOS)
+				}
+				// the length of the body is unknown
+				if r.Header.Get(HEADER_PREFIX_WOS+HEADER_DECODED_CONTENT_LENGTH) != "" || r.Header.Get(HEADER_PREFIX_WOS+HEADER_TRAILER) == "" {
+					t.Errorf("headers %v", r.Header)
+				}
+				authorization := r.Header.Get(HEADER_AUTH_CAMEL)
+				seed := authorization[strings.LastIndex(authorization, "Signature=")+len("Signature="):]
+				credential := strings.Split(strings.SplitN(authorization[strings.Index(authorization, "Credential=")+len("Credential="):], ",", 2)[0], "/")
+				longDate := r.Header.Get(HEADER_DATE_CAMEL)
+				signingKey, scope := getSigningKey("sk", credential[2], credential[1], true), getScope(credential[2], credential[1], true)
+				body, _ := ioutil.ReadAll(r.Body)
+				if decoded, err := decodeChunked(body, true, seed, signingKey, longDate, scope); err != nil || !bytes.Equal(decoded, data) {
+					t.Errorf("decoded %d bytes with error %v, want %d", len(decoded), err, len(data))
+				}
+				w.Header().Set("ETag", `"etag"`)
+			}))
+			defer server.Close()
+			client := newTestClient(t, server.URL, WithSignature(SignatureWos), WithStreamingSigning(MIN_STREAMING_CHUNK_SIZE))
+
+			if err := c.send(client, ioutil.NopCloser(bytes.NewReader(data))); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -1,20 +1,28 @@
 package wos
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 )
 
-// CreateSignedUrl creates signed url with the specified CreateSignedUrlInput, and returns the CreateSignedUrlOutput and error
-func (wosClient WosClient) CreateSignedUrl(input *CreateSignedUrlInput) (output *CreateSignedUrlOutput, err error) {
+// CreateSignedUrl creates signed url with the specified CreateSignedUrlInput, and returns the CreateSignedUrlOutput and error.
+// The url is created with the endpoint of the client, use WithCallEndpoint to create it with another endpoint.
+func (wosClient WosClient) CreateSignedUrl(input *CreateSignedUrlInput, extensions ...extensionOptions) (output *CreateSignedUrlOutput, err error) {
 	if input == nil {
 		return nil, errors.New("CreateSignedUrlInput is nil")
 	}
+	wosClient, err = wosClient.withExtensionConfigs(extensions)
+	if err != nil {
+		return nil, err
+	}
 
 	params := make(map[string]string, len(input.QueryParams))
 	for key, value := range input.QueryParams {
@@ -34,6 +42,20 @@ func (wosClient WosClient) CreateSignedUrl(input *CreateSignedUrlInput) (output
 		input.Expires = 300
 	}
 
+	cache := wosClient.conf.signedUrlCache
+	var cacheKey string
+	var sh securityHolder
+	if cache != nil {
+		// the credentials are pinned for the call so that the url is signed with the ones of the key
+		sh = wosClient.getSecurity()
+		wosClient.call.security = &sh
+		cacheKey = wosClient.getSignedUrlCacheKey(sh.ak, input, params, headers)
+		if output = cache.get(cacheKey, sh.sk, wosClient.now()); output != nil {
+			return
+		}
+		wosClient.call.signingTime = wosClient.now().Truncate(time.Second)
+	}
+
 	requestURL, err := wosClient.doAuthTemporary(string(input.Method), input.Bucket, input.Key, params, headers, int64(input.Expires))
 	if err != nil {
 		return nil, err
@@ -43,6 +65,9 @@ func (wosClient WosClient) CreateSignedUrl(input *CreateSignedUrlInput) (output
 		SignedUrl:                  requestURL,
 		ActualSignedRequestHeaders: headers,
 	}
+	if cache != nil {
+		cache.put(cacheKey, sh.sk, wosClient.call.signingTime, input.Expires, output)
+	}
 	return
 }
 
@@ -58,7 +83,7 @@ func (wosClient WosClient) CreateBrowserBasedSignature(input *CreateBrowserBased
 		params[key] = value
 	}
 
-	date := time.Now().UTC()
+	date := wosClient.now()
 	shortDate := date.Format(SHORT_DATE_FORMAT)
 	longDate := date.Format(LONG_DATE_FORMAT)
 	sh := wosClient.getSecurity()
@@ -76,42 +101,39 @@ func (wosClient WosClient) CreateBrowserBasedSignature(input *CreateBrowserBased
 		params[PARAM_DATE_AMZ_CAMEL] = longDate
 	}
 
+	conditions := make([]interface{}, 0, len(params)+2)
 	matchAnyBucket := true
 	matchAnyKey := true
-	count := 5
 	if bucket := strings.TrimSpace(input.Bucket); bucket != "" {
 		params["bucket"] = bucket
 		matchAnyBucket = false
-		count--
 	}
 
 	if key := strings.TrimSpace(input.Key); key != "" {
 		params["key"] = key
 		matchAnyKey = false
-		count--
 	}
 
-	originPolicySlice := make([]string, 0, len(params)+count)
-	originPolicySlice = append(originPolicySlice, fmt.Sprintf("{\"expiration\":\"%s\",", expiration))
-	originPolicySlice = append(originPolicySlice, "\"conditions\":[")
 	for key, value := range params {
 		if _key := strings.TrimSpace(strings.ToLower(key)); _key != "" {
-			originPolicySlice = append(originPolicySlice, fmt.Sprintf("{\"%s\":\"%s\"},", _key, value))
+			conditions = append(conditions, map[string]string{_key: value})
 		}
 	}
 
 	if matchAnyBucket {
-		originPolicySlice = append(originPolicySlice, "[\"starts-with\", \"$bucket\", \"\"],")
+		conditions = append(conditions, []string{PostPolicyConditionStartsWith, "$bucket", ""})
 	}
 
 	if matchAnyKey {
-		originPolicySlice = append(originPolicySlice, "[\"starts-with\", \"$key\", \"\"],")
+		conditions = append(conditions, []string{PostPolicyConditionStartsWith, "$key", ""})
 	}
 
-	originPolicySlice = append(originPolicySlice, "]}")
-
-	originPolicy := strings.Join(originPolicySlice, "")
-	policy := Base64Encode([]byte(originPolicy))
+	_originPolicy, err := json.Marshal(postPolicyDocument{Expiration: expiration, Conditions: conditions})
+	if err != nil {
+		return nil, err
+	}
+	originPolicy := string(_originPolicy)
+	policy := Base64Encode(_originPolicy)
 	var signature string
 
 	if wosClient.conf.signature == SignatureV2 {
@@ -132,9 +154,9 @@ func (wosClient WosClient) CreateBrowserBasedSignature(input *CreateBrowserBased
 }
 
 // ListBucketsWithSignedUrl lists buckets with the specified signed url and signed request headers
-func (wosClient WosClient) ListBucketsWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header) (output *ListBucketsOutput, err error) {
+func (wosClient WosClient) ListBucketsWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header, extensions ...extensionOptions) (output *ListBucketsOutput, err error) {
 	output = &ListBucketsOutput{}
-	err = wosClient.doHTTPWithSignedURL("ListBuckets", HTTP_GET, signedUrl, actualSignedRequestHeaders, nil, output, true)
+	err = wosClient.doHTTPWithSignedURL("ListBuckets", HTTP_GET, signedUrl, actualSignedRequestHeaders, nil, output, true, extensions)
 	if err != nil {
 		output = nil
 	}
@@ -142,9 +164,9 @@ func (wosClient WosClient) ListBucketsWithSignedUrl(signedUrl string, actualSign
 }
 
 // ListObjectsWithSignedUrl lists objects in a bucket with the specified signed url and signed request headers
-func (wosClient WosClient) ListObjectsWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header) (output *ListObjectsOutput, err error) {
+func (wosClient WosClient) ListObjectsWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header, extensions ...extensionOptions) (output *ListObjectsOutput, err error) {
 	output = &ListObjectsOutput{}
-	err = wosClient.doHTTPWithSignedURL("ListObjects", HTTP_GET, signedUrl, actualSignedRequestHeaders, nil, output, true)
+	err = wosClient.doHTTPWithSignedURL("ListObjects", HTTP_GET, signedUrl, actualSignedRequestHeaders, nil, output, true, extensions)
 	if err != nil {
 		output = nil
 	}
@@ -153,15 +175,15 @@ func (wosClient WosClient) ListObjectsWithSignedUrl(signedUrl string, actualSign
 
 // ListMultipartUploadsWithSignedUrl lists the multipart uploads that are initialized but not combined or aborted in a
 // specified bucket with the specified signed url and signed request headers
-func (wosClient WosClient) ListMultipartUploadsWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header) (output *ListMultipartUploadsOutput, err error) {
+func (wosClient WosClient) ListMultipartUploadsWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header, extensions ...extensionOptions) (output *ListMultipartUploadsOutput, err error) {
 	output = &ListMultipartUploadsOutput{}
-	err = wosClient.doHTTPWithSignedURL("ListMultipartUploads", HTTP_GET, signedUrl, actualSignedRequestHeaders, nil, output, true)
+	err = wosClient.doHTTPWithSignedURL("ListMultipartUploads", HTTP_GET, signedUrl, actualSignedRequestHeaders, nil, output, true, extensions)
 	if err != nil {
 		output = nil
 	} else if output.EncodingType == "url" {
 		err = decodeListMultipartUploadsOutput(output)
 		if err != nil {
-			doLog(LEVEL_ERROR, "Failed to get ListMultipartUploadsOutput with error: %v.", err)
+			wosClient.logf(LEVEL_ERROR, "Failed to get ListMultipartUploadsOutput with error: %v.", err)
 			output = nil
 		}
 	}
@@ -169,9 +191,9 @@ func (wosClient WosClient) ListMultipartUploadsWithSignedUrl(signedUrl string, a
 }
 
 // HeadBucketWithSignedUrl checks whether a bucket exists with the specified signed url and signed request headers
-func (wosClient WosClient) HeadBucketWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header) (output *BaseModel, err error) {
+func (wosClient WosClient) HeadBucketWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header, extensions ...extensionOptions) (output *BaseModel, err error) {
 	output = &BaseModel{}
-	err = wosClient.doHTTPWithSignedURL("HeadBucket", HTTP_HEAD, signedUrl, actualSignedRequestHeaders, nil, output, true)
+	err = wosClient.doHTTPWithSignedURL("HeadBucket", HTTP_HEAD, signedUrl, actualSignedRequestHeaders, nil, output, true, extensions)
 	if err != nil {
 		output = nil
 	}
@@ -179,9 +201,9 @@ func (wosClient WosClient) HeadBucketWithSignedUrl(signedUrl string, actualSigne
 }
 
 // HeadObjectWithSignedUrl checks whether an object exists with the specified signed url and signed request headers
-func (wosClient WosClient) HeadObjectWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header) (output *BaseModel, err error) {
+func (wosClient WosClient) HeadObjectWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header, extensions ...extensionOptions) (output *BaseModel, err error) {
 	output = &BaseModel{}
-	err = wosClient.doHTTPWithSignedURL("HeadObject", HTTP_HEAD, signedUrl, actualSignedRequestHeaders, nil, output, true)
+	err = wosClient.doHTTPWithSignedURL("HeadObject", HTTP_HEAD, signedUrl, actualSignedRequestHeaders, nil, output, true, extensions)
 	if err != nil {
 		output = nil
 	}
@@ -189,9 +211,9 @@ func (wosClient WosClient) HeadObjectWithSignedUrl(signedUrl string, actualSigne
 }
 
 // SetBucketLifecycleConfigurationWithSignedUrl sets lifecycle rules for a bucket with the specified signed url and signed request headers and data
-func (wosClient WosClient) SetBucketLifecycleConfigurationWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header, data io.Reader) (output *BaseModel, err error) {
+func (wosClient WosClient) SetBucketLifecycleConfigurationWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header, data io.Reader, extensions ...extensionOptions) (output *BaseModel, err error) {
 	output = &BaseModel{}
-	err = wosClient.doHTTPWithSignedURL("SetBucketLifecycleConfiguration", HTTP_PUT, signedUrl, actualSignedRequestHeaders, data, output, true)
+	err = wosClient.doHTTPWithSignedURL("SetBucketLifecycleConfiguration", HTTP_PUT, signedUrl, actualSignedRequestHeaders, data, output, true, extensions)
 	if err != nil {
 		output = nil
 	}
@@ -199,9 +221,9 @@ func (wosClient WosClient) SetBucketLifecycleConfigurationWithSignedUrl(signedUr
 }
 
 // GetBucketLifecycleConfigurationWithSignedUrl gets lifecycle rules of a bucket with the specified signed url and signed request headers
-func (wosClient WosClient) GetBucketLifecycleConfigurationWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header) (output *GetBucketLifecycleConfigurationOutput, err error) {
+func (wosClient WosClient) GetBucketLifecycleConfigurationWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header, extensions ...extensionOptions) (output *GetBucketLifecycleConfigurationOutput, err error) {
 	output = &GetBucketLifecycleConfigurationOutput{}
-	err = wosClient.doHTTPWithSignedURL("GetBucketLifecycleConfiguration", HTTP_GET, signedUrl, actualSignedRequestHeaders, nil, output, true)
+	err = wosClient.doHTTPWithSignedURL("GetBucketLifecycleConfiguration", HTTP_GET, signedUrl, actualSignedRequestHeaders, nil, output, true, extensions)
 	if err != nil {
 		output = nil
 	}
@@ -209,9 +231,9 @@ func (wosClient WosClient) GetBucketLifecycleConfigurationWithSignedUrl(signedUr
 }
 
 // DeleteBucketLifecycleConfigurationWithSignedUrl deletes lifecycle rules of a bucket with the specified signed url and signed request headers
-func (wosClient WosClient) DeleteBucketLifecycleConfigurationWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header) (output *BaseModel, err error) {
+func (wosClient WosClient) DeleteBucketLifecycleConfigurationWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header, extensions ...extensionOptions) (output *BaseModel, err error) {
 	output = &BaseModel{}
-	err = wosClient.doHTTPWithSignedURL("DeleteBucketLifecycleConfiguration", HTTP_DELETE, signedUrl, actualSignedRequestHeaders, nil, output, true)
+	err = wosClient.doHTTPWithSignedURL("DeleteBucketLifecycleConfiguration", HTTP_DELETE, signedUrl, actualSignedRequestHeaders, nil, output, true, extensions)
 	if err != nil {
 		output = nil
 	}
@@ -219,9 +241,9 @@ func (wosClient WosClient) DeleteBucketLifecycleConfigurationWithSignedUrl(signe
 }
 
 // DeleteObjectWithSignedUrl deletes an object with the specified signed url and signed request headers
-func (wosClient WosClient) DeleteObjectWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header) (output *DeleteObjectOutput, err error) {
+func (wosClient WosClient) DeleteObjectWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header, extensions ...extensionOptions) (output *DeleteObjectOutput, err error) {
 	output = &DeleteObjectOutput{}
-	err = wosClient.doHTTPWithSignedURL("DeleteObject", HTTP_DELETE, signedUrl, actualSignedRequestHeaders, nil, output, true)
+	err = wosClient.doHTTPWithSignedURL("DeleteObject", HTTP_DELETE, signedUrl, actualSignedRequestHeaders, nil, output, true, extensions)
 	if err != nil {
 		output = nil
 	}
@@ -229,9 +251,9 @@ func (wosClient WosClient) DeleteObjectWithSignedUrl(signedUrl string, actualSig
 }
 
 // DeleteObjectsWithSignedUrl deletes objects in a batch with the specified signed url and signed request headers and data
-func (wosClient WosClient) DeleteObjectsWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header, data io.Reader) (output *DeleteObjectsOutput, err error) {
+func (wosClient WosClient) DeleteObjectsWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header, data io.Reader, extensions ...extensionOptions) (output *DeleteObjectsOutput, err error) {
 	output = &DeleteObjectsOutput{}
-	err = wosClient.doHTTPWithSignedURL("DeleteObjects", HTTP_POST, signedUrl, actualSignedRequestHeaders, data, output, true)
+	err = wosClient.doHTTPWithSignedURL("DeleteObjects", HTTP_POST, signedUrl, actualSignedRequestHeaders, data, output, true, extensions)
 	if err != nil {
 		output = nil
 	}
@@ -239,9 +261,9 @@ func (wosClient WosClient) DeleteObjectsWithSignedUrl(signedUrl string, actualSi
 }
 
 // RestoreObjectWithSignedUrl restores an object with the specified signed url and signed request headers and data
-func (wosClient WosClient) RestoreObjectWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header, data io.Reader) (output *BaseModel, err error) {
+func (wosClient WosClient) RestoreObjectWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header, data io.Reader, extensions ...extensionOptions) (output *BaseModel, err error) {
 	output = &BaseModel{}
-	err = wosClient.doHTTPWithSignedURL("RestoreObject", HTTP_POST, signedUrl, actualSignedRequestHeaders, data, output, true)
+	err = wosClient.doHTTPWithSignedURL("RestoreObject", HTTP_POST, signedUrl, actualSignedRequestHeaders, data, output, true, extensions)
 	if err != nil {
 		output = nil
 	}
@@ -249,9 +271,9 @@ func (wosClient WosClient) RestoreObjectWithSignedUrl(signedUrl string, actualSi
 }
 
 // GetObjectMetadataWithSignedUrl gets object metadata with the specified signed url and signed request headers
-func (wosClient WosClient) GetObjectMetadataWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header) (output *GetObjectMetadataOutput, err error) {
+func (wosClient WosClient) GetObjectMetadataWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header, extensions ...extensionOptions) (output *GetObjectMetadataOutput, err error) {
 	output = &GetObjectMetadataOutput{}
-	err = wosClient.doHTTPWithSignedURL("GetObjectMetadata", HTTP_HEAD, signedUrl, actualSignedRequestHeaders, nil, output, true)
+	err = wosClient.doHTTPWithSignedURL("GetObjectMetadata", HTTP_HEAD, signedUrl, actualSignedRequestHeaders, nil, output, true, extensions)
 	if err != nil {
 		output = nil
 	} else {
@@ -261,9 +283,9 @@ func (wosClient WosClient) GetObjectMetadataWithSignedUrl(signedUrl string, actu
 }
 
 // GetAvinfoWithSignedUrl get object avinfo with the specified signed url and signed request headers
-func (wosClient WosClient) GetAvinfoWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header) (output *GetAvinfoOutput, err error) {
+func (wosClient WosClient) GetAvinfoWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header, extensions ...extensionOptions) (output *GetAvinfoOutput, err error) {
 	output = &GetAvinfoOutput{}
-	err = wosClient.doHTTPWithSignedURL("GetObject", HTTP_GET, signedUrl, actualSignedRequestHeaders, nil, output, true)
+	err = wosClient.doHTTPWithSignedURL("GetObject", HTTP_GET, signedUrl, actualSignedRequestHeaders, nil, output, true, extensions)
 	if err != nil {
 		output = nil
 	}
@@ -271,9 +293,9 @@ func (wosClient WosClient) GetAvinfoWithSignedUrl(signedUrl string, actualSigned
 }
 
 // GetObjectWithSignedUrl downloads object with the specified signed url and signed request headers
-func (wosClient WosClient) GetObjectWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header) (output *GetObjectOutput, err error) {
+func (wosClient WosClient) GetObjectWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header, extensions ...extensionOptions) (output *GetObjectOutput, err error) {
 	output = &GetObjectOutput{}
-	err = wosClient.doHTTPWithSignedURL("GetObject", HTTP_GET, signedUrl, actualSignedRequestHeaders, nil, output, true)
+	err = wosClient.doHTTPWithSignedURL("GetObject", HTTP_GET, signedUrl, actualSignedRequestHeaders, nil, output, true, extensions)
 	if err != nil {
 		output = nil
 	} else {
@@ -283,9 +305,9 @@ func (wosClient WosClient) GetObjectWithSignedUrl(signedUrl string, actualSigned
 }
 
 // PutObjectWithSignedUrl uploads an object to the specified bucket with the specified signed url and signed request headers and data
-func (wosClient WosClient) PutObjectWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header, data io.Reader) (output *PutObjectOutput, err error) {
+func (wosClient WosClient) PutObjectWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header, data io.Reader, extensions ...extensionOptions) (output *PutObjectOutput, err error) {
 	output = &PutObjectOutput{}
-	err = wosClient.doHTTPWithSignedURL("PutObject", HTTP_PUT, signedUrl, actualSignedRequestHeaders, data, output, true)
+	err = wosClient.doHTTPWithSignedURL("PutObject", HTTP_PUT, signedUrl, actualSignedRequestHeaders, data, output, true, extensions)
 	if err != nil {
 		output = nil
 	} else {
@@ -295,7 +317,7 @@ func (wosClient WosClient) PutObjectWithSignedUrl(signedUrl string, actualSigned
 }
 
 // PutFileWithSignedUrl uploads a file to the specified bucket with the specified signed url and signed request headers and sourceFile path
-func (wosClient WosClient) PutFileWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header, sourceFile string) (output *PutObjectOutput, err error) {
+func (wosClient WosClient) PutFileWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header, sourceFile string, extensions ...extensionOptions) (output *PutObjectOutput, err error) {
 	var data io.Reader
 	sourceFile = strings.TrimSpace(sourceFile)
 	if sourceFile != "" {
@@ -307,7 +329,7 @@ func (wosClient WosClient) PutFileWithSignedUrl(signedUrl string, actualSignedRe
 		defer func() {
 			errMsg := fd.Close()
 			if errMsg != nil {
-				doLog(LEVEL_WARN, "Failed to close file with reason: %v", errMsg)
+				wosClient.logf(LEVEL_WARN, "Failed to close file with reason: %v", errMsg)
 			}
 		}()
 
@@ -335,7 +357,7 @@ func (wosClient WosClient) PutFileWithSignedUrl(signedUrl string, actualSignedRe
 	}
 
 	output = &PutObjectOutput{}
-	err = wosClient.doHTTPWithSignedURL("PutObject", HTTP_PUT, signedUrl, actualSignedRequestHeaders, data, output, true)
+	err = wosClient.doHTTPWithSignedURL("PutObject", HTTP_PUT, signedUrl, actualSignedRequestHeaders, data, output, true, extensions)
 	if err != nil {
 		output = nil
 	} else {
@@ -345,9 +367,9 @@ func (wosClient WosClient) PutFileWithSignedUrl(signedUrl string, actualSignedRe
 }
 
 // CopyObjectWithSignedUrl creates a copy for an existing object with the specified signed url and signed request headers
-func (wosClient WosClient) CopyObjectWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header) (output *CopyObjectOutput, err error) {
+func (wosClient WosClient) CopyObjectWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header, extensions ...extensionOptions) (output *CopyObjectOutput, err error) {
 	output = &CopyObjectOutput{}
-	err = wosClient.doHTTPWithSignedURL("CopyObject", HTTP_PUT, signedUrl, actualSignedRequestHeaders, nil, output, true)
+	err = wosClient.doHTTPWithSignedURL("CopyObject", HTTP_PUT, signedUrl, actualSignedRequestHeaders, nil, output, true, extensions)
 	if err != nil {
 		output = nil
 	} else {
@@ -357,9 +379,9 @@ func (wosClient WosClient) CopyObjectWithSignedUrl(signedUrl string, actualSigne
 }
 
 // AbortMultipartUploadWithSignedUrl aborts a multipart upload in a specified bucket by using the multipart upload ID with the specified signed url and signed request headers
-func (wosClient WosClient) AbortMultipartUploadWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header) (output *BaseModel, err error) {
+func (wosClient WosClient) AbortMultipartUploadWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header, extensions ...extensionOptions) (output *BaseModel, err error) {
 	output = &BaseModel{}
-	err = wosClient.doHTTPWithSignedURL("AbortMultipartUpload", HTTP_DELETE, signedUrl, actualSignedRequestHeaders, nil, output, true)
+	err = wosClient.doHTTPWithSignedURL("AbortMultipartUpload", HTTP_DELETE, signedUrl, actualSignedRequestHeaders, nil, output, true, extensions)
 	if err != nil {
 		output = nil
 	}
@@ -367,9 +389,9 @@ func (wosClient WosClient) AbortMultipartUploadWithSignedUrl(signedUrl string, a
 }
 
 // InitiateMultipartUploadWithSignedUrl initializes a multipart upload with the specified signed url and signed request headers
-func (wosClient WosClient) InitiateMultipartUploadWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header) (output *InitiateMultipartUploadOutput, err error) {
+func (wosClient WosClient) InitiateMultipartUploadWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header, extensions ...extensionOptions) (output *InitiateMultipartUploadOutput, err error) {
 	output = &InitiateMultipartUploadOutput{}
-	err = wosClient.doHTTPWithSignedURL("InitiateMultipartUpload", HTTP_POST, signedUrl, actualSignedRequestHeaders, nil, output, true)
+	err = wosClient.doHTTPWithSignedURL("InitiateMultipartUpload", HTTP_POST, signedUrl, actualSignedRequestHeaders, nil, output, true, extensions)
 	if err != nil {
 		output = nil
 	} else {
@@ -377,7 +399,7 @@ func (wosClient WosClient) InitiateMultipartUploadWithSignedUrl(signedUrl string
 		if output.EncodingType == "url" {
 			err = decodeInitiateMultipartUploadOutput(output)
 			if err != nil {
-				doLog(LEVEL_ERROR, "Failed to get InitiateMultipartUploadOutput with error: %v.", err)
+				wosClient.logf(LEVEL_ERROR, "Failed to get InitiateMultipartUploadOutput with error: %v.", err)
 				output = nil
 			}
 		}
@@ -387,9 +409,9 @@ func (wosClient WosClient) InitiateMultipartUploadWithSignedUrl(signedUrl string
 
 // UploadPartWithSignedUrl uploads a part to a specified bucket by using a specified multipart upload ID
 // with the specified signed url and signed request headers and data
-func (wosClient WosClient) UploadPartWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header, data io.Reader) (output *UploadPartOutput, err error) {
+func (wosClient WosClient) UploadPartWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header, data io.Reader, extensions ...extensionOptions) (output *UploadPartOutput, err error) {
 	output = &UploadPartOutput{}
-	err = wosClient.doHTTPWithSignedURL("UploadPart", HTTP_PUT, signedUrl, actualSignedRequestHeaders, data, output, true)
+	err = wosClient.doHTTPWithSignedURL("UploadPart", HTTP_PUT, signedUrl, actualSignedRequestHeaders, data, output, true, extensions)
 	if err != nil {
 		output = nil
 	} else {
@@ -400,9 +422,9 @@ func (wosClient WosClient) UploadPartWithSignedUrl(signedUrl string, actualSigne
 
 // CompleteMultipartUploadWithSignedUrl combines the uploaded parts in a specified bucket by using the multipart upload ID
 // with the specified signed url and signed request headers and data
-func (wosClient WosClient) CompleteMultipartUploadWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header, data io.Reader) (output *CompleteMultipartUploadOutput, err error) {
+func (wosClient WosClient) CompleteMultipartUploadWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header, data io.Reader, extensions ...extensionOptions) (output *CompleteMultipartUploadOutput, err error) {
 	output = &CompleteMultipartUploadOutput{}
-	err = wosClient.doHTTPWithSignedURL("CompleteMultipartUpload", HTTP_POST, signedUrl, actualSignedRequestHeaders, data, output, true)
+	err = wosClient.doHTTPWithSignedURL("CompleteMultipartUpload", HTTP_POST, signedUrl, actualSignedRequestHeaders, data, output, true, extensions)
 	if err != nil {
 		output = nil
 	} else {
@@ -410,7 +432,7 @@ func (wosClient WosClient) CompleteMultipartUploadWithSignedUrl(signedUrl string
 		if output.EncodingType == "url" {
 			err = decodeCompleteMultipartUploadOutput(output)
 			if err != nil {
-				doLog(LEVEL_ERROR, "Failed to get CompleteMultipartUploadOutput with error: %v.", err)
+				wosClient.logf(LEVEL_ERROR, "Failed to get CompleteMultipartUploadOutput with error: %v.", err)
 				output = nil
 			}
 		}
@@ -419,15 +441,15 @@ func (wosClient WosClient) CompleteMultipartUploadWithSignedUrl(signedUrl string
 }
 
 // ListPartsWithSignedUrl lists the uploaded parts in a bucket by using the multipart upload ID with the specified signed url and signed request headers
-func (wosClient WosClient) ListPartsWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header) (output *ListPartsOutput, err error) {
+func (wosClient WosClient) ListPartsWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header, extensions ...extensionOptions) (output *ListPartsOutput, err error) {
 	output = &ListPartsOutput{}
-	err = wosClient.doHTTPWithSignedURL("ListParts", HTTP_GET, signedUrl, actualSignedRequestHeaders, nil, output, true)
+	err = wosClient.doHTTPWithSignedURL("ListParts", HTTP_GET, signedUrl, actualSignedRequestHeaders, nil, output, true, extensions)
 	if err != nil {
 		output = nil
 	} else if output.EncodingType == "url" {
 		err = decodeListPartsOutput(output)
 		if err != nil {
-			doLog(LEVEL_ERROR, "Failed to get ListPartsOutput with error: %v.", err)
+			wosClient.logf(LEVEL_ERROR, "Failed to get ListPartsOutput with error: %v.", err)
 			output = nil
 		}
 	}
@@ -435,9 +457,9 @@ func (wosClient WosClient) ListPartsWithSignedUrl(signedUrl string, actualSigned
 }
 
 // CopyPartWithSignedUrl copy a part to a specified bucket by using a specified multipart upload ID with the specified signed url and signed request headers
-func (wosClient WosClient) CopyPartWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header) (output *CopyPartOutput, err error) {
+func (wosClient WosClient) CopyPartWithSignedUrl(signedUrl string, actualSignedRequestHeaders http.Header, extensions ...extensionOptions) (output *CopyPartOutput, err error) {
 	output = &CopyPartOutput{}
-	err = wosClient.doHTTPWithSignedURL("CopyPart", HTTP_PUT, signedUrl, actualSignedRequestHeaders, nil, output, true)
+	err = wosClient.doHTTPWithSignedURL("CopyPart", HTTP_PUT, signedUrl, actualSignedRequestHeaders, nil, output, true, extensions)
 	if err != nil {
 		output = nil
 	} else {
@@ -445,3 +467,162 @@ func (wosClient WosClient) CopyPartWithSignedUrl(signedUrl string, actualSignedR
 	}
 	return
 }
+
+// IsExpired reports whether the signed url is expired at now, pass a later time to refuse the urls about to expire.
+func (info *SignedUrlInfo) IsExpired(now time.Time) bool {
+	return !now.Before(info.Expires)
+}
+
+// ParseSignedUrl parses a url created by CreateSignedUrl and returns its signature, bucket, key, sub resources and
+// expiry without verifying the signature. The bucket is located with the endpoints of the client.
+func (wosClient WosClient) ParseSignedUrl(signedUrl string) (*SignedUrlInfo, error) {
+	parsedURL, err := url.Parse(signedUrl)
+	if err != nil {
+		return nil, err
+	}
+	query, err := url.ParseQuery(parsedURL.RawQuery)
+	if err != nil {
+		return nil, err
+	}
+	params := make(map[string]string, len(query))
+	for key, values := range query {
+		params[key] = values[0]
+	}
+
+	info := &SignedUrlInfo{}
+	var authParams []string
+	if _, isWos := params[PARAM_SIGNATURE_WOS_CAMEL]; isWos || params[PARAM_SIGNATURE_AMZ_CAMEL] != "" {
+		info.Signature = getV4SignatureType(isWos)
+		algorithmParam, credentialParam, dateParam := PARAM_ALGORITHM_AMZ_CAMEL, PARAM_CREDENTIAL_AMZ_CAMEL, PARAM_DATE_AMZ_CAMEL
+		expiresParam, signedHeadersParam, signatureParam := PARAM_EXPIRES_AMZ_CAMEL, PARAM_SIGNEDHEADERS_AMZ_CAMEL, PARAM_SIGNATURE_AMZ_CAMEL
+		if isWos {
+			algorithmParam, credentialParam, dateParam = PARAM_ALGORITHM_WOS_CAMEL, PARAM_CREDENTIAL_WOS_CAMEL, PARAM_DATE_WOS_CAMEL
+			expiresParam, signedHeadersParam, signatureParam = PARAM_EXPIRES_WOS_CAMEL, PARAM_SIGNEDHEADERS_WOS_CAMEL, PARAM_SIGNATURE_WOS_CAMEL
+		}
+		authParams = []string{algorithmParam, credentialParam, dateParam, expiresParam, signedHeadersParam, signatureParam}
+
+		info.Algorithm = params[algorithmParam]
+		credential := strings.SplitN(params[credentialParam], "/", 2)
+		if len(credential) != 2 {
+			return nil, fmt.Errorf("The %s %s is malformed", credentialParam, params[credentialParam])
+		}
+		info.AccessKey, info.CredentialScope = credential[0], credential[1]
+		if scope := strings.Split(info.CredentialScope, "/"); len(scope) == 4 {
+			info.Region = scope[1]
+		}
+		if info.SignedAt, err = time.Parse(LONG_DATE_FORMAT, params[dateParam]); err != nil {
+			return nil, fmt.Errorf("The %s %s is malformed", dateParam, params[dateParam])
+		}
+		seconds, err := strconv.ParseInt(params[expiresParam], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("The %s %s is malformed", expiresParam, params[expiresParam])
+		}
+		info.Expires = info.SignedAt.Add(time.Duration(seconds) * time.Second)
+		if signedHeaders := params[signedHeadersParam]; signedHeaders != "" {
+			info.SignedHeaders = strings.Split(signedHeaders, ";")
+		}
+	} else if _, ok := params[v2SignatureParam]; ok {
+		info.Signature = SignatureV2
+		info.Algorithm = "HmacSHA1"
+		authParams = []string{v2AccessKeyIDParam, v2WosAccessKeyIDParam, v2ExpiresParam, v2SignatureParam}
+		info.AccessKey = params[v2AccessKeyIDParam]
+		if info.AccessKey == "" {
+			info.AccessKey = params[v2WosAccessKeyIDParam]
+		}
+		expires, err := strconv.ParseInt(params[v2ExpiresParam], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("The %s %s is malformed", v2ExpiresParam, params[v2ExpiresParam])
+		}
+		info.Expires = time.Unix(expires, 0).UTC()
+	} else {
+		return nil, errors.New("The url is not signed")
+	}
+
+	for _, param := range authParams {
+		delete(params, param)
+	}
+	// the security token is a credential, only its presence is reported
+	for key := range params {
+		if lowerKey := strings.ToLower(key); lowerKey == HEADER_STS_TOKEN_AMZ || lowerKey == HEADER_STS_TOKEN_WOS {
+			delete(params, key)
+			info.HasSecurityToken = true
+		}
+	}
+	info.SubResources = params
+	info.Bucket, info.Key = wosClient.conf.getBucketAndKey(strings.ToLower(parsedURL.Hostname()), parsedURL.Path)
+	info.MethodHints = getSignedUrlMethodHints(info.Bucket, params)
+	return info, nil
+}
+
+// getBucketAndKey returns the bucket and the key of a url of the endpoints of the client, the bucket of a url of
+// another host is located as the client would format it.
+func (conf *config) getBucketAndKey(host, path string) (bucket, key string) {
+	path = strings.TrimPrefix(path, "/")
+	if conf.cname {
+		return "", path
+	}
+	splitPath := func() (string, string) {
+		if index := strings.Index(path, "/"); index >= 0 {
+			return path[:index], path[index+1:]
+		}
+		return path, ""
+	}
+	holders := []*urlHolder{conf.urlHolder}
+	pathStyles := []bool{conf.pathStyle}
+	if conf.endpointPool != nil {
+		for _, endpoint := range conf.endpointPool.endpoints {
+			holders = append(holders, endpoint.urlHolder)
+			pathStyles = append(pathStyles, endpoint.pathStyle)
+		}
+	}
+	for i, holder := range holders {
+		domain := strings.ToLower(holder.host)
+		if host == domain {
+			if pathStyles[i] {
+				return splitPath()
+			}
+			return "", path
+		}
+		if strings.HasSuffix(host, "."+domain) {
+			return host[:len(host)-len(domain)-1], path
+		}
+	}
+	if conf.pathStyle || IsIP(host) {
+		return splitPath()
+	}
+	if index := strings.Index(host, "."); index > 0 {
+		return host[:index], path
+	}
+	return "", path
+}
+
+// getSignedUrlMethodHints returns the http methods of the operations matching the bucket and the sub resources.
+func getSignedUrlMethodHints(bucket string, subResources map[string]string) []HttpMethodType {
+	_, hasUploadID := subResources["uploadId"]
+	_, hasPartNumber := subResources["partNumber"]
+	_, hasUploads := subResources["uploads"]
+	onlyResponseParams := len(subResources) > 0
+	for key := range subResources {
+		if !strings.HasPrefix(key, "response-") {
+			onlyResponseParams = false
+			break
+		}
+	}
+	switch {
+	case bucket == "":
+		return []HttpMethodType{HttpMethodGet}
+	case hasUploadID && hasPartNumber:
+		return []HttpMethodType{HttpMethodPut}
+	case hasUploadID:
+		return []HttpMethodType{HttpMethodGet, HttpMethodPost, HttpMethodDelete}
+	case hasUploads:
+		return []HttpMethodType{HttpMethodGet, HttpMethodPost}
+	case onlyResponseParams:
+		// the response-* parameters override the headers of the response of GetObject
+		return []HttpMethodType{HttpMethodGet, HttpMethodHead}
+	case len(subResources) > 0:
+		return []HttpMethodType{HttpMethodGet, HttpMethodPut, HttpMethodPost, HttpMethodDelete}
+	default:
+		return []HttpMethodType{HttpMethodGet, HttpMethodHead, HttpMethodPut, HttpMethodDelete}
+	}
+}
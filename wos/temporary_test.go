@@ -0,0 +1,71 @@
+package wos
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSignedUrlSecurityToken(t *testing.T) {
+	cases := []struct {
+		name      string
+		signature SignatureType
+		// tokenParam is the query parameter of the security token of the url, if any.
+		tokenParam string
+	}{
+		{"v2", SignatureV2, ""},
+		{"v2 with token", SignatureV2, HEADER_STS_TOKEN_AMZ},
+		{"v4 with token", SignatureV4, HEADER_STS_TOKEN_AMZ},
+		{"wos", SignatureWos, ""},
+		{"wos with token", SignatureWos, "X-Wos-Security-Token"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			client := newTestClient(t, "http://wos.example.com", WithSignature(c.signature))
+			params := map[string]string{"versionId": "v1"}
+			if c.tokenParam != "" {
+				params[c.tokenParam] = "token"
+			}
+			output, err := client.CreateSignedUrl(&CreateSignedUrlInput{Method: HttpMethodGet, Bucket: "bucket", Key: "key", Expires: 60,
+				QueryParams: params})
+			if err != nil {
+				t.Fatal(err)
+			}
+			info, err := client.ParseSignedUrl(output.SignedUrl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := map[string]string{"versionId": "v1"}; !reflect.DeepEqual(info.SubResources, want) {
+				t.Errorf("SubResources = %v, want %v", info.SubResources, want)
+			}
+			if info.HasSecurityToken != (c.tokenParam != "") {
+				t.Errorf("HasSecurityToken = %t, want %t", info.HasSecurityToken, c.tokenParam != "")
+			}
+		})
+	}
+}
+
+func TestGetSignedUrlMethodHints(t *testing.T) {
+	cases := []struct {
+		name         string
+		bucket       string
+		subResources map[string]string
+		want         []HttpMethodType
+	}{
+		{"service", "", nil, []HttpMethodType{HttpMethodGet}},
+		{"object", "bucket", nil, []HttpMethodType{HttpMethodGet, HttpMethodHead, HttpMethodPut, HttpMethodDelete}},
+		{"response params", "bucket", map[string]string{"response-content-type": "text/plain", "response-expires": "0"},
+			[]HttpMethodType{HttpMethodGet, HttpMethodHead}},
+		{"response and other params", "bucket", map[string]string{"response-content-type": "text/plain", "acl": ""},
+			[]HttpMethodType{HttpMethodGet, HttpMethodPut, HttpMethodPost, HttpMethodDelete}},
+		{"part", "bucket", map[string]string{"uploadId": "id", "partNumber": "1"}, []HttpMethodType{HttpMethodPut}},
+		{"upload", "bucket", map[string]string{"uploadId": "id"}, []HttpMethodType{HttpMethodGet, HttpMethodPost, HttpMethodDelete}},
+		{"uploads", "bucket", map[string]string{"uploads": ""}, []HttpMethodType{HttpMethodGet, HttpMethodPost}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := getSignedUrlMethodHints(c.bucket, c.subResources); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("getSignedUrlMethodHints = %v, want %v", got, c.want)
+			}
+		})
+	}
+}
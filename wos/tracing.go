@@ -0,0 +1,137 @@
+package wos
+
+import (
+	"context"
+	"net/http"
+)
+
+// Attribute keys of the spans started by WosClient.
+const (
+	TraceAttributeAction          = "wos.action"
+	TraceAttributeBucket          = "wos.bucket"
+	TraceAttributeKey             = "wos.key"
+	TraceAttributeUploadID        = "wos.upload_id"
+	TraceAttributePartNumber      = "wos.part_number"
+	TraceAttributeRequestID       = "wos.request_id"
+	TraceAttributeClientRequestID = "wos.client_request_id"
+	TraceAttributeErrorCode       = "wos.error_code"
+	TraceAttributeAttempt         = "wos.attempt"
+	TraceAttributeRetry           = "wos.retry"
+	TraceAttributeMethod          = "http.method"
+	TraceAttributeStatusCode      = "http.status_code"
+	TraceAttributeHost            = "net.peer.name"
+)
+
+// Span defines interface with functions: SetAttribute, RecordError, End
+type Span interface {
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+// Tracer defines interface with functions: StartSpan, Inject
+//
+// StartSpan starts a span as a child of the span in ctx, and returns the context carrying the new span.
+// WosClient starts a span named "wos.<Action>" for each operation, such as wos.PutObject, wos.UploadPart
+// for each part of UploadFile, and a span named "wos.attempt" for each HTTP attempt.
+//
+// Inject writes the trace context of ctx into the request headers, such as the W3C traceparent header.
+// It is called only if the injection is enabled by WithTracer. The SDK has no propagator of its own,
+// no trace context is sent unless Inject writes it.
+//
+// With OpenTelemetry, StartSpan wraps trace.Tracer.Start and Inject wraps TextMapPropagator.Inject
+// with propagation.HeaderCarrier.
+type Tracer interface {
+	StartSpan(ctx context.Context, name string) (context.Context, Span)
+	Inject(ctx context.Context, header http.Header)
+}
+
+func getResponseRequestID(header http.Header) string {
+	if requestID := header.Get(HEADER_PREFIX_WOS + HEADER_REQUEST_ID); requestID != "" {
+		return requestID
+	}
+	return header.Get(HEADER_PREFIX + HEADER_REQUEST_ID)
+}
+
+// startSpan starts the span of an operation, and returns a copy of the client whose context carries the span.
+func (wosClient WosClient) startSpan(action, method, bucketName, objectKey string, params map[string]string) (WosClient, Span) {
+	tracer := wosClient.conf.tracer
+	if tracer == nil {
+		return wosClient, nil
+	}
+	ctx := wosClient.conf.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, span := tracer.StartSpan(ctx, "wos."+action)
+	conf := *wosClient.conf
+	conf.ctx = ctx
+	wosClient.conf = &conf
+
+	span.SetAttribute(TraceAttributeAction, action)
+	if method != "" {
+		span.SetAttribute(TraceAttributeMethod, method)
+	}
+	if bucketName != "" {
+		span.SetAttribute(TraceAttributeBucket, bucketName)
+	}
+	if objectKey != "" {
+		span.SetAttribute(TraceAttributeKey, objectKey)
+	}
+	if wosClient.call.clientRequestID != "" {
+		span.SetAttribute(TraceAttributeClientRequestID, wosClient.call.clientRequestID)
+	}
+	if uploadID, ok := params["uploadId"]; ok && uploadID != "" {
+		span.SetAttribute(TraceAttributeUploadID, uploadID)
+	}
+	if partNumber, ok := params["partNumber"]; ok {
+		span.SetAttribute(TraceAttributePartNumber, StringToInt(partNumber, 0))
+	}
+	return wosClient, span
+}
+
+func endSpan(span Span, resp *http.Response, err error) {
+	if span == nil {
+		return
+	}
+	if resp != nil {
+		span.SetAttribute(TraceAttributeStatusCode, resp.StatusCode)
+		if requestID := getResponseRequestID(resp.Header); requestID != "" {
+			span.SetAttribute(TraceAttributeRequestID, requestID)
+		}
+	} else if wosError, ok := err.(WosError); ok {
+		span.SetAttribute(TraceAttributeStatusCode, wosError.StatusCode)
+		if wosError.RequestId != "" {
+			span.SetAttribute(TraceAttributeRequestID, wosError.RequestId)
+		}
+		if wosError.Code != "" {
+			span.SetAttribute(TraceAttributeErrorCode, wosError.Code)
+		}
+	}
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// startAttemptSpan starts the span of an HTTP attempt, and returns the request carrying the span.
+func (wosClient WosClient) startAttemptSpan(req *http.Request, attempt int, retry bool) (*http.Request, Span) {
+	tracer := wosClient.conf.tracer
+	if tracer == nil {
+		return req, nil
+	}
+	ctx, span := tracer.StartSpan(req.Context(), "wos.attempt")
+	req = req.WithContext(ctx)
+	span.SetAttribute(TraceAttributeMethod, req.Method)
+	if req.Host != "" {
+		span.SetAttribute(TraceAttributeHost, req.Host)
+	} else {
+		span.SetAttribute(TraceAttributeHost, req.URL.Host)
+	}
+	span.SetAttribute(TraceAttributeAttempt, attempt)
+	span.SetAttribute(TraceAttributeRetry, retry)
+	if wosClient.conf.traceInjection {
+		tracer.Inject(ctx, req.Header)
+	}
+	return req, span
+}
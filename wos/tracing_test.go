@@ -0,0 +1,123 @@
+package wos
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+type testSpanKey struct{}
+
+type testSpan struct {
+	name       string
+	parent     *testSpan
+	lock       sync.Mutex
+	attributes map[string]interface{}
+	errs       []error
+	ended      bool
+}
+
+func (span *testSpan) SetAttribute(key string, value interface{}) {
+	span.lock.Lock()
+	defer span.lock.Unlock()
+	span.attributes[key] = value
+}
+
+func (span *testSpan) RecordError(err error) {
+	span.lock.Lock()
+	defer span.lock.Unlock()
+	span.errs = append(span.errs, err)
+}
+
+func (span *testSpan) End() {
+	span.lock.Lock()
+	defer span.lock.Unlock()
+	span.ended = true
+}
+
+// testTracer records the spans, and injects the name of the span in the traceparent header.
+type testTracer struct {
+	lock  sync.Mutex
+	spans []*testSpan
+}
+
+func (tracer *testTracer) StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	parent, _ := ctx.Value(testSpanKey{}).(*testSpan)
+	span := &testSpan{name: name, parent: parent, attributes: make(map[string]interface{})}
+	tracer.lock.Lock()
+	tracer.spans = append(tracer.spans, span)
+	tracer.lock.Unlock()
+	return context.WithValue(ctx, testSpanKey{}, span), span
+}
+
+func (tracer *testTracer) Inject(ctx context.Context, header http.Header) {
+	if span, ok := ctx.Value(testSpanKey{}).(*testSpan); ok {
+		header.Set("traceparent", span.name)
+	}
+}
+
+func TestTracer(t *testing.T) {
+	cases := []struct {
+		name   string
+		inject bool
+	}{
+		{"without injection", false},
+		{"with injection", true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var requests int32
+			var traceparents []string
+			var lock sync.Mutex
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				lock.Lock()
+				traceparents = append(traceparents, r.Header.Get("traceparent"))
+				lock.Unlock()
+				if atomic.AddInt32(&requests, 1) == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				w.Header().Set("X-Wos-Request-Id", "request-id")
+				w.Header().Set("Content-Type", "application/xml")
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte("<Error><Code>NoSuchKey</Code><RequestId>request-id</RequestId></Error>"))
+			}))
+			defer server.Close()
+			tracer := &testTracer{}
+			client := newTestClient(t, server.URL, WithTracer(tracer, c.inject))
+
+			if _, err := client.DeleteObject(&DeleteObjectInput{Bucket: "bucket", Key: "key"}); err == nil {
+				t.Fatal("DeleteObject succeeded")
+			}
+			if len(tracer.spans) != 3 {
+				t.Fatalf("%d spans, want 3", len(tracer.spans))
+			}
+			operation := tracer.spans[0]
+			if operation.name != "wos.DeleteObject" || operation.parent != nil || !operation.ended || len(operation.errs) != 1 {
+				t.Errorf("operation span %+v", operation)
+			}
+			for key, value := range map[string]interface{}{TraceAttributeAction: "DeleteObject", TraceAttributeBucket: "bucket", TraceAttributeKey: "key",
+				TraceAttributeStatusCode: http.StatusNotFound, TraceAttributeErrorCode: "NoSuchKey", TraceAttributeRequestID: "request-id"} {
+				if operation.attributes[key] != value {
+					t.Errorf("operation attribute %s = %v, want %v", key, operation.attributes[key], value)
+				}
+			}
+			for i, attempt := range tracer.spans[1:] {
+				if attempt.name != "wos.attempt" || attempt.parent != operation || !attempt.ended {
+					t.Errorf("attempt span %d %+v", i+1, attempt)
+				}
+				if attempt.attributes[TraceAttributeAttempt] != i+1 || attempt.attributes[TraceAttributeRetry] != (i > 0) {
+					t.Errorf("attempt span %d attributes %v", i+1, attempt.attributes)
+				}
+			}
+			for _, traceparent := range traceparents {
+				if want := map[bool]string{true: "wos.attempt"}[c.inject]; traceparent != want {
+					t.Errorf("traceparent %q, want %q", traceparent, want)
+				}
+			}
+		})
+	}
+}
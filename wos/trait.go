@@ -47,6 +47,10 @@ type IBaseModel interface {
 	setRequestID(requestID string)
 
 	setResponseHeaders(responseHeaders map[string][]string)
+
+	setDiagnostics(diagnostics *Diagnostics)
+
+	setClientRequestID(clientRequestID string)
 }
 
 // ISerializable defines interface with function: trans
@@ -73,7 +77,7 @@ func newSubResourceSerial(subResource SubResourceType) *DefaultSerializable {
 
 func trans(subResource SubResourceType, input interface{}) (params map[string]string, headers map[string][]string, data interface{}, err error) {
 	params = map[string]string{string(subResource): ""}
-	data, err = ConvertRequestToIoReader(input)
+	data, err = convertRequestToXML(input)
 	return
 }
 
@@ -85,6 +89,10 @@ func (baseModel *BaseModel) setRequestID(requestID string) {
 	baseModel.RequestId = requestID
 }
 
+func (baseModel *BaseModel) setClientRequestID(clientRequestID string) {
+	baseModel.ClientRequestId = clientRequestID
+}
+
 func (baseModel *BaseModel) setResponseHeaders(responseHeaders map[string][]string) {
 	baseModel.ResponseHeaders = responseHeaders
 }
@@ -191,7 +199,7 @@ func (input DeleteObjectsInput) trans(isWos bool) (params map[string]string, hea
 func (input RestoreObjectInput) trans(isWos bool) (params map[string]string, headers map[string][]string, data interface{}, err error) {
 	params = map[string]string{string(SubResourceRestore): ""}
 	if !isWos {
-		data, err = ConvertRequestToIoReader(input)
+		data, err = convertRequestToXML(input)
 	} else {
 		data = ConverntWosRestoreToXml(input)
 	}
@@ -2,6 +2,7 @@ package wos
 
 import (
 	"bufio"
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -44,19 +45,19 @@ type UploadCheckpoint struct {
 	UploadParts []UploadPartInfo `xml:"UploadParts>UploadPart"`
 }
 
-func (ufc *UploadCheckpoint) isValid(bucket, key, uploadFile string, fileStat os.FileInfo) bool {
+func (ufc *UploadCheckpoint) isValid(bucket, key, uploadFile string, fileStat os.FileInfo, wosClient *WosClient) bool {
 	if ufc.Bucket != bucket || ufc.Key != key || ufc.UploadFile != uploadFile {
-		doLog(LEVEL_INFO, "Checkpoint file is invalid, the bucketName or objectKey or uploadFile was changed. clear the record.")
+		wosClient.logf(LEVEL_INFO, "Checkpoint file is invalid, the bucketName or objectKey or uploadFile was changed. clear the record.")
 		return false
 	}
 
 	if ufc.FileInfo.Size != fileStat.Size() || ufc.FileInfo.LastModified != fileStat.ModTime().Unix() {
-		doLog(LEVEL_INFO, "Checkpoint file is invalid, the uploadFile was changed. clear the record.")
+		wosClient.logf(LEVEL_INFO, "Checkpoint file is invalid, the uploadFile was changed. clear the record.")
 		return false
 	}
 
 	if ufc.UploadId == "" {
-		doLog(LEVEL_INFO, "UploadId is invalid. clear the record.")
+		wosClient.logf(LEVEL_INFO, "UploadId is invalid. clear the record.")
 		return false
 	}
 
@@ -75,6 +76,9 @@ func (task *uploadPartTask) Run() interface{} {
 	if atomic.LoadInt32(task.abort) == 1 {
 		return errAbort
 	}
+	if ctx := task.wosClient.conf.ctx; isContextDone(ctx) {
+		return ctx.Err()
+	}
 
 	input := &UploadPartInput{}
 	input.Bucket = task.Bucket
@@ -96,17 +100,17 @@ func (task *uploadPartTask) Run() interface{} {
 
 	if err == nil {
 		if output.ETag == "" {
-			doLog(LEVEL_WARN, "Get invalid etag value after uploading part [%d].", task.PartNumber)
+			task.wosClient.logf(LEVEL_WARN, "Get invalid etag value after uploading part [%d].", task.PartNumber)
 			if !task.enableCheckpoint {
 				atomic.CompareAndSwapInt32(task.abort, 0, 1)
-				doLog(LEVEL_WARN, "Task is aborted, part number is [%d]", task.PartNumber)
+				task.wosClient.logf(LEVEL_WARN, "Task is aborted, part number is [%d]", task.PartNumber)
 			}
 			return fmt.Errorf("get invalid etag value after uploading part [%d]", task.PartNumber)
 		}
 		return output
 	} else if wosError, ok := err.(WosError); ok && wosError.StatusCode >= 400 && wosError.StatusCode < 500 {
 		atomic.CompareAndSwapInt32(task.abort, 0, 1)
-		doLog(LEVEL_WARN, "Task is aborted, part number is [%d]", task.PartNumber)
+		task.wosClient.logf(LEVEL_WARN, "Task is aborted, part number is [%d]", task.PartNumber)
 	}
 	return err
 }
@@ -127,35 +131,40 @@ func updateCheckpointFile(fc interface{}, checkpointFilePath string) error {
 	if err != nil {
 		return err
 	}
-	err = ioutil.WriteFile(checkpointFilePath, result, 0666)
-	return err
+	// write to a temp file and rename it, so that an interrupted upload or download never leaves a truncated checkpoint
+	tempFilePath := checkpointFilePath + ".tmp"
+	err = ioutil.WriteFile(tempFilePath, result, 0666)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tempFilePath, checkpointFilePath)
 }
 
 func getCheckpointFile(ufc *UploadCheckpoint, uploadFileStat os.FileInfo, input *UploadFileInput, wosClient *WosClient, extensions []extensionOptions) (needCheckpoint bool, err error) {
 	checkpointFilePath := input.CheckpointFile
 	checkpointFileStat, err := os.Stat(checkpointFilePath)
 	if err != nil {
-		doLog(LEVEL_DEBUG, fmt.Sprintf("Stat checkpoint file failed with error: [%v].", err))
+		wosClient.logf(LEVEL_DEBUG, "Stat checkpoint file failed with error: [%v].", err)
 		return true, nil
 	}
 	if checkpointFileStat.IsDir() {
-		doLog(LEVEL_ERROR, "Checkpoint file can not be a folder.")
+		wosClient.logf(LEVEL_ERROR, "Checkpoint file can not be a folder.")
 		return false, errors.New("checkpoint file can not be a folder")
 	}
 	err = loadCheckpointFile(checkpointFilePath, ufc)
 	if err != nil {
-		doLog(LEVEL_WARN, fmt.Sprintf("Load checkpoint file failed with error: [%v].", err))
+		wosClient.logf(LEVEL_WARN, "Load checkpoint file failed with error: [%v].", err)
 		return true, nil
-	} else if !ufc.isValid(input.Bucket, input.Key, input.UploadFile, uploadFileStat) {
+	} else if !ufc.isValid(input.Bucket, input.Key, input.UploadFile, uploadFileStat, wosClient) {
 		if ufc.Bucket != "" && ufc.Key != "" && ufc.UploadId != "" {
 			_err := abortTask(ufc.Bucket, ufc.Key, ufc.UploadId, wosClient, extensions)
 			if _err != nil {
-				doLog(LEVEL_WARN, "Failed to abort upload task [%s].", ufc.UploadId)
+				wosClient.logf(LEVEL_WARN, "Failed to abort upload task [%s].", ufc.UploadId)
 			}
 		}
 		_err := os.Remove(checkpointFilePath)
 		if _err != nil {
-			doLog(LEVEL_WARN, fmt.Sprintf("Failed to remove checkpoint file with error: [%v].", _err))
+			wosClient.logf(LEVEL_WARN, "Failed to remove checkpoint file with error: [%v].", _err)
 		}
 	} else {
 		return false, nil
@@ -188,11 +197,11 @@ func prepareUpload(ufc *UploadCheckpoint, uploadFileStat os.FileInfo, input *Upl
 	ufc.FileInfo.LastModified = uploadFileStat.ModTime().Unix()
 	ufc.UploadId = output.UploadId
 
-	err = sliceFile(input.PartSize, ufc)
+	err = sliceFile(input.PartSize, ufc, wosClient)
 	return err
 }
 
-func sliceFile(partSize int64, ufc *UploadCheckpoint) error {
+func sliceFile(partSize int64, ufc *UploadCheckpoint, wosClient *WosClient) error {
 	fileSize := ufc.FileInfo.Size
 	cnt := fileSize / partSize
 	if cnt >= 10000 {
@@ -207,7 +216,7 @@ func sliceFile(partSize int64, ufc *UploadCheckpoint) error {
 	}
 
 	if partSize > MAX_PART_SIZE {
-		doLog(LEVEL_ERROR, "The source upload file is too large")
+		wosClient.logf(LEVEL_ERROR, "The source upload file is too large")
 		return fmt.Errorf("The source upload file is too large")
 	}
 
@@ -238,6 +247,10 @@ func abortTask(bucket, key, uploadID string, wosClient *WosClient, extensions []
 	input.Bucket = bucket
 	input.Key = key
 	input.UploadId = uploadID
+	if isContextDone(wosClient.conf.ctx) {
+		// the upload was cancelled, abort it with a fresh context so that no parts are left behind
+		extensions = append(extensions[:len(extensions):len(extensions)], WithContext(context.Background()))
+	}
 	if extensions != nil {
 		_, err := wosClient.AbortMultipartUpload(input, extensions...)
 		return err
@@ -253,7 +266,7 @@ func handleUploadFileResult(uploadPartError error, ufc *UploadCheckpoint, enable
 		}
 		_err := abortTask(ufc.Bucket, ufc.Key, ufc.UploadId, wosClient, extensions)
 		if _err != nil {
-			doLog(LEVEL_WARN, "Failed to abort task [%s].", ufc.UploadId)
+			wosClient.logf(LEVEL_WARN, "Failed to abort task [%s].", ufc.UploadId)
 		}
 		return uploadPartError
 	}
@@ -285,7 +298,7 @@ func completeParts(ufc *UploadCheckpoint, enableCheckpoint bool, checkpointFileP
 		if enableCheckpoint {
 			_err := os.Remove(checkpointFilePath)
 			if _err != nil {
-				doLog(LEVEL_WARN, "Upload file successfully, but remove checkpoint file failed with error [%v].", _err)
+				wosClient.logf(LEVEL_WARN, "Upload file successfully, but remove checkpoint file failed with error [%v].", _err)
 			}
 		}
 		return completeOutput, err
@@ -293,7 +306,7 @@ func completeParts(ufc *UploadCheckpoint, enableCheckpoint bool, checkpointFileP
 	if !enableCheckpoint {
 		_err := abortTask(ufc.Bucket, ufc.Key, ufc.UploadId, wosClient, extensions)
 		if _err != nil {
-			doLog(LEVEL_WARN, "Failed to abort task [%s].", ufc.UploadId)
+			wosClient.logf(LEVEL_WARN, "Failed to abort task [%s].", ufc.UploadId)
 		}
 	}
 	return completeOutput, err
@@ -302,11 +315,11 @@ func completeParts(ufc *UploadCheckpoint, enableCheckpoint bool, checkpointFileP
 func (wosClient WosClient) resumeUpload(input *UploadFileInput, extensions []extensionOptions) (output *CompleteMultipartUploadOutput, err error) {
 	uploadFileStat, err := os.Stat(input.UploadFile)
 	if err != nil {
-		doLog(LEVEL_ERROR, fmt.Sprintf("Failed to stat uploadFile with error: [%v].", err))
+		wosClient.logf(LEVEL_ERROR, "Failed to stat uploadFile with error: [%v].", err)
 		return nil, err
 	}
 	if uploadFileStat.IsDir() {
-		doLog(LEVEL_ERROR, "UploadFile can not be a folder.")
+		wosClient.logf(LEVEL_ERROR, "UploadFile can not be a folder.")
 		return nil, errors.New("uploadFile can not be a folder")
 	}
 
@@ -330,10 +343,10 @@ func (wosClient WosClient) resumeUpload(input *UploadFileInput, extensions []ext
 		if enableCheckpoint {
 			err = updateCheckpointFile(ufc, checkpointFilePath)
 			if err != nil {
-				doLog(LEVEL_ERROR, "Failed to update checkpoint file with error [%v].", err)
+				wosClient.logf(LEVEL_ERROR, "Failed to update checkpoint file with error [%v].", err)
 				_err := abortTask(ufc.Bucket, ufc.Key, ufc.UploadId, &wosClient, extensions)
 				if _err != nil {
-					doLog(LEVEL_WARN, "Failed to abort task [%s].", ufc.UploadId)
+					wosClient.logf(LEVEL_WARN, "Failed to abort task [%s].", ufc.UploadId)
 				}
 				return nil, err
 			}
@@ -351,7 +364,7 @@ func (wosClient WosClient) resumeUpload(input *UploadFileInput, extensions []ext
 	return completeOutput, err
 }
 
-func handleUploadTaskResult(result interface{}, ufc *UploadCheckpoint, partNum int, enableCheckpoint bool, checkpointFilePath string, lock *sync.Mutex) (err error) {
+func handleUploadTaskResult(result interface{}, ufc *UploadCheckpoint, partNum int, enableCheckpoint bool, checkpointFilePath string, lock *sync.Mutex, wosClient *WosClient) (err error) {
 	if uploadPartOutput, ok := result.(*UploadPartOutput); ok {
 		lock.Lock()
 		defer lock.Unlock()
@@ -360,7 +373,7 @@ func handleUploadTaskResult(result interface{}, ufc *UploadCheckpoint, partNum i
 		if enableCheckpoint {
 			_err := updateCheckpointFile(ufc, checkpointFilePath)
 			if _err != nil {
-				doLog(LEVEL_WARN, "Failed to update checkpoint file with error [%v].", _err)
+				wosClient.logf(LEVEL_WARN, "Failed to update checkpoint file with error [%v].", _err)
 			}
 		}
 	} else if result != errAbort {
@@ -373,12 +386,14 @@ func handleUploadTaskResult(result interface{}, ufc *UploadCheckpoint, partNum i
 
 func (wosClient WosClient) uploadPartConcurrent(ufc *UploadCheckpoint, checkpointFilePath string, input *UploadFileInput, extensions []extensionOptions) error {
 	pool := NewRoutinePool(input.TaskNum, MAX_PART_NUM)
+	defer wosClient.observePool("UploadFile", pool)()
 	var uploadPartError atomic.Value
 	var errFlag int32
 	var abort int32
 	lock := new(sync.Mutex)
+	ctx := wosClient.conf.ctx
 	for _, uploadPart := range ufc.UploadParts {
-		if atomic.LoadInt32(&abort) == 1 {
+		if atomic.LoadInt32(&abort) == 1 || isContextDone(ctx) {
 			break
 		}
 		if uploadPart.IsCompleted {
@@ -401,7 +416,7 @@ func (wosClient WosClient) uploadPartConcurrent(ufc *UploadCheckpoint, checkpoin
 		}
 		pool.ExecuteFunc(func() interface{} {
 			result := task.Run()
-			err := handleUploadTaskResult(result, ufc, task.PartNumber, input.EnableCheckpoint, input.CheckpointFile, lock)
+			err := handleUploadTaskResult(result, ufc, task.PartNumber, input.EnableCheckpoint, input.CheckpointFile, lock, &wosClient)
 			if err != nil && atomic.CompareAndSwapInt32(&errFlag, 0, 1) {
 				uploadPartError.Store(err)
 			}
@@ -409,6 +424,10 @@ func (wosClient WosClient) uploadPartConcurrent(ufc *UploadCheckpoint, checkpoin
 		})
 	}
 	pool.ShutDown()
+	// the parts in flight fail with the errors of the transport once the context is done
+	if isContextDone(ctx) {
+		return ctx.Err()
+	}
 	if err, ok := uploadPartError.Load().(error); ok {
 		return err
 	}
@@ -451,22 +470,22 @@ type DownloadCheckpoint struct {
 	DownloadParts []DownloadPartInfo `xml:"DownloadParts>DownloadPart"`
 }
 
-func (dfc *DownloadCheckpoint) isValid(input *DownloadFileInput, output *GetObjectMetadataOutput) bool {
+func (dfc *DownloadCheckpoint) isValid(input *DownloadFileInput, output *GetObjectMetadataOutput, wosClient *WosClient) bool {
 	if dfc.Bucket != input.Bucket || dfc.Key != input.Key || dfc.DownloadFile != input.DownloadFile {
-		doLog(LEVEL_INFO, "Checkpoint file is invalid, the bucketName or objectKey or downloadFile was changed. clear the record.")
+		wosClient.logf(LEVEL_INFO, "Checkpoint file is invalid, the bucketName or objectKey or downloadFile was changed. clear the record.")
 		return false
 	}
 	if dfc.ObjectInfo.LastModified != output.LastModified.Unix() || dfc.ObjectInfo.ETag != output.ETag || dfc.ObjectInfo.Size != output.ContentLength {
-		doLog(LEVEL_INFO, "Checkpoint file is invalid, the object info was changed. clear the record.")
+		wosClient.logf(LEVEL_INFO, "Checkpoint file is invalid, the object info was changed. clear the record.")
 		return false
 	}
 	if dfc.TempFileInfo.Size != output.ContentLength {
-		doLog(LEVEL_INFO, "Checkpoint file is invalid, size was changed. clear the record.")
+		wosClient.logf(LEVEL_INFO, "Checkpoint file is invalid, size was changed. clear the record.")
 		return false
 	}
 	stat, err := os.Stat(dfc.TempFileInfo.TempFileUrl)
 	if err != nil || stat.Size() != dfc.ObjectInfo.Size {
-		doLog(LEVEL_INFO, "Checkpoint file is invalid, the temp download file was changed. clear the record.")
+		wosClient.logf(LEVEL_INFO, "Checkpoint file is invalid, the temp download file was changed. clear the record.")
 		return false
 	}
 
@@ -487,6 +506,9 @@ func (task *downloadPartTask) Run() interface{} {
 	if atomic.LoadInt32(task.abort) == 1 {
 		return errAbort
 	}
+	if ctx := task.wosClient.conf.ctx; isContextDone(ctx) {
+		return ctx.Err()
+	}
 	getObjectInput := &GetObjectInput{}
 	getObjectInput.GetObjectMetadataInput = task.GetObjectMetadataInput
 	getObjectInput.IfMatch = task.IfMatch
@@ -508,21 +530,21 @@ func (task *downloadPartTask) Run() interface{} {
 		defer func() {
 			errMsg := output.Body.Close()
 			if errMsg != nil {
-				doLog(LEVEL_WARN, "Failed to close response body.")
+				task.wosClient.logf(LEVEL_WARN, "Failed to close response body.")
 			}
 		}()
-		_err := updateDownloadFile(task.tempFileURL, task.RangeStart, output)
+		_err := updateDownloadFile(task.tempFileURL, task.RangeStart, output, task.wosClient)
 		if _err != nil {
 			if !task.enableCheckpoint {
 				atomic.CompareAndSwapInt32(task.abort, 0, 1)
-				doLog(LEVEL_WARN, "Task is aborted, part number is [%d]", task.partNumber)
+				task.wosClient.logf(LEVEL_WARN, "Task is aborted, part number is [%d]", task.partNumber)
 			}
 			return _err
 		}
 		return output
 	} else if wosError, ok := err.(WosError); ok && wosError.StatusCode >= 400 && wosError.StatusCode < 500 {
 		atomic.CompareAndSwapInt32(task.abort, 0, 1)
-		doLog(LEVEL_WARN, "Task is aborted, part number is [%d]", task.partNumber)
+		task.wosClient.logf(LEVEL_WARN, "Task is aborted, part number is [%d]", task.partNumber)
 	}
 	return err
 }
@@ -537,31 +559,31 @@ func getObjectInfo(input *DownloadFileInput, wosClient *WosClient, extensions []
 	return
 }
 
-func getDownloadCheckpointFile(dfc *DownloadCheckpoint, input *DownloadFileInput, output *GetObjectMetadataOutput) (needCheckpoint bool, err error) {
+func getDownloadCheckpointFile(dfc *DownloadCheckpoint, input *DownloadFileInput, output *GetObjectMetadataOutput, wosClient *WosClient) (needCheckpoint bool, err error) {
 	checkpointFilePath := input.CheckpointFile
 	checkpointFileStat, err := os.Stat(checkpointFilePath)
 	if err != nil {
-		doLog(LEVEL_DEBUG, fmt.Sprintf("Stat checkpoint file failed with error: [%v].", err))
+		wosClient.logf(LEVEL_DEBUG, "Stat checkpoint file failed with error: [%v].", err)
 		return true, nil
 	}
 	if checkpointFileStat.IsDir() {
-		doLog(LEVEL_ERROR, "Checkpoint file can not be a folder.")
+		wosClient.logf(LEVEL_ERROR, "Checkpoint file can not be a folder.")
 		return false, errors.New("checkpoint file can not be a folder")
 	}
 	err = loadCheckpointFile(checkpointFilePath, dfc)
 	if err != nil {
-		doLog(LEVEL_WARN, fmt.Sprintf("Load checkpoint file failed with error: [%v].", err))
+		wosClient.logf(LEVEL_WARN, "Load checkpoint file failed with error: [%v].", err)
 		return true, nil
-	} else if !dfc.isValid(input, output) {
+	} else if !dfc.isValid(input, output, wosClient) {
 		if dfc.TempFileInfo.TempFileUrl != "" {
 			_err := os.Remove(dfc.TempFileInfo.TempFileUrl)
 			if _err != nil {
-				doLog(LEVEL_WARN, "Failed to remove temp download file with error [%v].", _err)
+				wosClient.logf(LEVEL_WARN, "Failed to remove temp download file with error [%v].", _err)
 			}
 		}
 		_err := os.Remove(checkpointFilePath)
 		if _err != nil {
-			doLog(LEVEL_WARN, "Failed to remove checkpoint file with error [%v].", _err)
+			wosClient.logf(LEVEL_WARN, "Failed to remove checkpoint file with error [%v].", _err)
 		}
 	} else {
 		return false, nil
@@ -597,59 +619,59 @@ func sliceObject(objectSize, partSize int64, dfc *DownloadCheckpoint) {
 	}
 }
 
-func createFile(tempFileURL string, fileSize int64) error {
+func createFile(tempFileURL string, fileSize int64, wosClient *WosClient) error {
 	fd, err := syscall.Open(tempFileURL, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
 	if err != nil {
-		doLog(LEVEL_WARN, "Failed to open temp download file [%s].", tempFileURL)
+		wosClient.logf(LEVEL_WARN, "Failed to open temp download file [%s].", tempFileURL)
 		return err
 	}
 	defer func() {
 		errMsg := syscall.Close(fd)
 		if errMsg != nil {
-			doLog(LEVEL_WARN, "Failed to close file with error [%v].", errMsg)
+			wosClient.logf(LEVEL_WARN, "Failed to close file with error [%v].", errMsg)
 		}
 	}()
 	err = syscall.Ftruncate(fd, fileSize)
 	if err != nil {
-		doLog(LEVEL_WARN, "Failed to create file with error [%v].", err)
+		wosClient.logf(LEVEL_WARN, "Failed to create file with error [%v].", err)
 	}
 	return err
 }
 
-func prepareTempFile(tempFileURL string, fileSize int64) error {
+func prepareTempFile(tempFileURL string, fileSize int64, wosClient *WosClient) error {
 	parentDir := filepath.Dir(tempFileURL)
 	stat, err := os.Stat(parentDir)
 	if err != nil {
-		doLog(LEVEL_DEBUG, "Failed to stat path with error [%v].", err)
+		wosClient.logf(LEVEL_DEBUG, "Failed to stat path with error [%v].", err)
 		_err := os.MkdirAll(parentDir, os.ModePerm)
 		if _err != nil {
-			doLog(LEVEL_ERROR, "Failed to make dir with error [%v].", _err)
+			wosClient.logf(LEVEL_ERROR, "Failed to make dir with error [%v].", _err)
 			return _err
 		}
 	} else if !stat.IsDir() {
-		doLog(LEVEL_ERROR, "Cannot create folder [%s] due to a same file exists.", parentDir)
+		wosClient.logf(LEVEL_ERROR, "Cannot create folder [%s] due to a same file exists.", parentDir)
 		return fmt.Errorf("cannot create folder [%s] due to a same file exists", parentDir)
 	}
 
-	err = createFile(tempFileURL, fileSize)
+	err = createFile(tempFileURL, fileSize, wosClient)
 	if err == nil {
 		return nil
 	}
 	fd, err := os.OpenFile(tempFileURL, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
 	if err != nil {
-		doLog(LEVEL_ERROR, "Failed to open temp download file [%s].", tempFileURL)
+		wosClient.logf(LEVEL_ERROR, "Failed to open temp download file [%s].", tempFileURL)
 		return err
 	}
 	defer func() {
 		errMsg := fd.Close()
 		if errMsg != nil {
-			doLog(LEVEL_WARN, "Failed to close file with error [%v].", errMsg)
+			wosClient.logf(LEVEL_WARN, "Failed to close file with error [%v].", errMsg)
 		}
 	}()
 	if fileSize > 0 {
 		_, err = fd.WriteAt([]byte("a"), fileSize-1)
 		if err != nil {
-			doLog(LEVEL_ERROR, "Failed to create temp download file with error [%v].", err)
+			wosClient.logf(LEVEL_ERROR, "Failed to create temp download file with error [%v].", err)
 			return err
 		}
 	}
@@ -657,12 +679,12 @@ func prepareTempFile(tempFileURL string, fileSize int64) error {
 	return nil
 }
 
-func handleDownloadFileResult(tempFileURL string, enableCheckpoint bool, downloadFileError error) error {
+func handleDownloadFileResult(tempFileURL string, enableCheckpoint bool, downloadFileError error, wosClient *WosClient) error {
 	if downloadFileError != nil {
 		if !enableCheckpoint {
 			_err := os.Remove(tempFileURL)
 			if _err != nil {
-				doLog(LEVEL_WARN, "Failed to remove temp download file with error [%v].", _err)
+				wosClient.logf(LEVEL_WARN, "Failed to remove temp download file with error [%v].", _err)
 			}
 		}
 		return downloadFileError
@@ -684,7 +706,7 @@ func (wosClient WosClient) resumeDownload(input *DownloadFileInput, extensions [
 	var checkpointFilePath = input.CheckpointFile
 	var enableCheckpoint = input.EnableCheckpoint
 	if enableCheckpoint {
-		needCheckpoint, err = getDownloadCheckpointFile(dfc, input, getObjectmetaOutput)
+		needCheckpoint, err = getDownloadCheckpointFile(dfc, input, getObjectmetaOutput, &wosClient)
 		if err != nil {
 			return nil, err
 		}
@@ -703,7 +725,7 @@ func (wosClient WosClient) resumeDownload(input *DownloadFileInput, extensions [
 		dfc.TempFileInfo.Size = getObjectmetaOutput.ContentLength
 
 		sliceObject(objectSize, partSize, dfc)
-		_err := prepareTempFile(dfc.TempFileInfo.TempFileUrl, dfc.TempFileInfo.Size)
+		_err := prepareTempFile(dfc.TempFileInfo.TempFileUrl, dfc.TempFileInfo.Size, &wosClient)
 		if _err != nil {
 			return nil, _err
 		}
@@ -711,10 +733,10 @@ func (wosClient WosClient) resumeDownload(input *DownloadFileInput, extensions [
 		if enableCheckpoint {
 			_err := updateCheckpointFile(dfc, checkpointFilePath)
 			if _err != nil {
-				doLog(LEVEL_ERROR, "Failed to update checkpoint file with error [%v].", _err)
+				wosClient.logf(LEVEL_ERROR, "Failed to update checkpoint file with error [%v].", _err)
 				_errMsg := os.Remove(dfc.TempFileInfo.TempFileUrl)
 				if _errMsg != nil {
-					doLog(LEVEL_WARN, "Failed to remove temp download file with error [%v].", _errMsg)
+					wosClient.logf(LEVEL_WARN, "Failed to remove temp download file with error [%v].", _errMsg)
 				}
 				return nil, _err
 			}
@@ -722,41 +744,41 @@ func (wosClient WosClient) resumeDownload(input *DownloadFileInput, extensions [
 	}
 
 	downloadFileError := wosClient.downloadFileConcurrent(input, dfc, extensions)
-	err = handleDownloadFileResult(dfc.TempFileInfo.TempFileUrl, enableCheckpoint, downloadFileError)
+	err = handleDownloadFileResult(dfc.TempFileInfo.TempFileUrl, enableCheckpoint, downloadFileError, &wosClient)
 	if err != nil {
 		return nil, err
 	}
 
 	err = os.Rename(dfc.TempFileInfo.TempFileUrl, input.DownloadFile)
 	if err != nil {
-		doLog(LEVEL_ERROR, "Failed to rename temp download file [%s] to download file [%s] with error [%v].", dfc.TempFileInfo.TempFileUrl, input.DownloadFile, err)
+		wosClient.logf(LEVEL_ERROR, "Failed to rename temp download file [%s] to download file [%s] with error [%v].", dfc.TempFileInfo.TempFileUrl, input.DownloadFile, err)
 		return nil, err
 	}
 	if enableCheckpoint {
 		err = os.Remove(checkpointFilePath)
 		if err != nil {
-			doLog(LEVEL_WARN, "Download file successfully, but remove checkpoint file failed with error [%v].", err)
+			wosClient.logf(LEVEL_WARN, "Download file successfully, but remove checkpoint file failed with error [%v].", err)
 		}
 	}
 
 	return getObjectmetaOutput, nil
 }
 
-func updateDownloadFile(filePath string, rangeStart int64, output *GetObjectOutput) error {
+func updateDownloadFile(filePath string, rangeStart int64, output *GetObjectOutput, wosClient *WosClient) error {
 	fd, err := os.OpenFile(filePath, os.O_WRONLY, 0666)
 	if err != nil {
-		doLog(LEVEL_ERROR, "Failed to open file [%s].", filePath)
+		wosClient.logf(LEVEL_ERROR, "Failed to open file [%s].", filePath)
 		return err
 	}
 	defer func() {
 		errMsg := fd.Close()
 		if errMsg != nil {
-			doLog(LEVEL_WARN, "Failed to close file with error [%v].", errMsg)
+			wosClient.logf(LEVEL_WARN, "Failed to close file with error [%v].", errMsg)
 		}
 	}()
 	_, err = fd.Seek(rangeStart, 0)
 	if err != nil {
-		doLog(LEVEL_ERROR, "Failed to seek file with error [%v].", err)
+		wosClient.logf(LEVEL_ERROR, "Failed to seek file with error [%v].", err)
 		return err
 	}
 	fileWriter := bufio.NewWriterSize(fd, 65536)
@@ -768,17 +790,17 @@ func updateDownloadFile(filePath string, rangeStart int64, output *GetObjectOutp
 		if readCount > 0 {
 			wcnt, werr := fileWriter.Write(part[0:readCount])
 			if werr != nil {
-				doLog(LEVEL_ERROR, "Failed to write to file with error [%v].", werr)
+				wosClient.logf(LEVEL_ERROR, "Failed to write to file with error [%v].", werr)
 				return werr
 			}
 			if wcnt != readCount {
-				doLog(LEVEL_ERROR, "Failed to write to file [%s], expect: [%d], actual: [%d]", filePath, readCount, wcnt)
+				wosClient.logf(LEVEL_ERROR, "Failed to write to file [%s], expect: [%d], actual: [%d]", filePath, readCount, wcnt)
 				return fmt.Errorf("Failed to write to file [%s], expect: [%d], actual: [%d]", filePath, readCount, wcnt)
 			}
 		}
 		if readErr != nil {
 			if readErr != io.EOF {
-				doLog(LEVEL_ERROR, "Failed to read response body with error [%v].", readErr)
+				wosClient.logf(LEVEL_ERROR, "Failed to read response body with error [%v].", readErr)
 				return readErr
 			}
 			break
@@ -786,13 +808,13 @@ func updateDownloadFile(filePath string, rangeStart int64, output *GetObjectOutp
 	}
 	err = fileWriter.Flush()
 	if err != nil {
-		doLog(LEVEL_ERROR, "Failed to flush file with error [%v].", err)
+		wosClient.logf(LEVEL_ERROR, "Failed to flush file with error [%v].", err)
 		return err
 	}
 	return nil
 }
 
-func handleDownloadTaskResult(result interface{}, dfc *DownloadCheckpoint, partNum int64, enableCheckpoint bool, checkpointFile string, lock *sync.Mutex) (err error) {
+func handleDownloadTaskResult(result interface{}, dfc *DownloadCheckpoint, partNum int64, enableCheckpoint bool, checkpointFile string, lock *sync.Mutex, wosClient *WosClient) (err error) {
 	if _, ok := result.(*GetObjectOutput); ok {
 		lock.Lock()
 		defer lock.Unlock()
@@ -800,7 +822,7 @@ func handleDownloadTaskResult(result interface{}, dfc *DownloadCheckpoint, partN
 		if enableCheckpoint {
 			_err := updateCheckpointFile(dfc, checkpointFile)
 			if _err != nil {
-				doLog(LEVEL_WARN, "Failed to update checkpoint file with error [%v].", _err)
+				wosClient.logf(LEVEL_WARN, "Failed to update checkpoint file with error [%v].", _err)
 			}
 		}
 	} else if result != errAbort {
@@ -813,12 +835,14 @@ func handleDownloadTaskResult(result interface{}, dfc *DownloadCheckpoint, partN
 
 func (wosClient WosClient) downloadFileConcurrent(input *DownloadFileInput, dfc *DownloadCheckpoint, extensions []extensionOptions) error {
 	pool := NewRoutinePool(input.TaskNum, MAX_PART_NUM)
+	defer wosClient.observePool("DownloadFile", pool)()
 	var downloadPartError atomic.Value
 	var errFlag int32
 	var abort int32
 	lock := new(sync.Mutex)
+	ctx := wosClient.conf.ctx
 	for _, downloadPart := range dfc.DownloadParts {
-		if atomic.LoadInt32(&abort) == 1 {
+		if atomic.LoadInt32(&abort) == 1 || isContextDone(ctx) {
 			break
 		}
 		if downloadPart.IsCompleted {
@@ -843,7 +867,7 @@ func (wosClient WosClient) downloadFileConcurrent(input *DownloadFileInput, dfc
 		}
 		pool.ExecuteFunc(func() interface{} {
 			result := task.Run()
-			err := handleDownloadTaskResult(result, dfc, task.partNumber, input.EnableCheckpoint, input.CheckpointFile, lock)
+			err := handleDownloadTaskResult(result, dfc, task.partNumber, input.EnableCheckpoint, input.CheckpointFile, lock, &wosClient)
 			if err != nil && atomic.CompareAndSwapInt32(&errFlag, 0, 1) {
 				downloadPartError.Store(err)
 			}
@@ -851,6 +875,10 @@ func (wosClient WosClient) downloadFileConcurrent(input *DownloadFileInput, dfc
 		})
 	}
 	pool.ShutDown()
+	// the parts in flight fail with the errors of the transport once the context is done
+	if isContextDone(ctx) {
+		return ctx.Err()
+	}
 	if err, ok := downloadPartError.Load().(error); ok {
 		return err
 	}
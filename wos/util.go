@@ -84,30 +84,24 @@ func FormatUtcToRfc1123(t time.Time) string {
 // Md5 gets the md5 value of input
 func Md5(value []byte) []byte {
 	m := md5.New()
-	_, err := m.Write(value)
-	if err != nil {
-		doLog(LEVEL_WARN, "MD5 failed to write")
-	}
+	// the Write of a hash never returns an error
+	m.Write(value)
 	return m.Sum(nil)
 }
 
 // HmacSha1 gets hmac sha1 value of input
 func HmacSha1(key, value []byte) []byte {
 	mac := hmac.New(sha1.New, key)
-	_, err := mac.Write(value)
-	if err != nil {
-		doLog(LEVEL_WARN, "HmacSha1 failed to write")
-	}
+	// the Write of a hash never returns an error
+	mac.Write(value)
 	return mac.Sum(nil)
 }
 
 // HmacSha256 get hmac sha256 value if input
 func HmacSha256(key, value []byte) []byte {
 	mac := hmac.New(sha256.New, key)
-	_, err := mac.Write(value)
-	if err != nil {
-		doLog(LEVEL_WARN, "HmacSha256 failed to write")
-	}
+	// the Write of a hash never returns an error
+	mac.Write(value)
 	return mac.Sum(nil)
 }
 
@@ -134,10 +128,8 @@ func Base64Md5(value []byte) string {
 // Sha256Hash returns sha256 checksum
 func Sha256Hash(value []byte) []byte {
 	hash := sha256.New()
-	_, err := hash.Write(value)
-	if err != nil {
-		doLog(LEVEL_WARN, "Sha256Hash failed to write")
-	}
+	// the Write of a hash never returns an error
+	hash.Write(value)
 	return hash.Sum(nil)
 }
 
@@ -184,7 +176,7 @@ func UrlDecode(value string) (string, error) {
 	return "", err
 }
 
-// UrlDecodeWithoutError wrapper of UrlDecode
+// UrlDecodeWithoutError wrapper of UrlDecode, the error is logged through the global logger set by InitLog.
 func UrlDecodeWithoutError(value string) string {
 	ret, err := UrlDecode(value)
 	if err == nil {
@@ -306,7 +298,10 @@ func isPathStyle(headers map[string][]string, bucketName string) bool {
 	return false
 }
 
-// GetV2Authorization v2 Authorization
+// GetV2Authorization v2 Authorization.
+//
+// It is a package-level helper, the errors are logged through the global logger set by InitLog instead of the
+// logger of a client.
 func GetV2Authorization(ak, sk, method, bucketName, objectKey, queryURL string, headers map[string][]string) (ret map[string]string) {
 
 	if strings.HasPrefix(queryURL, "?") {
@@ -347,7 +342,7 @@ func GetV2Authorization(ak, sk, method, bucketName, objectKey, queryURL string,
 		pathStyle: pathStyle}
 	conf.signature = SignatureWos
 	_, canonicalizedURL := conf.formatUrls(bucketName, objectKey, params, false)
-	ret = v2Auth(ak, sk, method, canonicalizedURL, headers)
+	ret = WosClient{}.v2Auth(ak, sk, method, canonicalizedURL, headers)
 	v2HashPrefix := WOS_HASH_PREFIX
 	ret[HEADER_AUTH_CAMEL] = fmt.Sprintf("%s %s:%s", v2HashPrefix, ak, ret["Signature"])
 	return
@@ -402,7 +397,7 @@ func getTemporaryAndSignature(params map[string]string) (bool, string) {
 	return isTemporary, signature
 }
 
-// GetAuthorization Authorization
+// GetAuthorization Authorization, the errors are logged through the global logger as GetV2Authorization.
 func GetAuthorization(ak, sk, method, bucketName, objectKey, queryURL string, headers map[string][]string) (ret map[string]string) {
 
 	if strings.HasPrefix(queryURL, "?") {
@@ -447,7 +442,7 @@ func GetAuthorization(ak, sk, method, bucketName, objectKey, queryURL string, he
 		for _, headerKey := range headerKeys {
 			_headers[headerKey] = headers[headerKey]
 		}
-		ret = v4Auth(ak, sk, region, method, canonicalizedURL, parsedRequestURL.RawQuery, _headers, isWos)
+		ret = WosClient{}.v4Auth(ak, sk, region, method, canonicalizedURL, parsedRequestURL.RawQuery, _headers, isWos)
 
 		if isWos {
 			ret[HEADER_AUTH_CAMEL] = fmt.Sprintf("%s Credential=%s,SignedHeaders=%s,Signature=%s", V4_WOS_HASH_PREFIX, ret["Credential"], ret["SignedHeaders"], ret["Signature"])
@@ -462,7 +457,7 @@ func GetAuthorization(ak, sk, method, bucketName, objectKey, queryURL string, he
 			conf.signature = SignatureV2
 		}
 		_, canonicalizedURL := conf.formatUrls(bucketName, objectKey, params, false)
-		ret = v2Auth(ak, sk, method, canonicalizedURL, headers)
+		ret = WosClient{}.v2Auth(ak, sk, method, canonicalizedURL, headers)
 		v2HashPrefix := V2_HASH_PREFIX
 		ret[HEADER_AUTH_CAMEL] = fmt.Sprintf("%s %s:%s", v2HashPrefix, ak, ret["Signature"])
 	}
@@ -482,7 +477,7 @@ func getTemporaryAuthorization(ak, sk, method, bucketName, objectKey, signature
 			expires = params["expires"]
 		}
 		headers[HEADER_DATE_CAMEL] = []string{expires}
-		stringToSign := getV2StringToSign(method, canonicalizedURL, headers)
+		stringToSign := WosClient{}.getV2StringToSign(method, canonicalizedURL, headers)
 		ret = make(map[string]string, 3)
 		ret["Signature"] = UrlEncode(Base64Encode(HmacSha1([]byte(sk), []byte(stringToSign))), false)
 		ret["AWSAccessKeyId"] = UrlEncode(ak, false)
@@ -545,7 +540,7 @@ func getTemporaryAuthorization(ak, sk, method, bucketName, objectKey, signature
 			doLog(LEVEL_WARN, "Failed to parse requestUrl")
 			return nil
 		}
-		stringToSign := getV4StringToSign(method, canonicalizedURL, parsedRequestURL.RawQuery, scope, longDate, UNSIGNED_PAYLOAD, strings.Split(signedHeaders, ";"), headers, isWos)
+		stringToSign := WosClient{}.getV4StringToSign(method, canonicalizedURL, parsedRequestURL.RawQuery, scope, longDate, UNSIGNED_PAYLOAD, strings.Split(signedHeaders, ";"), headers, isWos)
 		ret[PARAM_SIGNATURE_AMZ_CAMEL] = UrlEncode(getSignature(stringToSign, sk, region, shortDate, isWos), false)
 	} else if signature == "v4" && isWos {
 		conf.signature = SignatureWos
@@ -605,7 +600,7 @@ func getTemporaryAuthorization(ak, sk, method, bucketName, objectKey, signature
 			doLog(LEVEL_WARN, "Failed to parse requestUrl")
 			return nil
 		}
-		stringToSign := getV4StringToSign(method, canonicalizedURL, parsedRequestURL.RawQuery, scope, longDate, UNSIGNED_PAYLOAD, strings.Split(signedHeaders, ";"), headers, isWos)
+		stringToSign := WosClient{}.getV4StringToSign(method, canonicalizedURL, parsedRequestURL.RawQuery, scope, longDate, UNSIGNED_PAYLOAD, strings.Split(signedHeaders, ";"), headers, isWos)
 		ret[PARAM_SIGNATURE_WOS_CAMEL] = UrlEncode(getSignature(stringToSign, sk, region, shortDate, isWos), false)
 	}
 	return
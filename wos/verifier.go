@@ -0,0 +1,747 @@
+package wos
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CredentialLookup returns the secret key of the access key, an error is returned if the access key is unknown.
+type CredentialLookup func(accessKey string) (secretKey string, err error)
+
+// VerifyAuthType defines where the signature of a verified request is carried
+type VerifyAuthType string
+
+const (
+	// VerifyAuthTypeHeader is the signature in the Authorization header
+	VerifyAuthTypeHeader VerifyAuthType = "Header"
+	// VerifyAuthTypeQuery is the signature in the query string of a signed URL
+	VerifyAuthTypeQuery VerifyAuthType = "Query"
+	// VerifyAuthTypePostPolicy is the signature of the policy of a browser based POST upload
+	VerifyAuthTypePostPolicy VerifyAuthType = "PostPolicy"
+)
+
+// VerifyFailureReason defines why a signature is not verified, the values are the error codes of the server.
+type VerifyFailureReason string
+
+const (
+	VerifyReasonMissingSignature     VerifyFailureReason = "MissingSecurityHeader"
+	VerifyReasonMalformedSignature   VerifyFailureReason = "AuthorizationHeaderMalformed"
+	VerifyReasonUnsupportedAlgorithm VerifyFailureReason = "UnsupportedAlgorithm"
+	VerifyReasonInvalidAccessKey     VerifyFailureReason = "InvalidAccessKeyId"
+	VerifyReasonSignatureMismatch    VerifyFailureReason = "SignatureDoesNotMatch"
+	VerifyReasonRequestExpired       VerifyFailureReason = "RequestExpired"
+	VerifyReasonTimeTooSkewed        VerifyFailureReason = "RequestTimeTooSkewed"
+	VerifyReasonInvalidPolicy        VerifyFailureReason = "InvalidPolicyDocument"
+	VerifyReasonPolicyCondition      VerifyFailureReason = "PolicyConditionFailed"
+	VerifyReasonContentSHA256        VerifyFailureReason = "XAmzContentSHA256Mismatch"
+)
+
+const (
+	DEFAULT_VERIFY_MAX_CLOCK_SKEW = 15 * time.Minute
+
+	v2AccessKeyIDParam    = "AWSAccessKeyId"
+	v2WosAccessKeyIDParam = "AccessKeyId"
+	v2ExpiresParam        = "Expires"
+	v2SignatureParam      = "Signature"
+	postPolicyField       = "policy"
+	postSignatureField    = "signature"
+	postAlgorithmField    = "algorithm"
+	postCredentialField   = "credential"
+	postDateField         = "date"
+	postFileField         = "file"
+)
+
+// VerifyError defines the reason of a failed verification
+type VerifyError struct {
+	Reason    VerifyFailureReason
+	Message   string
+	AccessKey string
+}
+
+func (err VerifyError) Error() string {
+	if err.AccessKey != "" {
+		return fmt.Sprintf("wos: signature verification failed, Reason=%s, Message=%s, AccessKey=%s", err.Reason, err.Message, err.AccessKey)
+	}
+	return fmt.Sprintf("wos: signature verification failed, Reason=%s, Message=%s", err.Reason, err.Message)
+}
+
+func newVerifyError(reason VerifyFailureReason, format string, v ...interface{}) VerifyError {
+	return VerifyError{Reason: reason, Message: fmt.Sprintf(format, v...)}
+}
+
+// VerifyResult defines the result of a verified request
+type VerifyResult struct {
+	AccessKey     string
+	Signature     SignatureType
+	AuthType      VerifyAuthType
+	Region        string
+	SignedHeaders []string
+	// Expires is the time after which the signature is not accepted, the signing time plus
+	// the MaxClockSkew of the Verifier for the Authorization header.
+	Expires time.Time
+}
+
+// Verifier verifies the signatures of the requests sent by WosClient or the signed URLs and the browser based
+// POST policies it creates, with the V2, V4 and WOS signatures. It can be used in an authenticating proxy or
+// a fake server.
+type Verifier struct {
+	// Credentials returns the secret key of an access key.
+	Credentials CredentialLookup
+	// Domain is the domain of the endpoint, such as "s3.wcsapi.com", the bucket of a virtual hosted style request
+	// is the prefix of the Host, a Host outside the Domain is a custom domain. The requests are path style if empty.
+	Domain string
+	// MaxClockSkew is the maximum difference between the signing time of a request and the clock of the Verifier.
+	MaxClockSkew time.Duration
+	// Now returns the current time, time.Now is used if nil.
+	Now func() time.Time
+}
+
+// NewVerifier creates a new Verifier instance.
+func NewVerifier(credentials CredentialLookup, domain string) *Verifier {
+	return &Verifier{Credentials: credentials, Domain: strings.ToLower(strings.TrimSpace(domain)), MaxClockSkew: DEFAULT_VERIFY_MAX_CLOCK_SKEW}
+}
+
+func (v *Verifier) now() time.Time {
+	if v.Now != nil {
+		return v.Now().UTC()
+	}
+	return time.Now().UTC()
+}
+
+func (v *Verifier) maxClockSkew() time.Duration {
+	if v.MaxClockSkew > 0 {
+		return v.MaxClockSkew
+	}
+	return DEFAULT_VERIFY_MAX_CLOCK_SKEW
+}
+
+func (v *Verifier) secretKey(accessKey string) (string, error) {
+	if v.Credentials == nil {
+		return "", VerifyError{Reason: VerifyReasonInvalidAccessKey, Message: "no credentials are set", AccessKey: accessKey}
+	}
+	sk, err := v.Credentials(accessKey)
+	if err != nil || sk == "" {
+		message := "the access key is unknown"
+		if err != nil {
+			message = err.Error()
+		}
+		return "", VerifyError{Reason: VerifyReasonInvalidAccessKey, Message: message, AccessKey: accessKey}
+	}
+	return sk, nil
+}
+
+// Verify verifies the signature in the Authorization header or the query string of the request. The request of
+// a browser based POST upload is verified with its form, which must be parsed by ParseMultipartForm before.
+// The error is a VerifyError if the signature is not verified.
+//
+// The body of a request signed with the SHA-256 of its payload by the V4 or WOS Authorization header is verified
+// while it is read: the Body of the request is replaced, and the read of its end returns a VerifyError with
+// VerifyReasonContentSHA256 if the body does not match. The body must be read to the end before the request is
+// accepted.
+func (v *Verifier) Verify(r *http.Request) (*VerifyResult, error) {
+	if authorization := r.Header.Get(HEADER_AUTH_CAMEL); authorization != "" {
+		return v.verifyHeader(r, authorization)
+	}
+	query := r.URL.Query()
+	if query.Get(PARAM_SIGNATURE_WOS_CAMEL) != "" || query.Get(PARAM_SIGNATURE_AMZ_CAMEL) != "" {
+		return v.verifyV4Query(r, query)
+	}
+	if query.Get(v2SignatureParam) != "" {
+		return v.verifyV2Query(r, query)
+	}
+	if r.Method == HTTP_POST && r.MultipartForm != nil {
+		fields := make(map[string]string, len(r.MultipartForm.Value)+1)
+		for key, values := range r.MultipartForm.Value {
+			if len(values) > 0 {
+				fields[key] = values[0]
+			}
+		}
+		// the bucket of the request is checked against the policy and is not a field of the form
+		var requestBucket bool
+		if bucket, _ := v.getBucket(r); bucket != "" {
+			if _, ok := fields["bucket"]; !ok {
+				fields["bucket"] = bucket
+				requestBucket = true
+			}
+		}
+		contentLength := int64(-1)
+		if files := r.MultipartForm.File[postFileField]; len(files) > 0 {
+			contentLength = files[0].Size
+		}
+		return v.verifyPostPolicy(fields, contentLength, requestBucket)
+	}
+	return nil, newVerifyError(VerifyReasonMissingSignature, "no signature is found in the request")
+}
+
+// getBucket returns the bucket of the request and whether it is in the Host, the bucket of a custom domain is the Host.
+func (v *Verifier) getBucket(r *http.Request) (bucket string, virtualHosted bool) {
+	host := strings.ToLower(r.Host)
+	if _host, _, err := net.SplitHostPort(host); err == nil {
+		host = _host
+	}
+	if v.Domain != "" && !IsIP(host) {
+		if strings.HasSuffix(host, "."+v.Domain) {
+			return host[:len(host)-len(v.Domain)-1], true
+		}
+		if host != v.Domain {
+			return host, true
+		}
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	if index := strings.Index(path, "/"); index >= 0 {
+		path = path[:index]
+	}
+	return path, false
+}
+
+// getCanonicalizedResource returns the resource signed by the V2 signature, the parameters of the signed url are excluded.
+func (v *Verifier) getCanonicalizedResource(r *http.Request, excludes ...string) string {
+	resource := r.URL.EscapedPath()
+	if resource == "" {
+		resource = "/"
+	}
+	if bucket, virtualHosted := v.getBucket(r); virtualHosted {
+		resource = "/" + bucket + resource
+	}
+
+	query := r.URL.Query()
+	for _, exclude := range excludes {
+		query.Del(exclude)
+	}
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		lowerKey := strings.ToLower(key)
+		if allowedResourceParameterNames[lowerKey] || strings.HasPrefix(lowerKey, HEADER_PREFIX) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for i, key := range keys {
+		if i == 0 {
+			resource += "?"
+		} else {
+			resource += "&"
+		}
+		resource += url.QueryEscape(key)
+		if value := query.Get(key); value != "" {
+			resource += "=" + value
+		}
+	}
+	return resource
+}
+
+func getVerifyHeaders(r *http.Request) map[string][]string {
+	headers := make(map[string][]string, len(r.Header)+1)
+	for key, values := range r.Header {
+		headers[strings.ToLower(key)] = values
+	}
+	headers[HEADER_HOST] = []string{r.Host}
+	return headers
+}
+
+func parseVerifyDate(value string) (time.Time, bool) {
+	if t, err := time.Parse(LONG_DATE_FORMAT, value); err == nil {
+		return t, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return t.UTC(), true
+	}
+	return time.Time{}, false
+}
+
+func (v *Verifier) checkClockSkew(date time.Time, accessKey string) error {
+	now := v.now()
+	if skew := now.Sub(date); skew > v.maxClockSkew() || skew < -v.maxClockSkew() {
+		return VerifyError{Reason: VerifyReasonTimeTooSkewed, Message: fmt.Sprintf("the difference between the request time %s and the current time %s is too large",
+			date.Format(ISO8601_DATE_FORMAT), now.Format(ISO8601_DATE_FORMAT)), AccessKey: accessKey}
+	}
+	return nil
+}
+
+func compareSignature(expected, actual string, accessKey string) error {
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(actual)) != 1 {
+		return VerifyError{Reason: VerifyReasonSignatureMismatch, Message: "the calculated signature does not match the signature provided", AccessKey: accessKey}
+	}
+	return nil
+}
+
+func (v *Verifier) verifyHeader(r *http.Request, authorization string) (*VerifyResult, error) {
+	index := strings.Index(authorization, " ")
+	if index < 0 {
+		return nil, newVerifyError(VerifyReasonMalformedSignature, "the Authorization header is malformed")
+	}
+	algorithm, value := authorization[:index], strings.TrimSpace(authorization[index+1:])
+	switch algorithm {
+	case V4_HASH_PREFIX, V4_WOS_HASH_PREFIX:
+		return v.verifyV4Header(r, algorithm == V4_WOS_HASH_PREFIX, value)
+	case V2_HASH_PREFIX, WOS_HASH_PREFIX:
+		return v.verifyV2Header(r, value)
+	}
+	return nil, newVerifyError(VerifyReasonUnsupportedAlgorithm, "the algorithm %s is not supported", algorithm)
+}
+
+func (v *Verifier) verifyV2Header(r *http.Request, value string) (*VerifyResult, error) {
+	index := strings.LastIndex(value, ":")
+	if index <= 0 {
+		return nil, newVerifyError(VerifyReasonMalformedSignature, "the Authorization header is malformed")
+	}
+	accessKey, signature := value[:index], value[index+1:]
+	dateValue := r.Header.Get(HEADER_DATE_AMZ)
+	if dateValue == "" {
+		dateValue = r.Header.Get(HEADER_DATE_CAMEL)
+	}
+	date, ok := parseVerifyDate(dateValue)
+	if !ok {
+		return nil, VerifyError{Reason: VerifyReasonMalformedSignature, Message: "the date of the request is missing or malformed", AccessKey: accessKey}
+	}
+	if err := v.checkClockSkew(date, accessKey); err != nil {
+		return nil, err
+	}
+	sk, err := v.secretKey(accessKey)
+	if err != nil {
+		return nil, err
+	}
+	stringToSign := WosClient{}.getV2StringToSign(r.Method, v.getCanonicalizedResource(r), getVerifyHeaders(r))
+	if err = compareSignature(Base64Encode(HmacSha1([]byte(sk), []byte(stringToSign))), signature, accessKey); err != nil {
+		return nil, err
+	}
+	return &VerifyResult{AccessKey: accessKey, Signature: SignatureV2, AuthType: VerifyAuthTypeHeader, Expires: date.Add(v.maxClockSkew())}, nil
+}
+
+func (v *Verifier) verifyV2Query(r *http.Request, values url.Values) (*VerifyResult, error) {
+	accessKey := values.Get(v2AccessKeyIDParam)
+	if accessKey == "" {
+		accessKey = values.Get(v2WosAccessKeyIDParam)
+	}
+	expiresValue := values.Get(v2ExpiresParam)
+	if accessKey == "" || expiresValue == "" {
+		return nil, newVerifyError(VerifyReasonMalformedSignature, "the query parameters of the signed url are incomplete")
+	}
+	expiresUnix, err := strconv.ParseInt(expiresValue, 10, 64)
+	if err != nil {
+		return nil, VerifyError{Reason: VerifyReasonMalformedSignature, Message: "the Expires is not a number", AccessKey: accessKey}
+	}
+	expires := time.Unix(expiresUnix, 0).UTC()
+	if v.now().After(expires) {
+		return nil, VerifyError{Reason: VerifyReasonRequestExpired, Message: "the signed url is expired at " + expires.Format(ISO8601_DATE_FORMAT), AccessKey: accessKey}
+	}
+	sk, err := v.secretKey(accessKey)
+	if err != nil {
+		return nil, err
+	}
+	headers := getVerifyHeaders(r)
+	headers[strings.ToLower(HEADER_DATE_CAMEL)] = []string{expiresValue}
+	delete(headers, HEADER_DATE_AMZ)
+	resource := v.getCanonicalizedResource(r, v2AccessKeyIDParam, v2WosAccessKeyIDParam, v2ExpiresParam, v2SignatureParam)
+	stringToSign := WosClient{}.getV2StringToSign(r.Method, resource, headers)
+	if err = compareSignature(Base64Encode(HmacSha1([]byte(sk), []byte(stringToSign))), values.Get(v2SignatureParam), accessKey); err != nil {
+		return nil, err
+	}
+	return &VerifyResult{AccessKey: accessKey, Signature: SignatureV2, AuthType: VerifyAuthTypeQuery, Expires: expires}, nil
+}
+
+// v4Credential is the parsed credential of the V4 and WOS signatures: ak/date/region/service/terminal.
+type v4Credential struct {
+	accessKey string
+	shortDate string
+	region    string
+}
+
+func parseV4Credential(credential string, isWos bool) (*v4Credential, error) {
+	parts := strings.Split(credential, "/")
+	if len(parts) != 5 {
+		return nil, newVerifyError(VerifyReasonMalformedSignature, "the credential %s is malformed", credential)
+	}
+	service, terminal := V4_SERVICE_NAME, V4_SERVICE_SUFFIX
+	if isWos {
+		service, terminal = V4_WOS_SERVICE_NAME, V4_WOS_SERVICE_SUFFIX
+	}
+	if parts[3] != service || parts[4] != terminal {
+		return nil, VerifyError{Reason: VerifyReasonMalformedSignature, Message: fmt.Sprintf("the scope of the credential must be %s/%s", service, terminal), AccessKey: parts[0]}
+	}
+	return &v4Credential{accessKey: parts[0], shortDate: parts[1], region: parts[2]}, nil
+}
+
+func (v *Verifier) verifyV4Header(r *http.Request, isWos bool, value string) (*VerifyResult, error) {
+	fields := make(map[string]string, 3)
+	for _, field := range strings.Split(value, ",") {
+		if index := strings.Index(field, "="); index > 0 {
+			fields[strings.TrimSpace(field[:index])] = strings.TrimSpace(field[index+1:])
+		}
+	}
+	if fields["Credential"] == "" || fields["SignedHeaders"] == "" || fields["Signature"] == "" {
+		return nil, newVerifyError(VerifyReasonMalformedSignature, "the Authorization header must contain Credential, SignedHeaders and Signature")
+	}
+	credential, err := parseV4Credential(fields["Credential"], isWos)
+	if err != nil {
+		return nil, err
+	}
+	signedHeaders := strings.Split(fields["SignedHeaders"], ";")
+	hostSigned := false
+	for _, signedHeader := range signedHeaders {
+		hostSigned = hostSigned || signedHeader == HEADER_HOST
+	}
+	// a signature without the host could be replayed to another bucket of the virtual hosted style
+	if !hostSigned {
+		return nil, VerifyError{Reason: VerifyReasonMalformedSignature, Message: "the SignedHeaders must contain host", AccessKey: credential.accessKey}
+	}
+	dateValue := r.Header.Get(HEADER_DATE_WOS)
+	if !isWos || dateValue == "" {
+		if _dateValue := r.Header.Get(HEADER_DATE_AMZ); _dateValue != "" {
+			dateValue = _dateValue
+		}
+	}
+	if dateValue == "" {
+		dateValue = r.Header.Get(HEADER_DATE_CAMEL)
+	}
+	date, ok := parseVerifyDate(dateValue)
+	if !ok {
+		return nil, VerifyError{Reason: VerifyReasonMalformedSignature, Message: "the date of the request is missing or malformed", AccessKey: credential.accessKey}
+	}
+	if err = v.checkClockSkew(date, credential.accessKey); err != nil {
+		return nil, err
+	}
+	payload := r.Header.Get(HEADER_CONTENT_SHA256_AMZ)
+	if isWos {
+		payload = r.Header.Get(HEADER_CONTENT_SHA256_WOS)
+	}
+	if payload == "" {
+		payload = UNSIGNED_PAYLOAD
+	}
+	if err = v.checkV4Signature(r, isWos, credential, date, r.URL.RawQuery, payload, signedHeaders, fields["Signature"]); err != nil {
+		return nil, err
+	}
+	if err = verifyPayload(r, payload, credential.accessKey); err != nil {
+		return nil, err
+	}
+	return &VerifyResult{AccessKey: credential.accessKey, Signature: getV4SignatureType(isWos), AuthType: VerifyAuthTypeHeader,
+		Region: credential.region, SignedHeaders: signedHeaders, Expires: date.Add(v.maxClockSkew())}, nil
+}
+
+func (v *Verifier) verifyV4Query(r *http.Request, values url.Values) (*VerifyResult, error) {
+	isWos := values.Get(PARAM_SIGNATURE_WOS_CAMEL) != ""
+	algorithmParam, credentialParam, dateParam := PARAM_ALGORITHM_AMZ_CAMEL, PARAM_CREDENTIAL_AMZ_CAMEL, PARAM_DATE_AMZ_CAMEL
+	expiresParam, signedHeadersParam, signatureParam := PARAM_EXPIRES_AMZ_CAMEL, PARAM_SIGNEDHEADERS_AMZ_CAMEL, PARAM_SIGNATURE_AMZ_CAMEL
+	algorithm := V4_HASH_PREFIX
+	if isWos {
+		algorithmParam, credentialParam, dateParam = PARAM_ALGORITHM_WOS_CAMEL, PARAM_CREDENTIAL_WOS_CAMEL, PARAM_DATE_WOS_CAMEL
+		expiresParam, signedHeadersParam, signatureParam = PARAM_EXPIRES_WOS_CAMEL, PARAM_SIGNEDHEADERS_WOS_CAMEL, PARAM_SIGNATURE_WOS_CAMEL
+		algorithm = V4_WOS_HASH_PREFIX
+	}
+	if values.Get(algorithmParam) != algorithm {
+		return nil, newVerifyError(VerifyReasonUnsupportedAlgorithm, "the algorithm %s is not supported", values.Get(algorithmParam))
+	}
+	credential, err := parseV4Credential(values.Get(credentialParam), isWos)
+	if err != nil {
+		return nil, err
+	}
+	date, err := time.Parse(LONG_DATE_FORMAT, values.Get(dateParam))
+	if err != nil {
+		return nil, VerifyError{Reason: VerifyReasonMalformedSignature, Message: "the " + dateParam + " is malformed", AccessKey: credential.accessKey}
+	}
+	seconds, err := strconv.ParseInt(values.Get(expiresParam), 10, 64)
+	if err != nil || seconds <= 0 {
+		return nil, VerifyError{Reason: VerifyReasonMalformedSignature, Message: "the " + expiresParam + " is malformed", AccessKey: credential.accessKey}
+	}
+	expires := date.Add(time.Duration(seconds) * time.Second)
+	now := v.now()
+	if now.After(expires) {
+		return nil, VerifyError{Reason: VerifyReasonRequestExpired, Message: "the signed url is expired at " + expires.Format(ISO8601_DATE_FORMAT), AccessKey: credential.accessKey}
+	}
+	if date.Sub(now) > v.maxClockSkew() {
+		return nil, VerifyError{Reason: VerifyReasonTimeTooSkewed, Message: "the signed url is not valid until " + date.Format(ISO8601_DATE_FORMAT), AccessKey: credential.accessKey}
+	}
+
+	// the signature is the last parameter of the signed url and is not signed
+	parts := strings.Split(r.URL.RawQuery, "&")
+	rawQuery := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part != "" && !strings.HasPrefix(part, signatureParam+"=") {
+			rawQuery = append(rawQuery, part)
+		}
+	}
+	signedHeaders := strings.Split(values.Get(signedHeadersParam), ";")
+	if err = v.checkV4Signature(r, isWos, credential, date, strings.Join(rawQuery, "&"), UNSIGNED_PAYLOAD, signedHeaders, values.Get(signatureParam)); err != nil {
+		return nil, err
+	}
+	return &VerifyResult{AccessKey: credential.accessKey, Signature: getV4SignatureType(isWos), AuthType: VerifyAuthTypeQuery,
+		Region: credential.region, SignedHeaders: signedHeaders, Expires: expires}, nil
+}
+
+func (v *Verifier) checkV4Signature(r *http.Request, isWos bool, credential *v4Credential, date time.Time, rawQuery, payload string,
+	signedHeaders []string, signature string) error {
+	shortDate := date.Format(SHORT_DATE_FORMAT)
+	if credential.shortDate != shortDate {
+		return VerifyError{Reason: VerifyReasonMalformedSignature, Message: "the date of the credential does not match the date of the request", AccessKey: credential.accessKey}
+	}
+	sk, err := v.secretKey(credential.accessKey)
+	if err != nil {
+		return err
+	}
+	requestHeaders := getVerifyHeaders(r)
+	headers := make(map[string][]string, len(signedHeaders))
+	for _, signedHeader := range signedHeaders {
+		headers[signedHeader] = requestHeaders[signedHeader]
+	}
+	path := r.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	scope := getScope(credential.region, shortDate, isWos)
+	stringToSign := WosClient{}.getV4StringToSign(r.Method, path, rawQuery, scope, date.Format(LONG_DATE_FORMAT), payload, signedHeaders, headers, isWos)
+	return compareSignature(getSignature(stringToSign, sk, credential.region, shortDate, isWos), signature, credential.accessKey)
+}
+
+// verifyPayload checks the body of the request against the signed SHA-256 of the payload while it is read,
+// the unsigned and the streaming payloads are not checked.
+func verifyPayload(r *http.Request, payload, accessKey string) error {
+	if payload == UNSIGNED_PAYLOAD || strings.HasPrefix(payload, "STREAMING-") {
+		return nil
+	}
+	expected, err := hex.DecodeString(payload)
+	if err != nil || len(expected) != sha256.Size {
+		return VerifyError{Reason: VerifyReasonMalformedSignature, Message: "the SHA-256 of the payload is malformed", AccessKey: accessKey}
+	}
+	if r.Body == nil || r.Body == http.NoBody {
+		if payload != EMPTY_CONTENT_SHA256 {
+			return VerifyError{Reason: VerifyReasonContentSHA256, Message: "the SHA-256 of the payload does not match the empty body", AccessKey: accessKey}
+		}
+		return nil
+	}
+	r.Body = &payloadVerifyReader{ReadCloser: r.Body, hash: sha256.New(), expected: expected, accessKey: accessKey}
+	return nil
+}
+
+// payloadVerifyReader hashes the body read through it, the read of the end fails if the hash does not match.
+type payloadVerifyReader struct {
+	io.ReadCloser
+	hash      hash.Hash
+	expected  []byte
+	accessKey string
+}
+
+func (r *payloadVerifyReader) Read(p []byte) (n int, err error) {
+	n, err = r.ReadCloser.Read(p)
+	// the Write of a hash never returns an error
+	r.hash.Write(p[:n])
+	if err == io.EOF && subtle.ConstantTimeCompare(r.hash.Sum(nil), r.expected) != 1 {
+		err = VerifyError{Reason: VerifyReasonContentSHA256, Message: "the SHA-256 of the payload does not match the body", AccessKey: r.accessKey}
+	}
+	return
+}
+
+func getV4SignatureType(isWos bool) SignatureType {
+	if isWos {
+		return SignatureWos
+	}
+	return SignatureV4
+}
+
+// trailingCommaRegex matches the trailing comma of the conditions of the policies created by the previous versions
+// of CreateBrowserBasedSignature.
+var trailingCommaRegex = regexp.MustCompile(`,\s*([\]}])`)
+
+// VerifyPostPolicy verifies the policy and the signature of a browser based POST upload with the fields of its form,
+// the bucket must be set in the fields if it is a condition of the policy. The contentLength is the size of the file
+// checked against the content-length-range condition, it is not checked if negative.
+//
+// Each field of the form must be allowed by a condition of the policy, except the policy, the signature, the file
+// and the credential fields.
+func (v *Verifier) VerifyPostPolicy(fields map[string]string, contentLength int64) (*VerifyResult, error) {
+	return v.verifyPostPolicy(fields, contentLength, false)
+}
+
+// postExemptFields are the fields of the form which are not restricted by the conditions of the policy.
+var postExemptFields = map[string]bool{
+	postPolicyField:                         true,
+	postSignatureField:                      true,
+	postFileField:                           true,
+	strings.ToLower(v2AccessKeyIDParam):     true,
+	HEADER_PREFIX + postSignatureField:      true,
+	HEADER_PREFIX_WOS + postSignatureField:  true,
+	HEADER_PREFIX + postCredentialField:     true,
+	HEADER_PREFIX_WOS + postCredentialField: true,
+	HEADER_PREFIX + postAlgorithmField:      true,
+	HEADER_PREFIX_WOS + postAlgorithmField:  true,
+	HEADER_PREFIX + postDateField:           true,
+	HEADER_PREFIX_WOS + postDateField:       true,
+}
+
+// verifyPostPolicy verifies the fields of the form, the bucket field is the one of the request if requestBucket is true.
+func (v *Verifier) verifyPostPolicy(fields map[string]string, contentLength int64, requestBucket bool) (*VerifyResult, error) {
+	_fields := make(map[string]string, len(fields))
+	for key, value := range fields {
+		_fields[strings.ToLower(key)] = value
+	}
+	policy := _fields[postPolicyField]
+	if policy == "" {
+		return nil, newVerifyError(VerifyReasonMissingSignature, "the policy is missing in the form")
+	}
+
+	var result *VerifyResult
+	var err error
+	if accessKey := _fields[strings.ToLower(v2AccessKeyIDParam)]; accessKey != "" {
+		result, err = v.verifyV2PostSignature(accessKey, policy, _fields[postSignatureField])
+	} else {
+		result, err = v.verifyV4PostSignature(_fields, policy)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	originPolicy, err := base64.StdEncoding.DecodeString(policy)
+	if err != nil {
+		return nil, VerifyError{Reason: VerifyReasonInvalidPolicy, Message: "the policy is not base64 encoded", AccessKey: result.AccessKey}
+	}
+	document := struct {
+		Expiration string        `json:"expiration"`
+		Conditions []interface{} `json:"conditions"`
+	}{}
+	if err = json.Unmarshal(trailingCommaRegex.ReplaceAll(originPolicy, []byte("$1")), &document); err != nil {
+		return nil, VerifyError{Reason: VerifyReasonInvalidPolicy, Message: "the policy is not a valid json: " + err.Error(), AccessKey: result.AccessKey}
+	}
+	expiration, err := time.Parse(time.RFC3339, document.Expiration)
+	if err != nil {
+		return nil, VerifyError{Reason: VerifyReasonInvalidPolicy, Message: "the expiration of the policy is malformed", AccessKey: result.AccessKey}
+	}
+	if v.now().After(expiration) {
+		return nil, VerifyError{Reason: VerifyReasonRequestExpired, Message: "the policy is expired at " + expiration.Format(ISO8601_DATE_FORMAT), AccessKey: result.AccessKey}
+	}
+	result.Expires = expiration.UTC()
+	covered := make(map[string]bool, len(document.Conditions))
+	for _, condition := range document.Conditions {
+		if err = checkPolicyCondition(condition, _fields, contentLength, covered); err != nil {
+			_err := err.(VerifyError)
+			_err.AccessKey = result.AccessKey
+			return nil, _err
+		}
+	}
+	for field := range _fields {
+		if covered[field] || postExemptFields[field] || requestBucket && field == postFieldBucket {
+			continue
+		}
+		return nil, VerifyError{Reason: VerifyReasonPolicyCondition, Message: fmt.Sprintf("the field %s is not allowed by the policy", field),
+			AccessKey: result.AccessKey}
+	}
+	return result, nil
+}
+
+func (v *Verifier) verifyV2PostSignature(accessKey, policy, signature string) (*VerifyResult, error) {
+	sk, err := v.secretKey(accessKey)
+	if err != nil {
+		return nil, err
+	}
+	if err = compareSignature(Base64Encode(HmacSha1([]byte(sk), []byte(policy))), signature, accessKey); err != nil {
+		return nil, err
+	}
+	return &VerifyResult{AccessKey: accessKey, Signature: SignatureV2, AuthType: VerifyAuthTypePostPolicy}, nil
+}
+
+func (v *Verifier) verifyV4PostSignature(fields map[string]string, policy string) (*VerifyResult, error) {
+	isWos := fields[HEADER_PREFIX_WOS+postCredentialField] != ""
+	prefix := HEADER_PREFIX
+	if isWos {
+		prefix = HEADER_PREFIX_WOS
+	}
+	if algorithm := fields[prefix+postAlgorithmField]; algorithm != "" && algorithm != V4_HASH_PREFIX && algorithm != V4_WOS_HASH_PREFIX {
+		return nil, newVerifyError(VerifyReasonUnsupportedAlgorithm, "the algorithm %s is not supported", algorithm)
+	}
+	credentialValue := fields[prefix+postCredentialField]
+	if credentialValue == "" {
+		return nil, newVerifyError(VerifyReasonMissingSignature, "the credential is missing in the form")
+	}
+	credential, err := parseV4Credential(credentialValue, isWos)
+	if err != nil {
+		return nil, err
+	}
+	if date, ok := parseVerifyDate(fields[prefix+postDateField]); ok && date.Format(SHORT_DATE_FORMAT) != credential.shortDate {
+		return nil, VerifyError{Reason: VerifyReasonMalformedSignature, Message: "the date of the credential does not match the date of the form", AccessKey: credential.accessKey}
+	}
+	signature := fields[postSignatureField]
+	if signature == "" {
+		signature = fields[strings.ToLower(prefix+"signature")]
+	}
+	sk, err := v.secretKey(credential.accessKey)
+	if err != nil {
+		return nil, err
+	}
+	if err = compareSignature(getSignature(policy, sk, credential.region, credential.shortDate, isWos), signature, credential.accessKey); err != nil {
+		return nil, err
+	}
+	return &VerifyResult{AccessKey: credential.accessKey, Signature: getV4SignatureType(isWos), AuthType: VerifyAuthTypePostPolicy, Region: credential.region}, nil
+}
+
+// checkPolicyCondition checks a condition of the policy: {"field":"value"}, ["eq", "$field", "value"],
+// ["starts-with", "$field", "prefix"] or ["content-length-range", min, max]. The fields restricted by the condition
+// are added to covered.
+func checkPolicyCondition(condition interface{}, fields map[string]string, contentLength int64, covered map[string]bool) error {
+	switch c := condition.(type) {
+	case map[string]interface{}:
+		for key, value := range c {
+			if err := checkPolicyMatch("eq", key, fmt.Sprint(value), fields); err != nil {
+				return err
+			}
+			covered[strings.ToLower(key)] = true
+		}
+		return nil
+	case []interface{}:
+		if len(c) != 3 {
+			return newVerifyError(VerifyReasonInvalidPolicy, "the condition %v is malformed", c)
+		}
+		operator, _ := c[0].(string)
+		operator = strings.ToLower(operator)
+		if operator == "content-length-range" {
+			min, minOk := c[1].(float64)
+			max, maxOk := c[2].(float64)
+			if !minOk || !maxOk {
+				return newVerifyError(VerifyReasonInvalidPolicy, "the condition %v is malformed", c)
+			}
+			if contentLength >= 0 && (contentLength < int64(min) || contentLength > int64(max)) {
+				return newVerifyError(VerifyReasonPolicyCondition, "the size %d of the file is not in the range [%d, %d]", contentLength, int64(min), int64(max))
+			}
+			return nil
+		}
+		field, _ := c[1].(string)
+		if !strings.HasPrefix(field, "$") {
+			return newVerifyError(VerifyReasonInvalidPolicy, "the condition %v is malformed", c)
+		}
+		if err := checkPolicyMatch(operator, field[1:], fmt.Sprint(c[2]), fields); err != nil {
+			return err
+		}
+		covered[strings.ToLower(field[1:])] = true
+		return nil
+	}
+	return newVerifyError(VerifyReasonInvalidPolicy, "the condition %v is malformed", condition)
+}
+
+func checkPolicyMatch(operator, field, value string, fields map[string]string) error {
+	field = strings.ToLower(field)
+	actual := fields[field]
+	switch operator {
+	case "eq":
+		if actual != value {
+			return newVerifyError(VerifyReasonPolicyCondition, "the field %s must be %q", field, value)
+		}
+	case "starts-with":
+		if !strings.HasPrefix(actual, value) {
+			return newVerifyError(VerifyReasonPolicyCondition, "the field %s must start with %q", field, value)
+		}
+	default:
+		return newVerifyError(VerifyReasonInvalidPolicy, "the operator %s is not supported", operator)
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package wos
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testCredentials(accessKey string) (string, error) {
+	if accessKey != "ak" {
+		return "", errors.New("unknown access key")
+	}
+	return "sk", nil
+}
+
+// verifyServer verifies the requests and reads their bodies, the requests which are not verified are rejected with 403.
+type verifyServer struct {
+	*httptest.Server
+	lock   sync.Mutex
+	result *VerifyResult
+	err    error
+}
+
+func newVerifyServer(t *testing.T) *verifyServer {
+	server := &verifyServer{}
+	verifier := NewVerifier(testCredentials, "")
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		result, err := verifier.Verify(r)
+		if err == nil && r.Body != nil {
+			_, err = io.Copy(ioutil.Discard, r.Body)
+		}
+		server.lock.Lock()
+		server.result, server.err = result, err
+		server.lock.Unlock()
+		if err != nil {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (server *verifyServer) last() (*VerifyResult, error) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	return server.result, server.err
+}
+
+func TestVerifierRoundTrip(t *testing.T) {
+	cases := []struct {
+		name      string
+		signature SignatureType
+		authType  VerifyAuthType
+	}{
+		{"v2 header", SignatureV2, VerifyAuthTypeHeader},
+		{"v2 query", SignatureV2, VerifyAuthTypeQuery},
+		{"v4 header", SignatureV4, VerifyAuthTypeHeader},
+		{"v4 query", SignatureV4, VerifyAuthTypeQuery},
+		{"wos header", SignatureWos, VerifyAuthTypeHeader},
+		{"wos query", SignatureWos, VerifyAuthTypeQuery},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			server := newVerifyServer(t)
+			client := newTestClient(t, server.URL, WithSignature(c.signature), WithPayloadSigning(true), WithMaxRetryCount(0))
+			if c.authType == VerifyAuthTypeHeader {
+				input := &PutObjectInput{Body: strings.NewReader("data")}
+				input.Bucket, input.Key = "bucket", "dir/key"
+				input.Metadata = map[string]string{"owner": "wos"}
+				if _, err := client.PutObject(input); err != nil {
+					_, verifyErr := server.last()
+					t.Fatalf("PutObject error = %v, verify error = %v", err, verifyErr)
+				}
+			} else {
+				output, err := client.CreateSignedUrl(&CreateSignedUrlInput{Method: HttpMethodGet, Bucket: "bucket", Key: "dir/key", Expires: 300})
+				if err != nil {
+					t.Fatal(err)
+				}
+				req, _ := http.NewRequest(HTTP_GET, output.SignedUrl, nil)
+				for key, values := range output.ActualSignedRequestHeaders {
+					req.Header[key] = values
+				}
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp.Body.Close()
+				if resp.StatusCode != http.StatusOK {
+					_, verifyErr := server.last()
+					t.Fatalf("status = %d, verify error = %v", resp.StatusCode, verifyErr)
+				}
+			}
+			result, _ := server.last()
+			if result.AccessKey != "ak" || result.Signature != c.signature || result.AuthType != c.authType {
+				t.Errorf("result = %+v, want %s %s", result, c.signature, c.authType)
+			}
+		})
+	}
+}
+
+func TestVerifierPayloadMismatch(t *testing.T) {
+	for _, signature := range []SignatureType{SignatureV4, SignatureWos} {
+		t.Run(string(signature), func(t *testing.T) {
+			server := newVerifyServer(t)
+			tamper := MiddlewareFuncs{AfterSignFunc: func(req *http.Request) error {
+				req.Body = ioutil.NopCloser(strings.NewReader("evil"))
+				return nil
+			}}
+			client := newTestClient(t, server.URL, WithSignature(signature), WithPayloadSigning(true), WithMaxRetryCount(0), WithMiddleware(tamper))
+			input := &PutObjectInput{Body: strings.NewReader("data")}
+			input.Bucket, input.Key = "bucket", "key"
+			if _, err := client.PutObject(input); err == nil {
+				t.Fatal("PutObject succeeded with a tampered body")
+			}
+			if _, err := server.last(); err == nil || err.(VerifyError).Reason != VerifyReasonContentSHA256 {
+				t.Errorf("verify error = %v, want %s", err, VerifyReasonContentSHA256)
+			}
+		})
+	}
+}
+
+func TestVerifierHostNotSigned(t *testing.T) {
+	cases := []struct {
+		name          string
+		isWos         bool
+		signedHeaders []string
+		wantErr       bool
+	}{
+		{"v4 with host", false, []string{HEADER_HOST, HEADER_DATE_AMZ}, false},
+		{"v4 without host", false, []string{HEADER_DATE_AMZ}, true},
+		{"wos with host", true, []string{HEADER_HOST, HEADER_DATE_WOS}, false},
+		{"wos without host", true, []string{HEADER_DATE_WOS}, true},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			date := time.Now().UTC()
+			longDate, shortDate := date.Format(LONG_DATE_FORMAT), date.Format(SHORT_DATE_FORMAT)
+			algorithm, dateHeader := V4_HASH_PREFIX, HEADER_DATE_AMZ
+			if c.isWos {
+				algorithm, dateHeader = V4_WOS_HASH_PREFIX, HEADER_DATE_WOS
+			}
+			r := httptest.NewRequest(HTTP_GET, "http://bucket.wos.example.com/key", nil)
+			r.Header.Set(dateHeader, longDate)
+
+			// the request is signed correctly with the signed headers
+			headers := map[string][]string{HEADER_HOST: {r.Host}, dateHeader: {longDate}}
+			credential, scope := getCredential("ak", "region", shortDate, c.isWos)
+			stringToSign := WosClient{}.getV4StringToSign(HTTP_GET, "/key", "", scope, longDate, UNSIGNED_PAYLOAD, c.signedHeaders, headers, c.isWos)
+			r.Header.Set(HEADER_AUTH_CAMEL, fmt.Sprintf("%s Credential=%s,SignedHeaders=%s,Signature=%s", algorithm, credential,
+				strings.Join(c.signedHeaders, ";"), getSignature(stringToSign, "sk", "region", shortDate, c.isWos)))
+
+			_, err := NewVerifier(testCredentials, "").Verify(r)
+			if !c.wantErr {
+				if err != nil {
+					t.Fatalf("Verify error = %v", err)
+				}
+				return
+			}
+			if verifyErr, ok := err.(VerifyError); !ok || verifyErr.Reason != VerifyReasonMalformedSignature || verifyErr.AccessKey != "ak" {
+				t.Errorf("Verify error = %v, want %s", err, VerifyReasonMalformedSignature)
+			}
+		})
+	}
+}
+
+func TestVerifyPostPolicyFields(t *testing.T) {
+	client := newTestClient(t, "http://127.0.0.1:1", WithSignature(SignatureV4))
+	policy, err := client.CreatePostPolicy(NewPostPolicy("bucket").SetKey("key").SetContentType("text/plain").SetMetadataStartsWith("owner", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := NewVerifier(testCredentials, "")
+	cases := []struct {
+		name   string
+		extra  map[string]string
+		reason VerifyFailureReason
+	}{
+		{"form fields", nil, ""},
+		{"covered by starts-with", map[string]string{"x-amz-meta-owner": "wos"}, ""},
+		{"file field", map[string]string{postFileField: "data"}, ""},
+		{"extra field", map[string]string{"acl": "public-read"}, VerifyReasonPolicyCondition},
+		{"extra metadata", map[string]string{"x-amz-meta-other": "wos"}, VerifyReasonPolicyCondition},
+		{"mismatched field", map[string]string{"Content-Type": "text/html"}, VerifyReasonPolicyCondition},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fields := map[string]string{"bucket": "bucket"}
+			for key, value := range policy.FormFields {
+				fields[key] = value
+			}
+			for key, value := range c.extra {
+				fields[key] = value
+			}
+			_, err := verifier.VerifyPostPolicy(fields, 4)
+			if c.reason == "" && err != nil {
+				t.Fatalf("VerifyPostPolicy error = %v", err)
+			}
+			if c.reason != "" && (err == nil || err.(VerifyError).Reason != c.reason) {
+				t.Fatalf("VerifyPostPolicy error = %v, want %s", err, c.reason)
+			}
+		})
+	}
+
+	// the fields of a policy signed by hand are restricted as well
+	document := `{"expiration":"2099-01-01T00:00:00Z","conditions":[{"bucket":"bucket"},["starts-with","$key",""]]}`
+	encoded := base64.StdEncoding.EncodeToString([]byte(document))
+	fields := map[string]string{"bucket": "bucket", "key": "key", "AWSAccessKeyId": "ak", "policy": encoded,
+		"signature": Base64Encode(HmacSha1([]byte("sk"), []byte(encoded))), "success_action_status": "201"}
+	if _, err = verifier.VerifyPostPolicy(fields, -1); err == nil || err.(VerifyError).Reason != VerifyReasonPolicyCondition {
+		t.Errorf("VerifyPostPolicy error = %v, want %s", err, VerifyReasonPolicyCondition)
+	}
+}